@@ -0,0 +1,19 @@
+// Package anomaly provides streaming anomaly detectors built on ewma and tdigest.
+//
+// All detectors implement Detector and are safe for concurrent use.
+package anomaly
+
+import (
+	"time"
+)
+
+// Detector scores observations from a stream.
+//
+// Observe records value observed at time t and returns a detector-specific
+// score, where a larger magnitude means a more unusual value, and whether
+// the score crossed the detector's threshold. The score is computed against
+// the state before value is recorded. Detectors report no anomalies until
+// they have seen enough observations to form a baseline.
+type Detector interface {
+	Observe(value float64, t time.Time) (score float64, anomalous bool)
+}
@@ -0,0 +1,50 @@
+package anomaly
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitalvas/gokit/ewma"
+)
+
+var (
+	_ Detector = (*ZScore)(nil)
+	_ Detector = (*MAD)(nil)
+	_ Detector = (*CUSUM)(nil)
+	_ Detector = (*Forecast)(nil)
+)
+
+func TestDetectors(t *testing.T) {
+	detectors := map[string]func() Detector{
+		"zscore":   func() Detector { return NewZScore(0.05, 4, 50) },
+		"mad":      func() Detector { return NewMAD(time.Hour, 5, 50) },
+		"cusum":    func() Detector { return NewCUSUM(0.5, 8, 50) },
+		"forecast": func() Detector { return NewForecast(ewma.NewHolt(0.1, 0.01), 5, 50) },
+	}
+
+	for name, create := range detectors {
+		t.Run(name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			d := create()
+			base := time.Unix(1700000000, 0)
+
+			falseAlarms := 0
+			for i := range 1000 {
+				_, anomalous := d.Observe(100+rng.NormFloat64()*5, base.Add(time.Duration(i)*time.Second))
+				if anomalous {
+					falseAlarms++
+				}
+			}
+			assert.LessOrEqual(t, falseAlarms, 5)
+
+			detected := false
+			for i := range 10 {
+				_, anomalous := d.Observe(200, base.Add(time.Duration(1000+i)*time.Second))
+				detected = detected || anomalous
+			}
+			assert.True(t, detected)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package anomaly
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// CUSUM detects persistent shifts in the mean with Page's two-sided
+// cumulative sum. Small deviations that a z-score would ignore accumulate
+// until they cross the threshold, so CUSUM finds level changes early.
+//
+// Values are standardized against a baseline mean and standard deviation
+// learned from the warm-up observations:
+//
+//	z = (x - mean) / stddev
+//	high = max(0, high + z - drift)
+//	low = max(0, low - z - drift)
+//
+// The score is the larger of the two sums. When it exceeds the threshold
+// the change is reported and both sums restart from zero.
+//
+// Use cases:
+//   - Gradual latency regressions after a deploy
+//   - Level shifts in throughput
+type CUSUM struct {
+	mu        sync.Mutex
+	drift     float64
+	threshold float64
+	warmup    int
+	count     int
+	mean      float64
+	m2        float64 // sum of squared deviations during warm-up
+	stddev    float64
+	high      float64
+	low       float64
+}
+
+// NewCUSUM creates a two-sided CUSUM detector.
+//
+// Parameters:
+//   - drift: allowed slack per observation in standard deviations, default 0.5
+//   - threshold: cumulative sum at which a shift is reported, default 5
+//   - warmup: observations used to learn the baseline, default 30
+func NewCUSUM(drift, threshold float64, warmup int) *CUSUM {
+	if drift < 0 {
+		drift = 0.5
+	}
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if warmup < 2 {
+		warmup = 30
+	}
+
+	return &CUSUM{
+		drift:     drift,
+		threshold: threshold,
+		warmup:    warmup,
+	}
+}
+
+// Observe records a value and returns the cumulative sum.
+// The time is unused; each observation is one step.
+func (c *CUSUM) Observe(value float64, _ time.Time) (score float64, anomalous bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.count < c.warmup {
+		// Welford's online mean and variance
+		c.count++
+		delta := value - c.mean
+		c.mean += delta / float64(c.count)
+		c.m2 += delta * (value - c.mean)
+
+		if c.count == c.warmup {
+			c.stddev = math.Sqrt(c.m2 / float64(c.count-1))
+		}
+		return 0, false
+	}
+
+	z := zscore(value-c.mean, c.stddev)
+	c.high = math.Max(0, c.high+z-c.drift)
+	c.low = math.Max(0, c.low-z-c.drift)
+
+	score = math.Max(c.high, c.low)
+	if score > c.threshold {
+		c.high = 0
+		c.low = 0
+		return score, true
+	}
+
+	return score, false
+}
+
+// SetBaseline sets the baseline mean and standard deviation directly,
+// skipping the warm-up, and clears the cumulative sums.
+func (c *CUSUM) SetBaseline(mean, stddev float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.mean = mean
+	c.stddev = stddev
+	c.count = c.warmup
+	c.high = 0
+	c.low = 0
+}
+
+// Baseline returns the baseline mean and standard deviation.
+// Both are zero until the warm-up completes.
+func (c *CUSUM) Baseline() (mean, stddev float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.count < c.warmup {
+		return 0, 0
+	}
+	return c.mean, c.stddev
+}
+
+// Reset clears the baseline and cumulative sums.
+func (c *CUSUM) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count = 0
+	c.mean = 0
+	c.m2 = 0
+	c.stddev = 0
+	c.high = 0
+	c.low = 0
+}
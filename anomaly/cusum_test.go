@@ -0,0 +1,96 @@
+package anomaly
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCUSUM(t *testing.T) {
+	c := NewCUSUM(-1, 0, 0)
+	assert.Equal(t, 0.5, c.drift)
+	assert.Equal(t, 5.0, c.threshold)
+	assert.Equal(t, 30, c.warmup)
+}
+
+func TestCUSUM(t *testing.T) {
+	var now time.Time
+
+	t.Run("learns baseline during warm-up", func(t *testing.T) {
+		c := NewCUSUM(0.5, 5, 4)
+		for _, v := range []float64{2, 4, 4, 6} {
+			score, anomalous := c.Observe(v, now)
+			assert.Equal(t, 0.0, score)
+			assert.False(t, anomalous)
+		}
+
+		mean, stddev := c.Baseline()
+		assert.InDelta(t, 4, mean, 1e-9)
+		assert.InDelta(t, 1.632993, stddev, 1e-6)
+	})
+
+	t.Run("detects small persistent shift", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		c := NewCUSUM(0.5, 5, 100)
+		z := NewZScore(0.1, 3, 100)
+
+		for range 500 {
+			v := 100 + rng.NormFloat64()
+			c.Observe(v, now)
+			z.Observe(v, now)
+		}
+
+		// Shift by 1.5 sigma: rarely a z-score outlier, but CUSUM accumulates
+		detectedAt := -1
+		for i := range 50 {
+			v := 101.5 + rng.NormFloat64()
+			if _, anomalous := c.Observe(v, now); anomalous && detectedAt < 0 {
+				detectedAt = i
+			}
+		}
+		assert.GreaterOrEqual(t, detectedAt, 0)
+		assert.Less(t, detectedAt, 15)
+	})
+
+	t.Run("detects downward shift and restarts", func(t *testing.T) {
+		c := NewCUSUM(0.5, 5, 2)
+		c.SetBaseline(10, 1)
+
+		score, anomalous := c.Observe(7, now)
+		assert.Equal(t, 2.5, score)
+		assert.False(t, anomalous)
+
+		score, anomalous = c.Observe(7, now)
+		assert.Equal(t, 5.0, score)
+		assert.False(t, anomalous)
+
+		score, anomalous = c.Observe(7, now)
+		assert.Equal(t, 7.5, score)
+		assert.True(t, anomalous)
+
+		score, _ = c.Observe(10, now)
+		assert.Equal(t, 0.0, score)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		c := NewCUSUM(0.5, 5, 2)
+		c.SetBaseline(10, 1)
+		c.Reset()
+		mean, stddev := c.Baseline()
+		assert.Equal(t, 0.0, mean)
+		assert.Equal(t, 0.0, stddev)
+	})
+}
+
+func BenchmarkCUSUM_Observe(b *testing.B) {
+	c := NewCUSUM(0.5, 5, 30)
+	var now time.Time
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		c.Observe(float64(i%100), now)
+		i++
+	}
+}
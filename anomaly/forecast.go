@@ -0,0 +1,69 @@
+package anomaly
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Forecaster predicts the next observation of a series.
+// Implemented by ewma.Holt and ewma.HoltWinters.
+type Forecaster interface {
+	Score(value float64) float64
+	Update(value float64)
+}
+
+// Forecast detects values outside the prediction of a forecasting model.
+// With ewma.HoltWinters, trend and seasonal patterns such as a daily
+// morning ramp are part of the forecast rather than anomalies.
+type Forecast struct {
+	mu        sync.Mutex
+	model     Forecaster
+	threshold float64
+	warmup    int
+	count     int
+}
+
+// NewForecast creates a detector around model, which it updates with
+// every observation.
+//
+// Parameters:
+//   - threshold: |score| in standard errors above which a value is anomalous, default 3
+//   - warmup: observations before anomalies are reported, default 30 plus
+//     the 2*period observations a model with a Period method (such as
+//     ewma.HoltWinters) needs before it forecasts
+//
+// The error variance is estimated from past residuals, so scores are
+// unreliable until the model has seen enough of them.
+func NewForecast(model Forecaster, threshold float64, warmup int) *Forecast {
+	if threshold <= 0 {
+		threshold = 3
+	}
+	if warmup <= 0 {
+		warmup = 30
+		if p, ok := model.(interface{ Period() int }); ok {
+			warmup += 2 * p.Period()
+		}
+	}
+
+	return &Forecast{
+		model:     model,
+		threshold: threshold,
+		warmup:    warmup,
+	}
+}
+
+// Observe scores a value against the one-step forecast, then records it.
+// The time is unused; the model assumes observations at a fixed interval.
+func (f *Forecast) Observe(value float64, _ time.Time) (score float64, anomalous bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	score = f.model.Score(value)
+	anomalous = f.count >= f.warmup && math.Abs(score) > f.threshold
+
+	f.model.Update(value)
+	f.count++
+
+	return score, anomalous
+}
@@ -0,0 +1,66 @@
+package anomaly
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitalvas/gokit/ewma"
+)
+
+func TestForecast(t *testing.T) {
+	var now time.Time
+
+	t.Run("defaults", func(t *testing.T) {
+		f := NewForecast(ewma.NewHolt(0.5, 0.1), 0, 0)
+		assert.Equal(t, 3.0, f.threshold)
+		assert.Equal(t, 30, f.warmup)
+
+		// Seasonal models first need two periods to initialize
+		f = NewForecast(ewma.NewHoltWinters(0.2, 0.05, 0.3, 24), 0, 0)
+		assert.Equal(t, 78, f.warmup)
+	})
+
+	t.Run("no anomalies during warmup", func(t *testing.T) {
+		f := NewForecast(ewma.NewHolt(0.5, 0.1), 3, 10)
+
+		// Two near-identical residuals give a tiny error variance
+		for _, v := range []float64{10, 11, 12, 13, 14.001} {
+			f.Observe(v, now)
+		}
+
+		score, anomalous := f.Observe(100, now)
+		assert.Greater(t, score, 3.0)
+		assert.False(t, anomalous)
+	})
+
+	t.Run("seasonal ramp is not anomalous", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		hw := ewma.NewHoltWinters(0.2, 0.05, 0.3, 24)
+		f := NewForecast(hw, 4, 0)
+
+		value := func(i int) float64 {
+			return 1000 + 400*math.Sin(2*math.Pi*float64(i)/24) + rng.NormFloat64()*10
+		}
+
+		for i := range 24 * 7 {
+			v := value(i)
+			f.Observe(v, now)
+		}
+
+		forecastAlarms := 0
+		for i := 24 * 7; i < 24*14; i++ {
+			v := value(i)
+			if _, anomalous := f.Observe(v, now); anomalous {
+				forecastAlarms++
+			}
+		}
+		assert.LessOrEqual(t, forecastAlarms, 2)
+
+		_, anomalous := f.Observe(value(24*14)+500, now)
+		assert.True(t, anomalous)
+		assert.Equal(t, 24*14+1, hw.Count())
+	})
+}
@@ -0,0 +1,164 @@
+package anomaly
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/vitalvas/gokit/tdigest"
+)
+
+// madScale converts MAD to a consistent estimator of the standard
+// deviation for normally distributed data.
+const madScale = 1.4826
+
+// madBuckets is the number of time slices in the sliding window.
+const madBuckets = 10
+
+// MAD detects values far from the median of a sliding time window,
+// measured in median absolute deviations. The median and MAD are robust:
+// a few extreme values do not shift the baseline the way they shift a
+// mean and standard deviation.
+//
+// The score is the modified z-score of Iglewicz and Hoaglin:
+//
+//	score = (x - median) / (1.4826 * MAD)
+//
+// Median and MAD are estimated with t-digests over the window, so memory
+// is bounded regardless of the observation rate. Merging the window is the
+// expensive step, so once warmed up the baseline is refreshed once per
+// slice (a tenth of the window) and reused for every value in that slice.
+// Deviations are measured against the baseline in use when each value is
+// observed.
+//
+// Use cases:
+//   - Heavy-tailed metrics such as latency
+//   - Baselines polluted by occasional outliers
+type MAD struct {
+	mu         sync.Mutex
+	values     *tdigest.Window
+	deviations *tdigest.Window
+	threshold  float64
+	warmup     int
+	window     time.Duration
+	width      time.Duration
+
+	// Baseline cached for the slice in epoch; count includes values
+	// observed since it was computed
+	epoch  int64
+	median float64
+	mad    float64
+	count  float64
+	cached bool
+}
+
+// NewMAD creates a median/MAD detector.
+//
+// Parameters:
+//   - window: sliding window for the baseline, default 10 minutes
+//   - threshold: |score| above which a value is anomalous, default 3.5
+//   - warmup: observations in the window before anomalies are reported, default 30
+func NewMAD(window time.Duration, threshold float64, warmup int) *MAD {
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	if threshold <= 0 {
+		threshold = 3.5
+	}
+	if warmup <= 0 {
+		warmup = 30
+	}
+
+	width := window / madBuckets
+	if width <= 0 {
+		width = window
+	}
+
+	return &MAD{
+		values:     tdigest.NewWindow(100, width, madBuckets),
+		deviations: tdigest.NewWindow(100, width, madBuckets),
+		threshold:  threshold,
+		warmup:     warmup,
+		window:     window,
+		width:      width,
+	}
+}
+
+// Observe records a value at time t and returns its modified z-score.
+func (m *MAD) Observe(value float64, t time.Time) (score float64, anomalous bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.refresh(t)
+	if m.count == 0 {
+		m.values.AddAt(value, t)
+		m.deviations.AddAt(0, t)
+		m.count++
+		return 0, false
+	}
+
+	score = zscore(value-m.median, madScale*m.mad)
+	anomalous = m.count >= float64(m.warmup) && math.Abs(score) > m.threshold
+
+	m.values.AddAt(value, t)
+	m.deviations.AddAt(math.Abs(value-m.median), t)
+	m.count++
+
+	return score, anomalous
+}
+
+// refresh recomputes the cached baseline when t enters a new slice, or on
+// every call until the window holds warmup values so that early scores
+// track the data exactly. Caller must hold mu.
+func (m *MAD) refresh(t time.Time) {
+	ns := t.UnixNano()
+	epoch := ns / int64(m.width)
+	if ns < 0 && ns%int64(m.width) != 0 {
+		epoch--
+	}
+
+	if m.cached && epoch == m.epoch && m.count >= float64(m.warmup) {
+		return
+	}
+
+	baseline := m.values.DigestAt(m.window, t)
+	m.count = baseline.Count()
+	if m.count > 0 {
+		m.median = baseline.Quantile(0.5)
+		m.mad = m.deviations.DigestAt(m.window, t).Quantile(0.5)
+	}
+
+	m.epoch = epoch
+	m.cached = true
+}
+
+// Median returns the median of the window ending at t.
+// Returns NaN if the window is empty.
+func (m *MAD) Median(t time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values.DigestAt(m.window, t).Quantile(0.5)
+}
+
+// MAD returns the median absolute deviation of the window ending at t.
+// Returns NaN if the window is empty.
+func (m *MAD) MAD(t time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.deviations.DigestAt(m.window, t).Quantile(0.5)
+}
+
+// Window returns the baseline window.
+func (m *MAD) Window() time.Duration {
+	return m.window
+}
+
+// Reset clears all state.
+func (m *MAD) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values.Reset()
+	m.deviations.Reset()
+	m.cached = false
+}
@@ -0,0 +1,106 @@
+package anomaly
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMAD(t *testing.T) {
+	m := NewMAD(0, 0, 0)
+	assert.Equal(t, 10*time.Minute, m.Window())
+	assert.Equal(t, 3.5, m.threshold)
+	assert.Equal(t, 30, m.warmup)
+}
+
+func TestMAD(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("median and mad", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		m := NewMAD(time.Hour, 3.5, 10)
+		for i := range 5000 {
+			m.Observe(100+rng.NormFloat64()*10, base.Add(time.Duration(i)*100*time.Millisecond))
+		}
+
+		now := base.Add(500 * time.Second)
+		assert.InDelta(t, 100, m.Median(now), 1)
+		// MAD of a normal distribution is about 0.6745 sigma
+		assert.InDelta(t, 6.745, m.MAD(now), 0.5)
+	})
+
+	t.Run("robust to outliers in the baseline", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(2))
+		m := NewMAD(time.Hour, 3.5, 10)
+		for i := range 1000 {
+			v := 100 + rng.NormFloat64()
+			if i%20 == 0 {
+				v = 10000
+			}
+			m.Observe(v, base.Add(time.Duration(i)*time.Second))
+		}
+
+		score, anomalous := m.Observe(110, base.Add(1000*time.Second))
+		assert.True(t, anomalous)
+		assert.Greater(t, score, 3.5)
+
+		score, anomalous = m.Observe(100.5, base.Add(1001*time.Second))
+		assert.False(t, anomalous)
+		assert.Less(t, math.Abs(score), 1.0)
+	})
+
+	t.Run("old values leave the window", func(t *testing.T) {
+		m := NewMAD(time.Minute, 3.5, 5)
+		for i := range 60 {
+			m.Observe(float64(i%3), base.Add(time.Duration(i)*time.Second))
+		}
+		assert.InDelta(t, 1, m.Median(base.Add(time.Minute)), 0.5)
+
+		later := base.Add(time.Hour)
+		assert.True(t, math.IsNaN(m.Median(later)))
+
+		score, anomalous := m.Observe(1000, later)
+		assert.Equal(t, 0.0, score)
+		assert.False(t, anomalous)
+	})
+
+	t.Run("baseline refreshes once per slice", func(t *testing.T) {
+		// One-minute window: slices are six seconds wide
+		m := NewMAD(time.Minute, 3.5, 5)
+		for i := range 10 {
+			m.Observe(float64(10+i%2), base.Add(time.Duration(i)*100*time.Millisecond))
+		}
+
+		// A level shift within the slice is scored against the cached baseline
+		for i := range 20 {
+			_, anomalous := m.Observe(1000, base.Add(time.Second+time.Duration(i)*100*time.Millisecond))
+			assert.True(t, anomalous)
+		}
+
+		// The next slice picks up the new values
+		assert.InDelta(t, 1000, m.Median(base.Add(6*time.Second)), 1)
+		score, _ := m.Observe(10, base.Add(6*time.Second))
+		assert.Less(t, score, 0.0)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		m := NewMAD(time.Minute, 3.5, 5)
+		m.Observe(1, base)
+		m.Reset()
+		assert.True(t, math.IsNaN(m.Median(base)))
+	})
+}
+
+func BenchmarkMAD_Observe(b *testing.B) {
+	m := NewMAD(time.Minute, 3.5, 30)
+	base := time.Unix(1700000000, 0)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		m.Observe(float64(i%100), base.Add(time.Duration(i)*time.Millisecond))
+		i++
+	}
+}
@@ -0,0 +1,127 @@
+package anomaly
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/vitalvas/gokit/ewma"
+)
+
+// ZScore detects values far from an exponentially weighted mean, measured
+// in exponentially weighted standard deviations (EWMV).
+//
+// Mean and variance are ewma.EWMA averages with the same alpha, following
+// the incremental update from Finch, "Incremental calculation of weighted
+// mean and variance" (2009):
+//
+//	diff = x - mean
+//	mean = alpha * x + (1 - alpha) * mean
+//	variance = alpha * (1 - alpha) * diff^2 + (1 - alpha) * variance
+//
+// Use cases:
+//   - Latency spikes on a stable baseline
+//   - Sudden drops in request rate
+//   - Metrics with roughly normal noise
+type ZScore struct {
+	mu        sync.Mutex
+	alpha     float64
+	threshold float64
+	warmup    int
+	mean      *ewma.EWMA
+	variance  *ewma.EWMA
+	count     int
+}
+
+// NewZScore creates an EWMV z-score detector.
+//
+// Parameters:
+//   - alpha: smoothing factor in (0, 1], default 0.1
+//   - threshold: |z| above which a value is anomalous, default 3
+//   - warmup: observations before anomalies are reported, default 30
+func NewZScore(alpha, threshold float64, warmup int) *ZScore {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.1
+	}
+	if threshold <= 0 {
+		threshold = 3
+	}
+	if warmup <= 0 {
+		warmup = 30
+	}
+
+	return &ZScore{
+		alpha:     alpha,
+		threshold: threshold,
+		warmup:    warmup,
+		mean:      ewma.NewWithAlpha(alpha),
+		variance:  ewma.NewWithAlpha(alpha),
+	}
+}
+
+// Observe records a value and returns its signed z-score.
+// The time is unused; observations are weighted by arrival order.
+func (z *ZScore) Observe(value float64, _ time.Time) (score float64, anomalous bool) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if z.count == 0 {
+		z.mean.Set(value)
+		z.variance.Set(0)
+		z.count++
+		return 0, false
+	}
+
+	diff := value - z.mean.Rate()
+	score = zscore(diff, math.Sqrt(z.variance.Rate()))
+	anomalous = z.count >= z.warmup && math.Abs(score) > z.threshold
+
+	z.mean.UpdateWithValue(value)
+	z.variance.UpdateWithValue((1 - z.alpha) * diff * diff)
+	z.count++
+
+	return score, anomalous
+}
+
+// Mean returns the exponentially weighted mean.
+func (z *ZScore) Mean() float64 {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return z.mean.Rate()
+}
+
+// StdDev returns the exponentially weighted standard deviation.
+func (z *ZScore) StdDev() float64 {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return math.Sqrt(z.variance.Rate())
+}
+
+// Count returns the number of observations recorded.
+func (z *ZScore) Count() int {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	return z.count
+}
+
+// Reset clears all state.
+func (z *ZScore) Reset() {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	z.mean.Reset()
+	z.variance.Reset()
+	z.count = 0
+}
+
+// zscore divides diff by the spread, treating zero spread as infinitely
+// tight: any difference is infinitely unusual.
+func zscore(diff, spread float64) float64 {
+	if spread == 0 {
+		if diff == 0 {
+			return 0
+		}
+		return math.Copysign(math.Inf(1), diff)
+	}
+	return diff / spread
+}
@@ -0,0 +1,108 @@
+package anomaly
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewZScore(t *testing.T) {
+	z := NewZScore(0, 0, 0)
+	assert.Equal(t, 0.1, z.alpha)
+	assert.Equal(t, 3.0, z.threshold)
+	assert.Equal(t, 30, z.warmup)
+}
+
+func TestZScore(t *testing.T) {
+	var now time.Time
+
+	t.Run("mean and stddev converge", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		z := NewZScore(0.01, 3, 10)
+		for range 20000 {
+			z.Observe(50+rng.NormFloat64()*2, now)
+		}
+
+		assert.InDelta(t, 50, z.Mean(), 0.5)
+		assert.InDelta(t, 2, z.StdDev(), 0.3)
+		assert.Equal(t, 20000, z.Count())
+	})
+
+	t.Run("score is signed", func(t *testing.T) {
+		z := NewZScore(0.5, 3, 2)
+		z.Observe(10, now)
+		z.Observe(12, now)
+		z.Observe(10, now)
+
+		up, _ := z.Observe(20, now)
+		assert.Greater(t, up, 0.0)
+
+		z.Reset()
+		z.Observe(10, now)
+		z.Observe(12, now)
+		z.Observe(10, now)
+		down, _ := z.Observe(0, now)
+		assert.Less(t, down, 0.0)
+	})
+
+	t.Run("no anomalies during warm-up", func(t *testing.T) {
+		z := NewZScore(0.1, 3, 5)
+		z.Observe(1, now)
+		for range 3 {
+			_, anomalous := z.Observe(1000, now)
+			assert.False(t, anomalous)
+		}
+	})
+
+	t.Run("constant series", func(t *testing.T) {
+		z := NewZScore(0.1, 3, 2)
+		for range 5 {
+			score, anomalous := z.Observe(7, now)
+			assert.Equal(t, 0.0, score)
+			assert.False(t, anomalous)
+		}
+
+		score, anomalous := z.Observe(8, now)
+		assert.True(t, math.IsInf(score, 1))
+		assert.True(t, anomalous)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		z := NewZScore(0.1, 3, 2)
+		z.Observe(5, now)
+		z.Reset()
+		assert.Equal(t, 0, z.Count())
+		assert.Equal(t, 0.0, z.Mean())
+	})
+
+	t.Run("concurrent observe", func(t *testing.T) {
+		z := NewZScore(0.1, 3, 2)
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 500 {
+					z.Observe(float64(i%10), now)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 2000, z.Count())
+	})
+}
+
+func BenchmarkZScore_Observe(b *testing.B) {
+	z := NewZScore(0.1, 3, 30)
+	var now time.Time
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		z.Observe(float64(i%100), now)
+		i++
+	}
+}
@@ -40,16 +40,7 @@ type Sketch struct {
 //   - New(0.01, 0.01):  width=272, depth=5, ~11KB memory
 //   - New(0.001, 0.1):  width=2719, depth=3, ~64KB memory
 func New(epsilon, delta float64) *Sketch {
-	if epsilon <= 0 {
-		epsilon = 0.001 // Default 0.1% error
-	}
-	if delta <= 0 || delta >= 1 {
-		delta = 0.01 // Default 1% failure probability
-	}
-
-	// Calculate dimensions
-	width := uint32(math.Ceil(math.E / epsilon))
-	depth := uint32(math.Ceil(math.Log(1 / delta)))
+	epsilon, delta, width, depth := dimensions(epsilon, delta)
 
 	// Allocate matrix
 	matrix := make([][]uint64, depth)
@@ -66,6 +57,22 @@ func New(epsilon, delta float64) *Sketch {
 	}
 }
 
+// dimensions applies defaults to epsilon and delta and returns the
+// matching width and depth.
+func dimensions(epsilon, delta float64) (float64, float64, uint32, uint32) {
+	if epsilon <= 0 {
+		epsilon = 0.001 // Default 0.1% error
+	}
+	if delta <= 0 || delta >= 1 {
+		delta = 0.01 // Default 1% failure probability
+	}
+
+	width := uint32(math.Ceil(math.E / epsilon))
+	depth := uint32(math.Ceil(math.Log(1 / delta)))
+
+	return epsilon, delta, width, depth
+}
+
 // NewWithSize creates a Count-min sketch with explicit dimensions.
 // Use this when you know the exact width and depth you need.
 func NewWithSize(width, depth uint32) *Sketch {
@@ -287,6 +294,11 @@ func Import(data []byte) (*Sketch, error) {
 
 // hash computes hash value for data with seed based on row.
 func (s *Sketch) hash(data []byte, row uint32) uint64 {
+	return hashRow(data, row)
+}
+
+// hashRow computes the hash of data for the given row.
+func hashRow(data []byte, row uint32) uint64 {
 	// Use different hash seed per row
 	seed := uint64(row)*0x9e3779b97f4a7c15 + 0x517cc1b727220a95
 	return hash64(data, seed)
@@ -0,0 +1,200 @@
+package countmin
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/vitalvas/gokit/ewma"
+)
+
+// decayedRescaleExponent bounds forward-decay weights before the matrix is
+// rescaled to a new landmark, keeping values well inside float64 range.
+const decayedRescaleExponent = 64
+
+// Decayed is a Count-min sketch whose counts decay exponentially with age.
+// Each occurrence contributes exp(-ln2/halfLife * age) to its item, so
+// CountAt returns a decayed frequency: recent events count fully, events
+// one half-life old count half.
+//
+// Counts are stored with forward decay relative to a landmark time, so an
+// update touches depth cells and never rescans the matrix; the matrix is
+// renormalized only when weights grow too large.
+//
+// Error bounds match Sketch with N replaced by the decayed total.
+type Decayed struct {
+	mu       sync.RWMutex
+	matrix   [][]float64
+	width    uint32
+	depth    uint32
+	total    float64
+	landmark time.Time
+	decay    ewma.Decay
+	clock    ewma.Clock
+}
+
+// NewDecayed creates a decaying Count-min sketch with the given error
+// bounds (see New) and half-life.
+func NewDecayed(epsilon, delta float64, halfLife time.Duration) *Decayed {
+	return NewDecayedWithClock(epsilon, delta, halfLife, ewma.SystemClock)
+}
+
+// NewDecayedWithClock creates a decaying Count-min sketch that reads the
+// current time from clock. If clock is nil, ewma.SystemClock is used.
+func NewDecayedWithClock(epsilon, delta float64, halfLife time.Duration, clock ewma.Clock) *Decayed {
+	if clock == nil {
+		clock = ewma.SystemClock
+	}
+
+	_, _, width, depth := dimensions(epsilon, delta)
+
+	matrix := make([][]float64, depth)
+	for i := range matrix {
+		matrix[i] = make([]float64, width)
+	}
+
+	return &Decayed{
+		matrix: matrix,
+		width:  width,
+		depth:  depth,
+		decay:  ewma.NewDecay(halfLife),
+		clock:  clock,
+	}
+}
+
+// Add records n occurrences of an item at the current time.
+func (d *Decayed) Add(data []byte, n uint64) {
+	d.AddAt(data, n, d.clock.Now())
+}
+
+// AddAt records n occurrences of an item at time t.
+func (d *Decayed) AddAt(data []byte, n uint64, t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	weight := float64(n) * d.forward(t)
+
+	d.total += weight
+
+	for i := uint32(0); i < d.depth; i++ {
+		index := hashRow(data, i) % uint64(d.width)
+		d.matrix[i][index] += weight
+	}
+}
+
+// AddString records n occurrences of a string item at the current time.
+func (d *Decayed) AddString(str string, n uint64) {
+	d.Add(stringToBytes(str), n)
+}
+
+// AddStringAt records n occurrences of a string item at time t.
+func (d *Decayed) AddStringAt(str string, n uint64, t time.Time) {
+	d.AddAt(stringToBytes(str), n, t)
+}
+
+// Count returns the decayed frequency of an item at the current time.
+func (d *Decayed) Count(data []byte) float64 {
+	return d.CountAt(data, d.clock.Now())
+}
+
+// CountAt returns the decayed frequency of an item at time t.
+// The estimate is guaranteed to be ≥ the true decayed count.
+func (d *Decayed) CountAt(data []byte, t time.Time) float64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.landmark.IsZero() {
+		return 0
+	}
+
+	minCount := math.Inf(1)
+	for i := uint32(0); i < d.depth; i++ {
+		index := hashRow(data, i) % uint64(d.width)
+		minCount = math.Min(minCount, d.matrix[i][index])
+	}
+
+	return minCount / d.scale(t)
+}
+
+// CountString returns the decayed frequency of a string item at the current time.
+func (d *Decayed) CountString(str string) float64 {
+	return d.Count(stringToBytes(str))
+}
+
+// CountStringAt returns the decayed frequency of a string item at time t.
+func (d *Decayed) CountStringAt(str string, t time.Time) float64 {
+	return d.CountAt(stringToBytes(str), t)
+}
+
+// Total returns the decayed total of all items at the current time.
+func (d *Decayed) Total() float64 {
+	return d.TotalAt(d.clock.Now())
+}
+
+// TotalAt returns the decayed total of all items at time t.
+func (d *Decayed) TotalAt(t time.Time) float64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.landmark.IsZero() {
+		return 0
+	}
+
+	return d.total / d.scale(t)
+}
+
+// Clear resets all counters to zero.
+func (d *Decayed) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i := range d.matrix {
+		clear(d.matrix[i])
+	}
+	d.total = 0
+	d.landmark = time.Time{}
+}
+
+// HalfLife returns the decay half-life.
+func (d *Decayed) HalfLife() time.Duration {
+	return d.decay.HalfLife()
+}
+
+// Width returns the width of the sketch.
+func (d *Decayed) Width() uint32 {
+	return d.width
+}
+
+// Depth returns the depth of the sketch.
+func (d *Decayed) Depth() uint32 {
+	return d.depth
+}
+
+// forward returns the forward-decay weight of an event at time t,
+// rescaling the matrix to a new landmark when weights grow too large.
+// Caller must hold d.mu.
+func (d *Decayed) forward(t time.Time) float64 {
+	if d.landmark.IsZero() {
+		d.landmark = t
+	}
+
+	exponent := d.decay.Lambda() * t.Sub(d.landmark).Seconds()
+	if exponent > decayedRescaleExponent {
+		factor := math.Exp(-exponent)
+		for i := range d.matrix {
+			for j := range d.matrix[i] {
+				d.matrix[i][j] *= factor
+			}
+		}
+		d.total *= factor
+		d.landmark = t
+		exponent = 0
+	}
+
+	return math.Exp(exponent)
+}
+
+// scale returns the forward-decay normalizer for time t.
+func (d *Decayed) scale(t time.Time) float64 {
+	return math.Exp(d.decay.Lambda() * t.Sub(d.landmark).Seconds())
+}
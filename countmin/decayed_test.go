@@ -0,0 +1,147 @@
+package countmin
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vitalvas/gokit/ewma"
+)
+
+func TestNewDecayed(t *testing.T) {
+	d := NewDecayed(0.01, 0.01, 30*time.Second)
+	assert.Equal(t, uint32(272), d.Width())
+	assert.Equal(t, uint32(5), d.Depth())
+	assert.Equal(t, 30*time.Second, d.HalfLife())
+	assert.Equal(t, 0.0, d.CountString("missing"))
+	assert.Equal(t, 0.0, d.Total())
+
+	t.Run("defaults", func(t *testing.T) {
+		d := NewDecayedWithClock(0, 0, 0, nil)
+		assert.Equal(t, uint32(2719), d.Width())
+		assert.Equal(t, 60*time.Second, d.HalfLife())
+	})
+}
+
+func TestDecayedCount(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("counts decay by half-life", func(t *testing.T) {
+		d := NewDecayed(0.001, 0.01, 10*time.Second)
+		d.AddStringAt("a", 100, base)
+
+		assert.InDelta(t, 100.0, d.CountStringAt("a", base), 1e-9)
+		assert.InDelta(t, 50.0, d.CountStringAt("a", base.Add(10*time.Second)), 1e-9)
+		assert.InDelta(t, 25.0, d.CountStringAt("a", base.Add(20*time.Second)), 1e-9)
+		assert.InDelta(t, 25.0, d.TotalAt(base.Add(20*time.Second)), 1e-9)
+	})
+
+	t.Run("recent events outweigh old ones", func(t *testing.T) {
+		d := NewDecayed(0.001, 0.01, 10*time.Second)
+		d.AddStringAt("old", 100, base)
+		d.AddStringAt("new", 30, base.Add(30*time.Second))
+
+		now := base.Add(30 * time.Second)
+		assert.InDelta(t, 12.5, d.CountStringAt("old", now), 1e-9)
+		assert.InDelta(t, 30.0, d.CountStringAt("new", now), 1e-9)
+	})
+
+	t.Run("matches per-item decayed sum", func(t *testing.T) {
+		d := NewDecayed(0.001, 0.01, 5*time.Second)
+		expected := 0.0
+		decay := ewma.NewDecay(5 * time.Second)
+		end := base.Add(100 * time.Second)
+
+		for i := range 100 {
+			at := base.Add(time.Duration(i) * time.Second)
+			d.AddStringAt("x", 2, at)
+			expected += 2 * decay.Factor(end.Sub(at))
+		}
+
+		assert.InDelta(t, expected, d.CountStringAt("x", end), 1e-9)
+	})
+
+	t.Run("rescale keeps counts", func(t *testing.T) {
+		d := NewDecayed(0.01, 0.01, time.Second)
+		d.AddStringAt("a", 1000, base)
+
+		// 100 half-lives pushes the forward weight past the rescale bound
+		later := base.Add(100 * time.Second)
+		d.AddStringAt("b", 10, later)
+
+		assert.False(t, d.landmark.Equal(base))
+		assert.InDelta(t, 10.0, d.CountStringAt("b", later), 1e-9)
+		assert.InDelta(t, 1000*math.Pow(2, -100), d.CountStringAt("a", later), 1e-30)
+	})
+
+	t.Run("overestimates only", func(t *testing.T) {
+		d := NewDecayed(0.01, 0.01, time.Minute)
+		for i := range 1000 {
+			d.AddStringAt(fmt.Sprintf("k%d", i), uint64(i%10+1), base)
+		}
+
+		for i := range 1000 {
+			assert.GreaterOrEqual(t, d.CountStringAt(fmt.Sprintf("k%d", i), base), float64(i%10+1)-1e-9)
+		}
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		clock := ewma.NewManualClock(base)
+		d := NewDecayedWithClock(0.01, 0.01, time.Minute, clock)
+		d.AddString("a", 4)
+		d.Add([]byte("a"), 4)
+
+		clock.Advance(time.Minute)
+		assert.InDelta(t, 4.0, d.CountString("a"), 1e-9)
+		assert.InDelta(t, 4.0, d.Count([]byte("a")), 1e-9)
+		assert.InDelta(t, 4.0, d.Total(), 1e-9)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		d := NewDecayed(0.01, 0.01, time.Minute)
+		d.AddStringAt("a", 5, base)
+		d.Clear()
+
+		assert.Equal(t, 0.0, d.CountStringAt("a", base))
+		assert.Equal(t, 0.0, d.TotalAt(base))
+
+		d.AddStringAt("a", 5, base.Add(time.Hour))
+		assert.InDelta(t, 5.0, d.CountStringAt("a", base.Add(time.Hour)), 1e-9)
+	})
+}
+
+func TestDecayedConcurrency(t *testing.T) {
+	d := NewDecayed(0.01, 0.01, time.Minute)
+	base := time.Unix(1700000000, 0)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				d.AddStringAt(fmt.Sprintf("k%d", i%10), 1, base)
+				if i%100 == 0 {
+					_ = d.CountStringAt(fmt.Sprintf("k%d", g), base)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.InDelta(t, 8000.0, d.TotalAt(base), 1e-6)
+}
+
+func BenchmarkDecayed_Add(b *testing.B) {
+	d := NewDecayed(0.001, 0.01, time.Minute)
+	base := time.Unix(1700000000, 0)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		d.AddStringAt("key", 1, base.Add(time.Duration(i)*time.Millisecond))
+		i++
+	}
+}
@@ -0,0 +1,472 @@
+package ddsketch
+
+import (
+	"encoding/gob"
+	"errors"
+	"math"
+	"sync"
+)
+
+// ErrInvalidData is returned by Import when the exported data is inconsistent.
+var ErrInvalidData = errors.New("ddsketch: invalid data")
+
+// DDSketch is a quantile sketch with a relative-error guarantee.
+// Every quantile estimate q̂ satisfies |q̂ - q| <= α·|q|, where α is the
+// configured relative accuracy, regardless of the data distribution.
+//
+// Values are mapped to logarithmically sized bins. Positive and negative
+// values use separate stores, and values too close to zero to be indexed
+// are counted separately.
+//
+// Unlike t-digest, merging sketches with the same relative accuracy is
+// exact: the result is identical to a sketch built from all values, so
+// merges are associative and commutative.
+//
+// Use cases: Latency percentiles across many hosts, SLO tracking
+type DDSketch struct {
+	mu        sync.RWMutex
+	mapping   logarithmicMapping
+	storeType StoreType
+	positive  store
+	negative  store
+	zeroCount float64
+	count     float64
+	sum       float64
+	min       float64
+	max       float64
+}
+
+// New creates a new DDSketch with the specified relative accuracy and a
+// dense store.
+//
+// Relative accuracy controls the size-accuracy tradeoff:
+//   - 0.01: Default, 1% relative error (~1400 bins for 1ns-1h)
+//   - 0.005: High accuracy, about twice as many bins
+//   - 0.05: Low memory, about five times fewer bins
+func New(relativeAccuracy float64) *DDSketch {
+	return NewWithStore(relativeAccuracy, DenseStore)
+}
+
+// NewWithStore creates a new DDSketch with the specified relative accuracy
+// and store type.
+func NewWithStore(relativeAccuracy float64, storeType StoreType) *DDSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = 0.01
+	}
+
+	return &DDSketch{
+		mapping:   newLogarithmicMapping(relativeAccuracy),
+		storeType: storeType,
+		positive:  newStore(storeType),
+		negative:  newStore(storeType),
+		min:       math.Inf(1),
+		max:       math.Inf(-1),
+	}
+}
+
+// Add inserts a value into the sketch.
+func (s *DDSketch) Add(value float64) {
+	s.AddWeighted(value, 1)
+}
+
+// AddWeighted inserts a value with a specific weight.
+// NaN, infinite and values beyond the indexable range are ignored.
+func (s *DDSketch) AddWeighted(value, weight float64) {
+	if weight <= 0 || math.IsNaN(value) || math.Abs(value) > s.mapping.maxIndexable {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case value > s.mapping.minIndexable:
+		s.positive.add(s.mapping.index(value), weight)
+	case value < -s.mapping.minIndexable:
+		s.negative.add(s.mapping.index(-value), weight)
+	default:
+		s.zeroCount += weight
+	}
+
+	s.count += weight
+	s.sum += value * weight
+
+	if value < s.min {
+		s.min = value
+	}
+	if value > s.max {
+		s.max = value
+	}
+}
+
+// Quantile returns the estimated value at the given quantile (0-1).
+// The estimate is within the relative accuracy of the true value.
+//
+// Examples:
+//   - Quantile(0.5) returns median
+//   - Quantile(0.99) returns 99th percentile
+func (s *DDSketch) Quantile(q float64) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := q * (s.count - 1)
+	negativeCount := s.negative.count()
+
+	var value float64
+
+	switch {
+	case rank < negativeCount:
+		// Negative values are stored by magnitude, so walk them in reverse
+		value = -s.mapping.value(keyAtRank(reversed{s.negative}, rank))
+	case rank < negativeCount+s.zeroCount:
+		value = 0
+	default:
+		value = s.mapping.value(keyAtRank(s.positive, rank-negativeCount-s.zeroCount))
+	}
+
+	// Representative values may fall slightly outside the observed range
+	return math.Max(s.min, math.Min(s.max, value))
+}
+
+// CDF returns the cumulative distribution function value at x.
+// Returns the proportion of values <= x.
+func (s *DDSketch) CDF(x float64) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+
+	if x < s.min {
+		return 0
+	}
+	if x >= s.max {
+		return 1
+	}
+
+	below := float64(0)
+
+	s.negative.forEachDesc(func(index int, count float64) bool {
+		if -s.mapping.value(index) > x {
+			return false
+		}
+		below += count
+		return true
+	})
+
+	if x >= 0 {
+		below += s.zeroCount
+
+		s.positive.forEach(func(index int, count float64) bool {
+			if s.mapping.value(index) > x {
+				return false
+			}
+			below += count
+			return true
+		})
+	}
+
+	return below / s.count
+}
+
+// Count returns the total number of values added to the sketch.
+func (s *DDSketch) Count() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.count
+}
+
+// Sum returns the exact sum of all values.
+func (s *DDSketch) Sum() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sum
+}
+
+// Min returns the minimum value seen.
+func (s *DDSketch) Min() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.min
+}
+
+// Max returns the maximum value seen.
+func (s *DDSketch) Max() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.max
+}
+
+// Mean returns the exact mean of all values.
+func (s *DDSketch) Mean() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.count == 0 {
+		return math.NaN()
+	}
+	return s.sum / s.count
+}
+
+// Merge combines another sketch into this one.
+// With equal relative accuracy the merge is exact. Otherwise the other
+// sketch's bins are re-inserted by their representative values, which
+// adds the other sketch's error on top of this one's.
+func (s *DDSketch) Merge(other *DDSketch) {
+	if other == nil || other == s {
+		return
+	}
+
+	// Copy the other sketch under its own lock to avoid lock ordering issues
+	other.mu.RLock()
+	mapping := other.mapping
+	positive := collectBins(other.positive)
+	negative := collectBins(other.negative)
+	zeroCount, count, sum := other.zeroCount, other.count, other.sum
+	minValue, maxValue := other.min, other.max
+	other.mu.RUnlock()
+
+	if count == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exact := s.mapping.equals(mapping)
+
+	for _, b := range positive {
+		index := b.Index
+		if !exact {
+			index = s.mapping.index(mapping.value(b.Index))
+		}
+		s.positive.add(index, b.Count)
+	}
+	for _, b := range negative {
+		index := b.Index
+		if !exact {
+			index = s.mapping.index(mapping.value(b.Index))
+		}
+		s.negative.add(index, b.Count)
+	}
+
+	s.zeroCount += zeroCount
+	s.count += count
+	s.sum += sum
+
+	if minValue < s.min {
+		s.min = minValue
+	}
+	if maxValue > s.max {
+		s.max = maxValue
+	}
+}
+
+// Reset clears all data from the sketch.
+func (s *DDSketch) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positive.reset()
+	s.negative.reset()
+	s.zeroCount = 0
+	s.count = 0
+	s.sum = 0
+	s.min = math.Inf(1)
+	s.max = math.Inf(-1)
+}
+
+// RelativeAccuracy returns the configured relative accuracy.
+func (s *DDSketch) RelativeAccuracy() float64 {
+	return s.mapping.relativeAccuracy
+}
+
+// Export serializes the sketch for storage or transmission.
+func (s *DDSketch) Export() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var buf []byte
+	enc := gob.NewEncoder(&gobWriter{buf: &buf})
+
+	data := &sketchData{
+		RelativeAccuracy: s.mapping.relativeAccuracy,
+		StoreType:        s.storeType,
+		Positive:         collectBins(s.positive),
+		Negative:         collectBins(s.negative),
+		ZeroCount:        s.zeroCount,
+		Count:            s.count,
+		Sum:              s.sum,
+		Min:              s.min,
+		Max:              s.max,
+	}
+
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// Import deserializes a sketch from exported data.
+func Import(data []byte) (*DDSketch, error) {
+	var sketchData sketchData
+	dec := gob.NewDecoder(&gobReader{buf: data})
+
+	if err := dec.Decode(&sketchData); err != nil {
+		return nil, err
+	}
+
+	if sketchData.RelativeAccuracy <= 0 || sketchData.RelativeAccuracy >= 1 {
+		return nil, ErrInvalidData
+	}
+
+	s := NewWithStore(sketchData.RelativeAccuracy, sketchData.StoreType)
+
+	if !sketchData.valid(s.mapping) {
+		return nil, ErrInvalidData
+	}
+
+	for _, b := range sketchData.Positive {
+		s.positive.add(b.Index, b.Count)
+	}
+	for _, b := range sketchData.Negative {
+		s.negative.add(b.Index, b.Count)
+	}
+
+	s.zeroCount = sketchData.ZeroCount
+	s.count = sketchData.Count
+	s.sum = sketchData.Sum
+	s.min = sketchData.Min
+	s.max = sketchData.Max
+
+	return s, nil
+}
+
+// Internal helpers
+
+// reversed walks a store in descending index order.
+type reversed struct {
+	store
+}
+
+func (r reversed) forEach(fn func(index int, count float64) bool) {
+	r.store.forEachDesc(fn)
+}
+
+func collectBins(s store) []bin {
+	var bins []bin
+	s.forEach(func(index int, count float64) bool {
+		bins = append(bins, bin{Index: index, Count: count})
+		return true
+	})
+
+	return bins
+}
+
+// bin is a single store entry used for merging and gob encoding
+type bin struct {
+	Index int
+	Count float64
+}
+
+// sketchData is used for gob encoding/decoding
+type sketchData struct {
+	RelativeAccuracy float64
+	StoreType        StoreType
+	Positive         []bin
+	Negative         []bin
+	ZeroCount        float64
+	Count            float64
+	Sum              float64
+	Min              float64
+	Max              float64
+}
+
+// valid reports whether the decoded data can be loaded into a sketch with
+// mapping: a known store type, bin indices within the mapping's range, finite
+// non-negative counts, bin counts adding up to Count, and finite Min <= Max
+// (or the empty sketch's infinities when Count is zero). Out-of-range indices
+// would make the dense store allocate a slice spanning them.
+func (d *sketchData) valid(mapping logarithmicMapping) bool {
+	if d.StoreType != DenseStore && d.StoreType != SparseStore {
+		return false
+	}
+
+	if !validCount(d.ZeroCount) || !validCount(d.Count) {
+		return false
+	}
+
+	if d.Count == 0 {
+		if !math.IsInf(d.Min, 1) || !math.IsInf(d.Max, -1) {
+			return false
+		}
+	} else if math.IsNaN(d.Min) || math.IsInf(d.Min, 0) || math.IsNaN(d.Max) || math.IsInf(d.Max, 0) || d.Min > d.Max {
+		return false
+	}
+
+	minIndex, maxIndex := mapping.minIndex(), mapping.maxIndex()
+	total := d.ZeroCount
+
+	for _, bins := range [][]bin{d.Positive, d.Negative} {
+		for _, b := range bins {
+			if b.Index < minIndex || b.Index > maxIndex || !validCount(b.Count) {
+				return false
+			}
+			total += b.Count
+		}
+	}
+
+	// Allow for rounding in the order counts were added
+	return math.Abs(total-d.Count) <= 1e-9*math.Max(d.Count, 1)
+}
+
+// validCount reports whether c is a finite, non-negative count.
+func validCount(c float64) bool {
+	return c >= 0 && !math.IsInf(c, 1)
+}
+
+// gobWriter implements io.Writer for gob encoding
+type gobWriter struct {
+	buf *[]byte
+}
+
+func (w *gobWriter) Write(p []byte) (int, error) {
+	*w.buf = append(*w.buf, p...)
+	return len(p), nil
+}
+
+// gobReader implements io.Reader for gob decoding
+type gobReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *gobReader) Read(p []byte) (int, error) {
+	if r.pos >= len(r.buf) {
+		return 0, nil
+	}
+	n := copy(p, r.buf[r.pos:])
+	r.pos += n
+	return n, nil
+}
@@ -0,0 +1,423 @@
+package ddsketch
+
+import (
+	"encoding/gob"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func assertRelative(t *testing.T, expected, actual, accuracy float64, msgAndArgs ...interface{}) {
+	t.Helper()
+	assert.LessOrEqual(t, math.Abs(actual-expected), accuracy*math.Abs(expected)+1e-12, msgAndArgs...)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("create with relative accuracy", func(t *testing.T) {
+		s := New(0.02)
+		assert.NotNil(t, s)
+		assert.Equal(t, 0.02, s.RelativeAccuracy())
+		assert.Equal(t, 0.0, s.Count())
+	})
+
+	t.Run("invalid accuracy uses default", func(t *testing.T) {
+		assert.Equal(t, 0.01, New(0).RelativeAccuracy())
+		assert.Equal(t, 0.01, New(-1).RelativeAccuracy())
+		assert.Equal(t, 0.01, New(1).RelativeAccuracy())
+	})
+
+	t.Run("sparse store", func(t *testing.T) {
+		s := NewWithStore(0.01, SparseStore)
+		assert.IsType(t, &sparseStore{}, s.positive)
+		assert.IsType(t, &sparseStore{}, s.negative)
+	})
+}
+
+func TestAdd(t *testing.T) {
+	t.Run("statistics", func(t *testing.T) {
+		s := New(0.01)
+		s.Add(3)
+		s.Add(-2)
+		s.Add(0)
+		s.AddWeighted(10, 2)
+
+		assert.Equal(t, 5.0, s.Count())
+		assert.Equal(t, 21.0, s.Sum())
+		assert.Equal(t, 4.2, s.Mean())
+		assert.Equal(t, -2.0, s.Min())
+		assert.Equal(t, 10.0, s.Max())
+	})
+
+	t.Run("invalid values are ignored", func(t *testing.T) {
+		s := New(0.01)
+		s.Add(math.NaN())
+		s.Add(math.Inf(1))
+		s.Add(math.Inf(-1))
+		s.AddWeighted(1, 0)
+		s.AddWeighted(1, -1)
+
+		assert.Equal(t, 0.0, s.Count())
+	})
+
+	t.Run("tiny values count as zero", func(t *testing.T) {
+		s := New(0.01)
+		s.Add(1e-320)
+		s.Add(-1e-320)
+
+		assert.Equal(t, 2.0, s.zeroCount)
+		assert.Equal(t, 0.0, s.Quantile(0.5))
+	})
+}
+
+func TestQuantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	distributions := map[string]func() float64{
+		"uniform":     func() float64 { return rng.Float64() * 1000 },
+		"exponential": func() float64 { return rng.ExpFloat64() * 0.05 },
+		"lognormal":   func() float64 { return math.Exp(rng.NormFloat64() * 2) },
+		"normal":      func() float64 { return rng.NormFloat64() * 100 },
+		"negative":    func() float64 { return -rng.ExpFloat64() * 10 },
+	}
+
+	for _, storeType := range []StoreType{DenseStore, SparseStore} {
+		for name, gen := range distributions {
+			t.Run(name, func(t *testing.T) {
+				accuracy := 0.01
+				s := NewWithStore(accuracy, storeType)
+
+				values := make([]float64, 10000)
+				for i := range values {
+					values[i] = gen()
+					s.Add(values[i])
+				}
+				sort.Float64s(values)
+
+				for _, q := range []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 0.999, 1} {
+					expected := exactQuantile(values, q)
+					assertRelative(t, expected, s.Quantile(q), accuracy, "q=%.3f", q)
+				}
+			})
+		}
+	}
+
+	t.Run("empty sketch", func(t *testing.T) {
+		assert.True(t, math.IsNaN(New(0.01).Quantile(0.5)))
+	})
+
+	t.Run("mixed signs with zeros", func(t *testing.T) {
+		s := New(0.01)
+		var values []float64
+		for i := -50; i <= 50; i++ {
+			values = append(values, float64(i))
+		}
+		for range 20 {
+			values = append(values, 0)
+		}
+		for _, v := range values {
+			s.Add(v)
+		}
+		sort.Float64s(values)
+
+		assert.Equal(t, 0.0, s.Quantile(0.5))
+		assert.Equal(t, -50.0, s.Quantile(0))
+		assert.Equal(t, 50.0, s.Quantile(1))
+		assertRelative(t, exactQuantile(values, 0.05), s.Quantile(0.05), 0.01)
+		assertRelative(t, exactQuantile(values, 0.95), s.Quantile(0.95), 0.01)
+	})
+}
+
+func TestCDF(t *testing.T) {
+	t.Run("uniform", func(t *testing.T) {
+		s := New(0.01)
+		for i := 1; i <= 1000; i++ {
+			s.Add(float64(i))
+		}
+
+		assert.Equal(t, 0.0, s.CDF(0))
+		assert.Equal(t, 1.0, s.CDF(1000))
+		assert.InDelta(t, 0.5, s.CDF(500), 0.01)
+		assert.InDelta(t, 0.9, s.CDF(900), 0.01)
+	})
+
+	t.Run("negative and zero", func(t *testing.T) {
+		s := New(0.01)
+		s.Add(-10)
+		s.Add(-1)
+		s.Add(0)
+		s.Add(1)
+		s.Add(10)
+
+		assert.Equal(t, 0.2, s.CDF(-5))
+		assert.Equal(t, 0.6, s.CDF(0))
+		assert.Equal(t, 0.8, s.CDF(5))
+	})
+
+	t.Run("empty sketch", func(t *testing.T) {
+		assert.True(t, math.IsNaN(New(0.01).CDF(1)))
+	})
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("merge is exact", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(7))
+
+		all := New(0.01)
+		parts := make([]*DDSketch, 10)
+		for i := range parts {
+			parts[i] = New(0.01)
+		}
+
+		for i := range 10000 {
+			v := rng.NormFloat64() * 50
+			all.Add(v)
+			parts[i%len(parts)].Add(v)
+		}
+
+		// Merge in two different orders
+		forward := New(0.01)
+		for _, p := range parts {
+			forward.Merge(p)
+		}
+
+		backward := NewWithStore(0.01, SparseStore)
+		for i := len(parts) - 1; i >= 0; i-- {
+			backward.Merge(parts[i])
+		}
+
+		for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+			assert.Equal(t, all.Quantile(q), forward.Quantile(q))
+			assert.Equal(t, all.Quantile(q), backward.Quantile(q))
+		}
+
+		assert.Equal(t, all.Count(), forward.Count())
+		assert.Equal(t, all.Min(), forward.Min())
+		assert.Equal(t, all.Max(), forward.Max())
+		assert.InDelta(t, all.Sum(), forward.Sum(), 1e-6)
+	})
+
+	t.Run("merge with different accuracy", func(t *testing.T) {
+		s1 := New(0.01)
+		s2 := New(0.02)
+		for i := 1; i <= 1000; i++ {
+			s1.Add(float64(i))
+			s2.Add(float64(-i))
+		}
+
+		s1.Merge(s2)
+		assert.Equal(t, 2000.0, s1.Count())
+		assertRelative(t, 500, s1.Quantile(0.75), 0.03)
+		assertRelative(t, -500, s1.Quantile(0.25), 0.03)
+	})
+
+	t.Run("merge nil, empty and self", func(t *testing.T) {
+		s := New(0.01)
+		s.Add(1)
+
+		s.Merge(nil)
+		s.Merge(New(0.01))
+		s.Merge(s)
+
+		assert.Equal(t, 1.0, s.Count())
+	})
+}
+
+func TestReset(t *testing.T) {
+	for _, storeType := range []StoreType{DenseStore, SparseStore} {
+		s := NewWithStore(0.01, storeType)
+		s.Add(1)
+		s.Add(-1)
+		s.Add(0)
+		s.Reset()
+
+		assert.Equal(t, 0.0, s.Count())
+		assert.True(t, math.IsNaN(s.Min()))
+		assert.True(t, math.IsNaN(s.Max()))
+		assert.True(t, math.IsNaN(s.Mean()))
+
+		s.Add(5)
+		assert.Equal(t, 5.0, s.Quantile(0.5))
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	for _, storeType := range []StoreType{DenseStore, SparseStore} {
+		s := NewWithStore(0.02, storeType)
+		for i := -500; i < 1000; i++ {
+			s.Add(float64(i) / 10)
+		}
+
+		data, err := s.Export()
+		require.NoError(t, err)
+
+		imported, err := Import(data)
+		require.NoError(t, err)
+
+		assert.Equal(t, s.RelativeAccuracy(), imported.RelativeAccuracy())
+		assert.Equal(t, storeType, imported.storeType)
+		assert.Equal(t, s.Count(), imported.Count())
+		assert.Equal(t, s.Sum(), imported.Sum())
+		assert.Equal(t, s.Min(), imported.Min())
+		assert.Equal(t, s.Max(), imported.Max())
+		for _, q := range []float64{0.1, 0.5, 0.9} {
+			assert.Equal(t, s.Quantile(q), imported.Quantile(q))
+		}
+	}
+
+	t.Run("empty sketch", func(t *testing.T) {
+		data, err := New(0.01).Export()
+		require.NoError(t, err)
+
+		imported, err := Import(data)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, imported.Count())
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		_, err := Import([]byte{0, 1, 2, 3})
+		assert.Error(t, err)
+	})
+
+	t.Run("crafted data", func(t *testing.T) {
+		encode := func(d sketchData) []byte {
+			var buf []byte
+			require.NoError(t, gob.NewEncoder(&gobWriter{buf: &buf}).Encode(&d))
+			return buf
+		}
+
+		valid := sketchData{
+			RelativeAccuracy: 0.01,
+			Positive:         []bin{{Index: 10, Count: 2}},
+			Negative:         []bin{{Index: 5, Count: 1}},
+			ZeroCount:        1,
+			Count:            4,
+			Min:              -1,
+			Max:              1.2,
+		}
+		_, err := Import(encode(valid))
+		require.NoError(t, err)
+
+		tests := []struct {
+			name   string
+			modify func(d *sketchData)
+		}{
+			{"huge positive index", func(d *sketchData) { d.Positive[0].Index = 1 << 40 }},
+			{"huge negative index", func(d *sketchData) { d.Negative[0].Index = -1 << 40 }},
+			{"index spread", func(d *sketchData) {
+				d.Positive = []bin{{Index: -1 << 40, Count: 1}, {Index: 1 << 40, Count: 1}}
+			}},
+			{"NaN count", func(d *sketchData) { d.Positive[0].Count = math.NaN() }},
+			{"infinite count", func(d *sketchData) { d.Positive[0].Count = math.Inf(1) }},
+			{"negative count", func(d *sketchData) { d.Negative[0].Count = -1 }},
+			{"negative zero count", func(d *sketchData) { d.ZeroCount = -1 }},
+			{"count mismatch", func(d *sketchData) { d.Count = 10 }},
+			{"NaN total", func(d *sketchData) { d.Count = math.NaN() }},
+			{"unknown store type", func(d *sketchData) { d.StoreType = SparseStore + 1 }},
+			{"min above max", func(d *sketchData) { d.Min, d.Max = 2, 1 }},
+			{"NaN min", func(d *sketchData) { d.Min = math.NaN() }},
+			{"infinite max", func(d *sketchData) { d.Max = math.Inf(1) }},
+			{"empty with finite min", func(d *sketchData) {
+				d.Positive, d.Negative, d.ZeroCount, d.Count = nil, nil, 0, 0
+			}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				d := valid
+				d.Positive = slices.Clone(valid.Positive)
+				d.Negative = slices.Clone(valid.Negative)
+				tt.modify(&d)
+
+				_, err := Import(encode(d))
+				assert.ErrorIs(t, err, ErrInvalidData)
+			})
+		}
+	})
+}
+
+func TestConcurrency(t *testing.T) {
+	s := New(0.01)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for i := range 1000 {
+				s.Add(float64(offset*1000 + i + 1))
+				if i%100 == 0 {
+					_ = s.Quantile(0.99)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8000.0, s.Count())
+}
+
+func BenchmarkDDSketch_Add(b *testing.B) {
+	s := New(0.01)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		s.Add(float64(i%10000) + 1)
+		i++
+	}
+}
+
+func BenchmarkDDSketch_Quantile(b *testing.B) {
+	s := New(0.01)
+	for i := range 10000 {
+		s.Add(float64(i) + 1)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = s.Quantile(0.99)
+	}
+}
+
+func BenchmarkDDSketch_Merge(b *testing.B) {
+	s1 := New(0.01)
+	s2 := New(0.01)
+	for i := range 1000 {
+		s1.Add(float64(i) + 1)
+		s2.Add(float64(i) + 1001)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		s1.Merge(s2)
+	}
+}
+
+func FuzzDDSketch_Add(f *testing.F) {
+	f.Add(1.0)
+	f.Add(0.0)
+	f.Add(-1.0)
+	f.Add(1e-300)
+	f.Add(1e300)
+
+	f.Fuzz(func(t *testing.T, val float64) {
+		s := New(0.01)
+		s.Add(val)
+
+		if math.IsNaN(val) || math.IsInf(val, 0) || math.Abs(val) > s.mapping.maxIndexable {
+			return
+		}
+
+		got := s.Quantile(0.5)
+		if math.Abs(got-val) > 0.01*math.Abs(val)+1e-300 {
+			t.Errorf("quantile %v outside relative accuracy of %v", got, val)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package ddsketch
+
+import "math"
+
+// minNormalFloat64 is the smallest positive normal float64.
+const minNormalFloat64 = 0x1p-1022
+
+// logarithmicMapping maps positive values to integer bin indices so that
+// every value in a bin is within relativeAccuracy of the bin's
+// representative value.
+//
+// With gamma = (1+α)/(1-α), bin i covers (gamma^(i-1), gamma^i] and its
+// representative value is 2*gamma^i/(gamma+1).
+type logarithmicMapping struct {
+	relativeAccuracy float64
+	gamma            float64
+	multiplier       float64
+	minIndexable     float64
+	maxIndexable     float64
+}
+
+func newLogarithmicMapping(relativeAccuracy float64) logarithmicMapping {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	multiplier := 1 / math.Log(gamma)
+
+	return logarithmicMapping{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		multiplier:       multiplier,
+		// Keep indices well inside int32 range and values inside float64 range
+		minIndexable: math.Max(
+			math.Exp(math.MinInt32/multiplier+1),
+			minNormalFloat64*gamma,
+		),
+		maxIndexable: math.Min(
+			math.Exp(math.MaxInt32/multiplier-1),
+			math.MaxFloat64/gamma,
+		),
+	}
+}
+
+// index returns the bin index for a positive value.
+func (m logarithmicMapping) index(value float64) int {
+	return int(math.Ceil(math.Log(value) * m.multiplier))
+}
+
+// value returns the representative value of a bin.
+func (m logarithmicMapping) value(index int) float64 {
+	return 2 * math.Exp(float64(index)/m.multiplier) / (1 + m.gamma)
+}
+
+// minIndex returns the lowest index of an indexable value.
+func (m logarithmicMapping) minIndex() int {
+	return m.index(m.minIndexable)
+}
+
+// maxIndex returns the highest index of an indexable value.
+func (m logarithmicMapping) maxIndex() int {
+	return m.index(m.maxIndexable)
+}
+
+func (m logarithmicMapping) equals(other logarithmicMapping) bool {
+	return m.gamma == other.gamma
+}
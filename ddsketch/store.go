@@ -0,0 +1,175 @@
+package ddsketch
+
+import "sort"
+
+// StoreType selects how bin counts are stored.
+type StoreType uint8
+
+const (
+	// DenseStore keeps counts in a contiguous slice covering the range of
+	// indices seen. Fast, and compact for values within a few orders of
+	// magnitude.
+	DenseStore StoreType = iota
+
+	// SparseStore keeps counts in a map. Compact when values are spread
+	// over a very wide range with few distinct bins.
+	SparseStore
+)
+
+// store holds counts per bin index.
+type store interface {
+	add(index int, count float64)
+	count() float64
+	isEmpty() bool
+	// forEach visits bins in ascending index order until fn returns false.
+	forEach(fn func(index int, count float64) bool)
+	// forEachDesc visits bins in descending index order until fn returns false.
+	forEachDesc(fn func(index int, count float64) bool)
+	reset()
+}
+
+func newStore(typ StoreType) store {
+	if typ == SparseStore {
+		return newSparseStore()
+	}
+	return newDenseStore()
+}
+
+// keyAtRank returns the index of the bin holding the value of the given rank
+// (0-based, in ascending order).
+func keyAtRank(s store, rank float64) int {
+	key := 0
+	cumulative := float64(0)
+
+	s.forEach(func(index int, count float64) bool {
+		key = index
+		cumulative += count
+		return cumulative <= rank
+	})
+
+	return key
+}
+
+// denseStore stores counts in a slice, where bins[i] holds index offset+i.
+type denseStore struct {
+	bins   []float64
+	offset int
+	total  float64
+}
+
+func newDenseStore() *denseStore {
+	return &denseStore{}
+}
+
+func (s *denseStore) add(index int, count float64) {
+	if len(s.bins) == 0 {
+		s.bins = make([]float64, 1, 64)
+		s.offset = index
+	}
+
+	if index < s.offset {
+		// Grow to the left
+		grow := s.offset - index
+		bins := make([]float64, len(s.bins)+grow, cap(s.bins)+grow)
+		copy(bins[grow:], s.bins)
+		s.bins = bins
+		s.offset = index
+	} else if index >= s.offset+len(s.bins) {
+		// Grow to the right
+		s.bins = append(s.bins, make([]float64, index-s.offset-len(s.bins)+1)...)
+	}
+
+	s.bins[index-s.offset] += count
+	s.total += count
+}
+
+func (s *denseStore) count() float64 {
+	return s.total
+}
+
+func (s *denseStore) isEmpty() bool {
+	return s.total == 0
+}
+
+func (s *denseStore) forEach(fn func(index int, count float64) bool) {
+	for i, c := range s.bins {
+		if c == 0 {
+			continue
+		}
+		if !fn(s.offset+i, c) {
+			return
+		}
+	}
+}
+
+func (s *denseStore) forEachDesc(fn func(index int, count float64) bool) {
+	for i := len(s.bins) - 1; i >= 0; i-- {
+		if s.bins[i] == 0 {
+			continue
+		}
+		if !fn(s.offset+i, s.bins[i]) {
+			return
+		}
+	}
+}
+
+func (s *denseStore) reset() {
+	s.bins = s.bins[:0]
+	s.offset = 0
+	s.total = 0
+}
+
+// sparseStore stores counts in a map keyed by bin index.
+type sparseStore struct {
+	bins  map[int]float64
+	total float64
+}
+
+func newSparseStore() *sparseStore {
+	return &sparseStore{bins: make(map[int]float64)}
+}
+
+func (s *sparseStore) add(index int, count float64) {
+	s.bins[index] += count
+	s.total += count
+}
+
+func (s *sparseStore) count() float64 {
+	return s.total
+}
+
+func (s *sparseStore) isEmpty() bool {
+	return s.total == 0
+}
+
+func (s *sparseStore) sortedKeys() []int {
+	keys := make([]int, 0, len(s.bins))
+	for k := range s.bins {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	return keys
+}
+
+func (s *sparseStore) forEach(fn func(index int, count float64) bool) {
+	for _, k := range s.sortedKeys() {
+		if !fn(k, s.bins[k]) {
+			return
+		}
+	}
+}
+
+func (s *sparseStore) forEachDesc(fn func(index int, count float64) bool) {
+	keys := s.sortedKeys()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !fn(keys[i], s.bins[keys[i]]) {
+			return
+		}
+	}
+}
+
+func (s *sparseStore) reset() {
+	clear(s.bins)
+	s.total = 0
+}
@@ -0,0 +1,77 @@
+package ddsketch
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func collectDesc(s store) []bin {
+	var bins []bin
+	s.forEachDesc(func(index int, count float64) bool {
+		bins = append(bins, bin{Index: index, Count: count})
+		return true
+	})
+	return bins
+}
+
+func TestStores(t *testing.T) {
+	for name, newFn := range map[string]func() store{
+		"dense":  func() store { return newDenseStore() },
+		"sparse": func() store { return newSparseStore() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := newFn()
+			assert.True(t, s.isEmpty())
+
+			s.add(5, 1)
+			s.add(-3, 2)
+			s.add(10, 1)
+			s.add(5, 1)
+
+			assert.False(t, s.isEmpty())
+			assert.Equal(t, 5.0, s.count())
+			assert.Equal(t, []bin{{-3, 2}, {5, 2}, {10, 1}}, collectBins(s))
+			assert.Equal(t, []bin{{10, 1}, {5, 2}, {-3, 2}}, collectDesc(s))
+
+			assert.Equal(t, -3, keyAtRank(s, 0))
+			assert.Equal(t, -3, keyAtRank(s, 1.5))
+			assert.Equal(t, 5, keyAtRank(s, 2))
+			assert.Equal(t, 10, keyAtRank(s, 4))
+			assert.Equal(t, 10, keyAtRank(reversed{s}, 0))
+
+			s.reset()
+			assert.True(t, s.isEmpty())
+			assert.Empty(t, collectBins(s))
+
+			s.add(100, 1)
+			assert.Equal(t, []bin{{100, 1}}, collectBins(s))
+		})
+	}
+
+	t.Run("newStore selects type", func(t *testing.T) {
+		assert.IsType(t, &denseStore{}, newStore(DenseStore))
+		assert.IsType(t, &sparseStore{}, newStore(SparseStore))
+	})
+}
+
+func TestMapping(t *testing.T) {
+	for _, accuracy := range []float64{0.001, 0.01, 0.05} {
+		m := newLogarithmicMapping(accuracy)
+
+		for _, v := range []float64{1e-9, 0.001, 0.5, 1, 3.14, 1000, 1e12} {
+			got := m.value(m.index(v))
+			assert.LessOrEqual(t, (got-v)/v, accuracy+1e-12, "value %v", v)
+			assert.GreaterOrEqual(t, (got-v)/v, -accuracy-1e-12, "value %v", v)
+		}
+	}
+
+	t.Run("indexable range", func(t *testing.T) {
+		m := newLogarithmicMapping(0.01)
+		assert.Greater(t, m.minIndexable, 0.0)
+		assert.Less(t, m.maxIndexable, math.MaxFloat64)
+		assert.False(t, m.equals(newLogarithmicMapping(0.02)))
+		assert.True(t, m.equals(newLogarithmicMapping(0.01)))
+	})
+}
@@ -0,0 +1,103 @@
+package ewma
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time.
+// Inject a ManualClock for deterministic tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the wall clock used by default.
+var SystemClock Clock = systemClock{}
+
+// ManualClock is a Clock that only moves when told to.
+// Safe for concurrent use.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a clock stopped at t.
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{now: t}
+}
+
+// Now returns the clock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Decay computes exponential decay for a fixed half-life.
+// A value decays to half its size every half-life:
+//
+//	value(t) = value(t0) * exp(-ln2/halfLife * (t - t0))
+//
+// Decay is the shared math behind Rate and the decaying trackers in
+// countmin, hyperloglog and spacesaving.
+type Decay struct {
+	halfLife time.Duration
+	lambda   float64 // decay constant per second
+}
+
+// NewDecay creates a Decay with the given half-life.
+// If halfLife is not positive, 60 seconds is used.
+func NewDecay(halfLife time.Duration) Decay {
+	if halfLife <= 0 {
+		halfLife = 60 * time.Second
+	}
+
+	return Decay{
+		halfLife: halfLife,
+		lambda:   math.Ln2 / halfLife.Seconds(),
+	}
+}
+
+// HalfLife returns the decay half-life.
+func (d Decay) HalfLife() time.Duration {
+	return d.halfLife
+}
+
+// Lambda returns the decay constant per second (ln2 / half-life).
+func (d Decay) Lambda() float64 {
+	return d.lambda
+}
+
+// Factor returns the multiplier applied to a value after elapsed time.
+// Returns 1 if elapsed is not positive.
+func (d Decay) Factor(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Exp(-d.lambda * elapsed.Seconds())
+}
+
+// Apply decays a value recorded at from to time to.
+// Values are not grown when to is before from.
+func (d Decay) Apply(value float64, from, to time.Time) float64 {
+	return value * d.Factor(to.Sub(from))
+}
@@ -0,0 +1,98 @@
+package ewma
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualClock(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	c := NewManualClock(base)
+	assert.Equal(t, base, c.Now())
+
+	c.Advance(time.Minute)
+	assert.Equal(t, base.Add(time.Minute), c.Now())
+
+	c.Set(base)
+	assert.Equal(t, base, c.Now())
+
+	t.Run("concurrent use", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					c.Advance(time.Millisecond)
+					_ = c.Now()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, base.Add(400*time.Millisecond), c.Now())
+	})
+}
+
+func TestSystemClock(t *testing.T) {
+	before := time.Now()
+	now := SystemClock.Now()
+	assert.False(t, now.Before(before))
+}
+
+func TestDecay(t *testing.T) {
+	t.Run("half-life", func(t *testing.T) {
+		d := NewDecay(10 * time.Second)
+		assert.Equal(t, 10*time.Second, d.HalfLife())
+		assert.InDelta(t, math.Ln2/10, d.Lambda(), 1e-12)
+		assert.InDelta(t, 0.5, d.Factor(10*time.Second), 1e-12)
+		assert.InDelta(t, 0.25, d.Factor(20*time.Second), 1e-12)
+	})
+
+	t.Run("non-positive elapsed does not decay", func(t *testing.T) {
+		d := NewDecay(time.Second)
+		assert.Equal(t, 1.0, d.Factor(0))
+		assert.Equal(t, 1.0, d.Factor(-time.Second))
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		d := NewDecay(time.Minute)
+		base := time.Unix(1700000000, 0)
+		assert.InDelta(t, 50.0, d.Apply(100, base, base.Add(time.Minute)), 1e-9)
+		assert.Equal(t, 100.0, d.Apply(100, base, base.Add(-time.Minute)))
+	})
+
+	t.Run("default half-life", func(t *testing.T) {
+		assert.Equal(t, 60*time.Second, NewDecay(0).HalfLife())
+		assert.Equal(t, 60*time.Second, NewDecay(-time.Second).HalfLife())
+	})
+}
+
+func TestRateWithClock(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	clock := NewManualClock(base)
+	r := NewRateWithClock(10*time.Second, clock)
+
+	r.Add(8)
+	assert.Equal(t, 8.0, r.Rate())
+
+	clock.Advance(10 * time.Second)
+	assert.InDelta(t, 4.0, r.Rate(), 1e-9)
+
+	r.Add(1)
+	assert.InDelta(t, 5.0, r.Rate(), 1e-9)
+	assert.Equal(t, base.Add(10*time.Second), r.Snapshot().LastUpdate)
+
+	r.Set(2)
+	clock.Advance(10 * time.Second)
+	assert.InDelta(t, 1.0, r.Rate(), 1e-9)
+
+	t.Run("nil clock uses system clock", func(t *testing.T) {
+		r := NewRateWithClock(time.Second, nil)
+		r.Add(1)
+		assert.InDelta(t, 1.0, r.Rate(), 0.1)
+	})
+}
@@ -26,6 +26,8 @@ type EWMA struct {
 	uncounted   uint64
 	initialized bool
 	interval    time.Duration
+	clock       Clock     // non-nil in auto-tick mode
+	lastTick    time.Time // start of the current interval in auto-tick mode
 }
 
 // New creates a new EWMA with the specified alpha value.
@@ -48,6 +50,23 @@ func New(alpha float64, interval time.Duration) *EWMA {
 	}
 }
 
+// NewAutoTick creates an EWMA that ticks itself. Instead of relying on a
+// caller to invoke Tick every interval, it applies all intervals that have
+// elapsed on clock before every read and write, so the average stays
+// correct even if no ticker runs or a ticker stalls.
+// If clock is nil, SystemClock is used.
+func NewAutoTick(alpha float64, interval time.Duration, clock Clock) *EWMA {
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	e := New(alpha, interval)
+	e.clock = clock
+	e.lastTick = clock.Now()
+
+	return e
+}
+
 // NewWithAlpha creates an EWMA with custom alpha value.
 func NewWithAlpha(alpha float64) *EWMA {
 	return New(alpha, Interval)
@@ -74,6 +93,7 @@ func New15MinuteEWMA() *EWMA {
 // Add records one or more events.
 func (e *EWMA) Add(n uint64) {
 	e.mu.Lock()
+	e.catchUp()
 	e.uncounted += n
 	e.mu.Unlock()
 }
@@ -90,6 +110,8 @@ func (e *EWMA) UpdateWithValue(value float64) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	e.catchUp()
+
 	if !e.initialized {
 		e.rate = value
 		e.initialized = true
@@ -109,10 +131,22 @@ func (e *EWMA) UpdateWithValue(value float64) {
 //	        myEWMA.Tick()
 //	    }
 //	}()
+//
+// In auto-tick mode, Tick only applies intervals that have already elapsed.
 func (e *EWMA) Tick() {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	if e.clock != nil {
+		e.catchUp()
+		return
+	}
+
+	e.tick()
+}
+
+// tick applies one interval. Caller must hold e.mu.
+func (e *EWMA) tick() {
 	// Calculate instantaneous rate (events per interval)
 	instantRate := float64(e.uncounted) / e.interval.Seconds()
 	e.uncounted = 0
@@ -125,8 +159,37 @@ func (e *EWMA) Tick() {
 	}
 }
 
+// catchUp applies every interval elapsed since the last tick in auto-tick
+// mode. Events recorded since then belong to the first interval; the rest
+// were idle and only decay the rate. Caller must hold e.mu.
+func (e *EWMA) catchUp() {
+	if e.clock == nil {
+		return
+	}
+
+	elapsed := e.clock.Now().Sub(e.lastTick)
+	if elapsed < e.interval {
+		return
+	}
+
+	missed := int64(elapsed / e.interval)
+	e.lastTick = e.lastTick.Add(time.Duration(missed) * e.interval)
+
+	e.tick()
+	if missed > 1 {
+		e.rate *= math.Pow(1-e.alpha, float64(missed-1))
+	}
+}
+
 // Rate returns the current rate (events per second).
 func (e *EWMA) Rate() float64 {
+	if e.clock != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.catchUp()
+		return e.rate
+	}
+
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	return e.rate
@@ -135,8 +198,10 @@ func (e *EWMA) Rate() float64 {
 // Snapshot returns a snapshot of the current state.
 // Use this for consistent reads of multiple values.
 func (e *EWMA) Snapshot() Snapshot {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.catchUp()
 
 	return Snapshot{
 		Rate:        e.rate,
@@ -153,6 +218,9 @@ func (e *EWMA) Reset() {
 	e.rate = 0
 	e.uncounted = 0
 	e.initialized = false
+	if e.clock != nil {
+		e.lastTick = e.clock.Now()
+	}
 }
 
 // Set sets the rate directly.
@@ -161,6 +229,7 @@ func (e *EWMA) Set(rate float64) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	e.catchUp()
 	e.rate = rate
 	e.initialized = true
 }
@@ -189,6 +258,16 @@ func NewMovingAverage() *MovingAverage {
 	}
 }
 
+// NewAutoTickMovingAverage creates a MovingAverage whose windows tick
+// themselves from clock (see NewAutoTick). If clock is nil, SystemClock is used.
+func NewAutoTickMovingAverage(clock Clock) *MovingAverage {
+	return &MovingAverage{
+		m1:  NewAutoTick(1-math.Exp(-float64(Interval)/float64(time.Minute)), Interval, clock),
+		m5:  NewAutoTick(1-math.Exp(-float64(Interval)/float64(5*time.Minute)), Interval, clock),
+		m15: NewAutoTick(1-math.Exp(-float64(Interval)/float64(15*time.Minute)), Interval, clock),
+	}
+}
+
 // Add records one or more events across all time windows.
 func (ma *MovingAverage) Add(n uint64) {
 	ma.m1.Add(n)
@@ -0,0 +1,407 @@
+package ewma
+
+import (
+	"math"
+	"sync"
+)
+
+// Holt implements double exponential smoothing (Holt's linear method).
+// It tracks a smoothed level and trend, so unlike EWMA it follows ramps
+// without lagging behind them.
+//
+// Use cases:
+//   - Traffic baselines with steady growth
+//   - Capacity forecasting
+//   - Anomaly detection on trending series
+//
+// Observations are assumed to arrive at a fixed interval; Forecast steps
+// are measured in that interval.
+type Holt struct {
+	mu sync.RWMutex
+	holtState
+}
+
+// NewHolt creates a Holt smoother.
+//
+// Parameters:
+//   - alpha: level smoothing factor in (0, 1], default 0.5
+//   - beta: trend smoothing factor in (0, 1], default 0.1
+func NewHolt(alpha, beta float64) *Holt {
+	return &Holt{holtState: newHoltState(alpha, beta, 0, 0)}
+}
+
+// Update records the next observation.
+func (h *Holt) Update(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.update(value)
+}
+
+// Forecast returns the predicted value steps observations ahead.
+// Forecast(1) predicts the next observation.
+// Returns NaN until two observations have been recorded.
+func (h *Holt) Forecast(steps int) float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.forecast(steps)
+}
+
+// PredictionInterval returns the forecast steps ahead widened by z
+// standard errors (z=1.96 for a 95% interval).
+// Returns NaN bounds until the residual variance can be estimated.
+func (h *Holt) PredictionInterval(steps int, z float64) (lower, upper float64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.interval(steps, z)
+}
+
+// Score returns how many standard errors value lies from the one-step
+// forecast. The sign gives the direction; |score| > 3 is a common anomaly
+// threshold. Score does not record the value; call Update afterwards.
+// Returns 0 until the residual variance can be estimated.
+func (h *Holt) Score(value float64) float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.score(value)
+}
+
+// Level returns the smoothed level.
+func (h *Holt) Level() float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.level
+}
+
+// Trend returns the smoothed trend per observation.
+func (h *Holt) Trend() float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.trend
+}
+
+// Count returns the number of observations recorded.
+func (h *Holt) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.count
+}
+
+// Reset clears all state.
+func (h *Holt) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reset()
+}
+
+// HoltWinters implements triple exponential smoothing with additive
+// seasonality. On top of Holt's level and trend it learns one seasonal
+// offset per position in the period, so a daily traffic ramp is forecast
+// rather than flagged.
+//
+// Use cases:
+//   - Traffic baselines with daily or weekly cycles
+//   - Seasonal anomaly detection
+//   - Forecasting periodic load
+//
+// The first two periods of observations initialize the model; Forecast
+// returns NaN until then.
+type HoltWinters struct {
+	mu sync.RWMutex
+	holtState
+}
+
+// NewHoltWinters creates a Holt-Winters smoother.
+//
+// Parameters:
+//   - alpha: level smoothing factor in (0, 1], default 0.5
+//   - beta: trend smoothing factor in (0, 1], default 0.1
+//   - gamma: seasonal smoothing factor in (0, 1], default 0.1
+//   - period: observations per season (e.g. 24 for hourly data with a daily cycle), default 24
+func NewHoltWinters(alpha, beta, gamma float64, period int) *HoltWinters {
+	if period < 2 {
+		period = 24
+	}
+
+	return &HoltWinters{holtState: newHoltState(alpha, beta, gamma, period)}
+}
+
+// Update records the next observation.
+func (hw *HoltWinters) Update(value float64) {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+	hw.update(value)
+}
+
+// Forecast returns the predicted value steps observations ahead.
+// Forecast(1) predicts the next observation.
+// Returns NaN until two full periods have been recorded.
+func (hw *HoltWinters) Forecast(steps int) float64 {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+	return hw.forecast(steps)
+}
+
+// PredictionInterval returns the forecast steps ahead widened by z
+// standard errors (z=1.96 for a 95% interval).
+// Returns NaN bounds until the residual variance can be estimated.
+func (hw *HoltWinters) PredictionInterval(steps int, z float64) (lower, upper float64) {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+	return hw.interval(steps, z)
+}
+
+// Score returns how many standard errors value lies from the one-step
+// forecast. See Holt.Score.
+func (hw *HoltWinters) Score(value float64) float64 {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+	return hw.score(value)
+}
+
+// Level returns the smoothed deseasonalized level.
+func (hw *HoltWinters) Level() float64 {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+	return hw.level
+}
+
+// Trend returns the smoothed trend per observation.
+func (hw *HoltWinters) Trend() float64 {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+	return hw.trend
+}
+
+// Seasonal returns a copy of the seasonal offsets, starting with the
+// position of the next observation.
+func (hw *HoltWinters) Seasonal() []float64 {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+
+	out := make([]float64, hw.period)
+	for i := range out {
+		out[i] = hw.seasonal[(hw.pos+i)%hw.period]
+	}
+	return out
+}
+
+// Period returns the number of observations per season.
+func (hw *HoltWinters) Period() int {
+	return hw.period
+}
+
+// Count returns the number of observations recorded.
+func (hw *HoltWinters) Count() int {
+	hw.mu.RLock()
+	defer hw.mu.RUnlock()
+	return hw.count
+}
+
+// Reset clears all state.
+func (hw *HoltWinters) Reset() {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+	hw.reset()
+}
+
+// holtState holds the smoothing state shared by Holt and HoltWinters.
+// A zero period disables seasonality. Callers provide locking.
+type holtState struct {
+	alpha, beta, gamma float64
+	period             int
+
+	level    float64
+	trend    float64
+	seasonal []float64
+	pos      int       // seasonal index of the next observation
+	warmup   []float64 // observations buffered before initialization
+	ready    bool
+	count    int
+
+	sse       float64 // sum of squared one-step errors
+	residuals int
+}
+
+func newHoltState(alpha, beta, gamma float64, period int) holtState {
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.5
+	}
+	if beta <= 0 || beta > 1 {
+		beta = 0.1
+	}
+	if gamma <= 0 || gamma > 1 {
+		gamma = 0.1
+	}
+
+	s := holtState{
+		alpha:  alpha,
+		beta:   beta,
+		gamma:  gamma,
+		period: period,
+	}
+	s.reset()
+
+	return s
+}
+
+func (s *holtState) reset() {
+	s.level = 0
+	s.trend = 0
+	s.pos = 0
+	s.ready = false
+	s.count = 0
+	s.sse = 0
+	s.residuals = 0
+	s.seasonal = make([]float64, s.period)
+	s.warmup = s.warmup[:0]
+}
+
+// warmupSize returns the number of observations used for initialization.
+func (s *holtState) warmupSize() int {
+	if s.period > 0 {
+		return 2 * s.period
+	}
+	return 2
+}
+
+func (s *holtState) update(value float64) {
+	s.count++
+
+	if !s.ready {
+		s.warmup = append(s.warmup, value)
+		if len(s.warmup) == s.warmupSize() {
+			s.initialize()
+		}
+		return
+	}
+
+	season := 0.0
+	if s.period > 0 {
+		season = s.seasonal[s.pos]
+	}
+
+	err := value - (s.level + s.trend + season)
+	s.sse += err * err
+	s.residuals++
+
+	prevLevel := s.level
+	s.level = s.alpha*(value-season) + (1-s.alpha)*(s.level+s.trend)
+	s.trend = s.beta*(s.level-prevLevel) + (1-s.beta)*s.trend
+
+	if s.period > 0 {
+		s.seasonal[s.pos] = s.gamma*(value-s.level) + (1-s.gamma)*season
+		s.pos = (s.pos + 1) % s.period
+	}
+}
+
+// initialize sets level, trend and seasonal offsets from the warm-up
+// observations. Without seasonality the trend is the difference of the
+// two observations. With seasonality the trend is the change in the
+// period means, and each offset is the average detrended deviation at
+// that position over the two periods.
+func (s *holtState) initialize() {
+	if s.period == 0 {
+		s.level = s.warmup[1]
+		s.trend = s.warmup[1] - s.warmup[0]
+		s.ready = true
+		return
+	}
+
+	p := s.period
+	first := mean(s.warmup[:p])
+	second := mean(s.warmup[p:])
+	s.trend = (second - first) / float64(p)
+
+	center := float64(p-1) / 2
+	for i := range p {
+		offset := (float64(i) - center) * s.trend
+		s.seasonal[i] = ((s.warmup[i] - (first + offset)) + (s.warmup[p+i] - (second + offset))) / 2
+	}
+
+	s.level = second + center*s.trend
+	s.pos = 0
+	s.ready = true
+}
+
+func (s *holtState) forecast(steps int) float64 {
+	if !s.ready {
+		return math.NaN()
+	}
+	if steps < 1 {
+		steps = 1
+	}
+
+	value := s.level + float64(steps)*s.trend
+	if s.period > 0 {
+		value += s.seasonal[(s.pos+steps-1)%s.period]
+	}
+
+	return value
+}
+
+// variance returns the forecast error variance steps ahead, using the
+// ETS(A,A,N) and ETS(A,A,A) closed forms (Hyndman et al., "Forecasting
+// with Exponential Smoothing", 2008, Table 6.1).
+func (s *holtState) variance(steps int) float64 {
+	if s.residuals < 2 {
+		return math.NaN()
+	}
+	if steps < 1 {
+		steps = 1
+	}
+
+	sigma2 := s.sse / float64(s.residuals)
+
+	// Convert classic parameters to the ETS parameterization
+	a := s.alpha
+	b := s.alpha * s.beta
+	h := float64(steps)
+
+	factor := 1 + (h-1)*(a*a+a*b*h+b*b*h*(2*h-1)/6)
+
+	if s.period > 0 {
+		g := s.gamma * (1 - s.alpha)
+		m := float64(s.period)
+		k := math.Floor((h - 1) / m)
+		factor += k * g * (2*a + g + b*m*(k+1))
+	}
+
+	return sigma2 * factor
+}
+
+func (s *holtState) interval(steps int, z float64) (lower, upper float64) {
+	variance := s.variance(steps)
+	if math.IsNaN(variance) {
+		return math.NaN(), math.NaN()
+	}
+
+	f := s.forecast(steps)
+	w := z * math.Sqrt(variance)
+
+	return f - w, f + w
+}
+
+func (s *holtState) score(value float64) float64 {
+	variance := s.variance(1)
+	if math.IsNaN(variance) {
+		return 0
+	}
+
+	diff := value - s.forecast(1)
+	if variance == 0 {
+		if diff == 0 {
+			return 0
+		}
+		return math.Copysign(math.Inf(1), diff)
+	}
+
+	return diff / math.Sqrt(variance)
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,233 @@
+package ewma
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHolt(t *testing.T) {
+	h := NewHolt(0.3, 0.2)
+	assert.Equal(t, 0.3, h.alpha)
+	assert.Equal(t, 0.2, h.beta)
+
+	h = NewHolt(0, 2)
+	assert.Equal(t, 0.5, h.alpha)
+	assert.Equal(t, 0.1, h.beta)
+}
+
+func TestHolt(t *testing.T) {
+	t.Run("not ready", func(t *testing.T) {
+		h := NewHolt(0.5, 0.1)
+		assert.True(t, math.IsNaN(h.Forecast(1)))
+		h.Update(1)
+		assert.True(t, math.IsNaN(h.Forecast(1)))
+		assert.Equal(t, 0.0, h.Score(100))
+
+		lower, upper := h.PredictionInterval(1, 1.96)
+		assert.True(t, math.IsNaN(lower))
+		assert.True(t, math.IsNaN(upper))
+	})
+
+	t.Run("follows linear trend exactly", func(t *testing.T) {
+		h := NewHolt(0.5, 0.3)
+		for i := range 50 {
+			h.Update(10 + 2*float64(i))
+		}
+
+		assert.InDelta(t, 108.0, h.Level(), 1e-9)
+		assert.InDelta(t, 2.0, h.Trend(), 1e-9)
+		assert.InDelta(t, 110.0, h.Forecast(1), 1e-9)
+		assert.InDelta(t, 128.0, h.Forecast(10), 1e-9)
+		assert.InDelta(t, 110.0, h.Forecast(0), 1e-9)
+		assert.Equal(t, 50, h.Count())
+	})
+
+	t.Run("beats ewma on a ramp", func(t *testing.T) {
+		h := NewHolt(0.3, 0.1)
+		e := NewWithAlpha(0.3)
+		for i := range 200 {
+			v := float64(i)
+			h.Update(v)
+			e.UpdateWithValue(v)
+		}
+
+		assert.InDelta(t, 200.0, h.Forecast(1), 0.5)
+		assert.Less(t, e.Rate(), 198.0)
+	})
+
+	t.Run("prediction interval and score", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1))
+		h := NewHolt(0.2, 0.05)
+		for i := range 2000 {
+			h.Update(100 + 0.5*float64(i) + rng.NormFloat64()*5)
+		}
+
+		lower, upper := h.PredictionInterval(1, 1.96)
+		f := h.Forecast(1)
+		assert.InDelta(t, f, (lower+upper)/2, 1e-9)
+		assert.Greater(t, upper-lower, 15.0)
+		assert.Less(t, upper-lower, 30.0)
+
+		// Intervals widen with the horizon
+		lower10, upper10 := h.PredictionInterval(10, 1.96)
+		assert.Greater(t, upper10-lower10, upper-lower)
+
+		assert.Less(t, math.Abs(h.Score(f+3)), 1.0)
+		assert.Greater(t, h.Score(f+50), 5.0)
+		assert.Less(t, h.Score(f-50), -5.0)
+	})
+
+	t.Run("zero variance", func(t *testing.T) {
+		h := NewHolt(0.5, 0.5)
+		for range 10 {
+			h.Update(5)
+		}
+		assert.Equal(t, 0.0, h.Score(5))
+		assert.True(t, math.IsInf(h.Score(6), 1))
+		assert.True(t, math.IsInf(h.Score(4), -1))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		h := NewHolt(0.5, 0.1)
+		h.Update(1)
+		h.Update(2)
+		h.Reset()
+		assert.Equal(t, 0, h.Count())
+		assert.True(t, math.IsNaN(h.Forecast(1)))
+	})
+}
+
+func TestNewHoltWinters(t *testing.T) {
+	hw := NewHoltWinters(0, 0, 0, 0)
+	assert.Equal(t, 24, hw.Period())
+	assert.Equal(t, 0.5, hw.alpha)
+	assert.Equal(t, 0.1, hw.beta)
+	assert.Equal(t, 0.1, hw.gamma)
+}
+
+func seasonalValue(i int) float64 {
+	// Daily cycle over 24 hourly points with upward trend
+	return 1000 + 2*float64(i) + 300*math.Sin(2*math.Pi*float64(i)/24)
+}
+
+func TestHoltWinters(t *testing.T) {
+	t.Run("warm-up needs two periods", func(t *testing.T) {
+		hw := NewHoltWinters(0.3, 0.1, 0.1, 4)
+		for i := range 7 {
+			hw.Update(float64(i))
+			assert.True(t, math.IsNaN(hw.Forecast(1)))
+		}
+		hw.Update(7)
+		assert.False(t, math.IsNaN(hw.Forecast(1)))
+	})
+
+	t.Run("exact seasonal pattern", func(t *testing.T) {
+		pattern := []float64{10, 20, 30, 20}
+		hw := NewHoltWinters(0.3, 0.1, 0.2, 4)
+		for i := range 40 {
+			hw.Update(pattern[i%4])
+		}
+
+		for step := 1; step <= 8; step++ {
+			assert.InDelta(t, pattern[(40+step-1)%4], hw.Forecast(step), 1e-9, "step %d", step)
+		}
+		assert.InDelta(t, 0.0, hw.Trend(), 1e-9)
+		assert.InDelta(t, 20.0, hw.Level(), 1e-9)
+
+		seasonal := hw.Seasonal()
+		assert.Len(t, seasonal, 4)
+		assert.InDelta(t, -10.0, seasonal[0], 1e-9)
+		assert.InDelta(t, 10.0, seasonal[2], 1e-9)
+	})
+
+	t.Run("seasonal ramp is not anomalous", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(2))
+		hw := NewHoltWinters(0.2, 0.05, 0.2, 24)
+		h := NewHolt(0.2, 0.05)
+
+		for i := range 24 * 14 {
+			v := seasonalValue(i) + rng.NormFloat64()*10
+			hw.Update(v)
+			h.Update(v)
+		}
+
+		// Morning ramp: forecasting the next 6 hours
+		n := 24 * 14
+		for step := 1; step <= 6; step++ {
+			assert.InDelta(t, seasonalValue(n+step-1), hw.Forecast(step), 40, "step %d", step)
+		}
+
+		next := seasonalValue(n)
+		assert.Less(t, math.Abs(hw.Score(next)), 3.0)
+		assert.Greater(t, math.Abs(hw.Score(next+500)), 10.0)
+
+		// Holt without seasonality has much wider errors on the same data
+		lowerHW, upperHW := hw.PredictionInterval(1, 1.96)
+		lowerH, upperH := h.PredictionInterval(1, 1.96)
+		assert.Less(t, upperHW-lowerHW, (upperH-lowerH)/2)
+	})
+
+	t.Run("interval widens each season", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(3))
+		hw := NewHoltWinters(0.3, 0.1, 0.3, 4)
+		for i := range 200 {
+			hw.Update(float64(i%4) + rng.NormFloat64())
+		}
+
+		width := func(steps int) float64 {
+			lower, upper := hw.PredictionInterval(steps, 1)
+			return upper - lower
+		}
+		assert.Greater(t, width(5), width(4))
+		assert.Greater(t, width(9), width(8))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		hw := NewHoltWinters(0.3, 0.1, 0.1, 2)
+		for i := range 10 {
+			hw.Update(float64(i))
+		}
+		hw.Reset()
+		assert.Equal(t, 0, hw.Count())
+		assert.True(t, math.IsNaN(hw.Forecast(1)))
+		assert.Equal(t, []float64{0, 0}, hw.Seasonal())
+	})
+}
+
+func TestHoltConcurrency(t *testing.T) {
+	h := NewHolt(0.5, 0.1)
+	hw := NewHoltWinters(0.5, 0.1, 0.1, 12)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				h.Update(float64(i))
+				hw.Update(float64(i % 12))
+				_ = h.Forecast(3)
+				_ = hw.Score(1)
+				_, _ = hw.PredictionInterval(2, 1.96)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2000, h.Count())
+	assert.Equal(t, 2000, hw.Count())
+}
+
+func BenchmarkHoltWinters_Update(b *testing.B) {
+	hw := NewHoltWinters(0.3, 0.1, 0.1, 24)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		hw.Update(seasonalValue(i))
+		i++
+	}
+}
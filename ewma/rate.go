@@ -1,7 +1,6 @@
 package ewma
 
 import (
-	"math"
 	"sync"
 	"time"
 )
@@ -18,8 +17,8 @@ type Rate struct {
 	mu         sync.RWMutex
 	rate       float64
 	lastUpdate time.Time
-	halfLife   time.Duration
-	decayRate  float64
+	decay      Decay
+	clock      Clock
 }
 
 // NewRate creates a new Rate with the specified half-life.
@@ -29,20 +28,26 @@ type Rate struct {
 //   - Medium half-life (30s-60s): Balanced
 //   - Long half-life (5m-15m): Smooth, stable rates
 func NewRate(halfLife time.Duration) *Rate {
-	if halfLife <= 0 {
-		halfLife = 60 * time.Second
+	return NewRateWithClock(halfLife, SystemClock)
+}
+
+// NewRateWithClock creates a new Rate that reads the current time from clock.
+// If clock is nil, SystemClock is used.
+func NewRateWithClock(halfLife time.Duration, clock Clock) *Rate {
+	if clock == nil {
+		clock = SystemClock
 	}
 
 	return &Rate{
-		halfLife:  halfLife,
-		decayRate: math.Ln2 / halfLife.Seconds(),
+		decay: NewDecay(halfLife),
+		clock: clock,
 	}
 }
 
 // Add records one or more events.
 // Rate is automatically updated with exponential decay.
 func (r *Rate) Add(n float64) {
-	r.AddAt(n, time.Now())
+	r.AddAt(n, r.clock.Now())
 }
 
 // AddAt records events at a specific time.
@@ -58,10 +63,8 @@ func (r *Rate) AddAt(n float64, t time.Time) {
 	}
 
 	// Apply decay
-	elapsed := t.Sub(r.lastUpdate).Seconds()
-	if elapsed > 0 {
-		decayFactor := math.Exp(-r.decayRate * elapsed)
-		r.rate = r.rate*decayFactor + n
+	if t.After(r.lastUpdate) {
+		r.rate = r.decay.Apply(r.rate, r.lastUpdate, t) + n
 		r.lastUpdate = t
 	} else {
 		// Same or earlier time, just add
@@ -71,7 +74,7 @@ func (r *Rate) AddAt(n float64, t time.Time) {
 
 // Rate returns the current rate with automatic decay applied.
 func (r *Rate) Rate() float64 {
-	return r.RateAt(time.Now())
+	return r.RateAt(r.clock.Now())
 }
 
 // RateAt returns the rate at a specific time with decay applied.
@@ -83,19 +86,13 @@ func (r *Rate) RateAt(t time.Time) float64 {
 		return 0
 	}
 
-	elapsed := t.Sub(r.lastUpdate).Seconds()
-	if elapsed <= 0 {
-		return r.rate
-	}
-
-	decayFactor := math.Exp(-r.decayRate * elapsed)
-	return r.rate * decayFactor
+	return r.decay.Apply(r.rate, r.lastUpdate, t)
 }
 
 // Set sets the rate directly.
 // Useful for initialization or testing.
 func (r *Rate) Set(rate float64) {
-	r.SetAt(rate, time.Now())
+	r.SetAt(rate, r.clock.Now())
 }
 
 // SetAt sets the rate at a specific time.
@@ -118,7 +115,7 @@ func (r *Rate) Reset() {
 
 // HalfLife returns the decay half-life.
 func (r *Rate) HalfLife() time.Duration {
-	return r.halfLife
+	return r.decay.HalfLife()
 }
 
 // RateSnapshot represents a point-in-time view of a Rate.
@@ -136,6 +133,6 @@ func (r *Rate) Snapshot() RateSnapshot {
 	return RateSnapshot{
 		Rate:       r.rate,
 		LastUpdate: r.lastUpdate,
-		HalfLife:   r.halfLife,
+		HalfLife:   r.decay.HalfLife(),
 	}
 }
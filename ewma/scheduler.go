@@ -0,0 +1,136 @@
+package ewma
+
+import (
+	"sync"
+	"time"
+)
+
+// Ticker is updated on a fixed interval.
+// Implemented by *EWMA and *MovingAverage.
+//
+// A Scheduler identifies registered Tickers with ==, so implement Ticker on
+// a pointer type. Registering a value of a non-comparable type, such as a
+// struct holding a slice or map, panics.
+type Ticker interface {
+	Tick()
+}
+
+// Scheduler ticks many registered averages from a single goroutine,
+// replacing a per-metric ticker goroutine.
+//
+// Register EWMAs created with the same interval as the scheduler.
+// Call Stop() to release the background goroutine when done.
+type Scheduler struct {
+	mu       sync.Mutex
+	tickers  []Ticker
+	interval time.Duration
+	ticker   *time.Ticker
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewScheduler creates a scheduler that ticks every interval and starts
+// its background goroutine. If interval is not positive, Interval is used.
+func NewScheduler(interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = Interval
+	}
+
+	s := &Scheduler{
+		interval: interval,
+		ticker:   time.NewTicker(interval),
+		done:     make(chan struct{}),
+	}
+
+	go s.loop()
+
+	return s
+}
+
+// Register adds t to the set of averages ticked by the scheduler.
+// Registering the same value twice has no effect. t must be a pointer or
+// another comparable value; see Ticker.
+func (s *Scheduler) Register(t Ticker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.tickers {
+		if existing == t {
+			return
+		}
+	}
+
+	s.tickers = append(s.tickers, t)
+}
+
+// Unregister removes t, compared with ==, from the scheduler.
+func (s *Scheduler) Unregister(t Ticker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.tickers {
+		if existing == t {
+			s.tickers = append(s.tickers[:i], s.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+// NewEWMA creates an EWMA with the scheduler's interval and registers it.
+func (s *Scheduler) NewEWMA(alpha float64) *EWMA {
+	e := New(alpha, s.interval)
+	s.Register(e)
+	return e
+}
+
+// NewMovingAverage creates a MovingAverage and registers it.
+// The scheduler interval should be Interval.
+func (s *Scheduler) NewMovingAverage() *MovingAverage {
+	ma := NewMovingAverage()
+	s.Register(ma)
+	return ma
+}
+
+// Tick ticks every registered average immediately.
+func (s *Scheduler) Tick() {
+	s.mu.Lock()
+	tickers := make([]Ticker, len(s.tickers))
+	copy(tickers, s.tickers)
+	s.mu.Unlock()
+
+	for _, t := range tickers {
+		t.Tick()
+	}
+}
+
+// Len returns the number of registered averages.
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tickers)
+}
+
+// Interval returns the tick interval.
+func (s *Scheduler) Interval() time.Duration {
+	return s.interval
+}
+
+// Stop stops the background goroutine.
+// It is safe to call multiple times.
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		s.ticker.Stop()
+		close(s.done)
+	})
+}
+
+func (s *Scheduler) loop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.ticker.C:
+			s.Tick()
+		}
+	}
+}
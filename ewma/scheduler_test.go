@@ -0,0 +1,185 @@
+package ewma
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingTicker struct {
+	ticks atomic.Int64
+}
+
+func (c *countingTicker) Tick() { c.ticks.Add(1) }
+
+func TestScheduler(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		s := NewScheduler(0)
+		defer s.Stop()
+		assert.Equal(t, Interval, s.Interval())
+	})
+
+	t.Run("register and tick", func(t *testing.T) {
+		s := NewScheduler(time.Hour)
+		defer s.Stop()
+
+		a := &countingTicker{}
+		b := &countingTicker{}
+		s.Register(a)
+		s.Register(a)
+		s.Register(b)
+		assert.Equal(t, 2, s.Len())
+
+		s.Tick()
+		assert.Equal(t, int64(1), a.ticks.Load())
+		assert.Equal(t, int64(1), b.ticks.Load())
+
+		s.Unregister(a)
+		s.Unregister(a)
+		s.Tick()
+		assert.Equal(t, int64(1), a.ticks.Load())
+		assert.Equal(t, int64(2), b.ticks.Load())
+	})
+
+	t.Run("background ticks", func(t *testing.T) {
+		s := NewScheduler(5 * time.Millisecond)
+		c := &countingTicker{}
+		s.Register(c)
+
+		assert.Eventually(t, func() bool { return c.ticks.Load() >= 3 }, time.Second, time.Millisecond)
+
+		s.Stop()
+		s.Stop()
+		stopped := c.ticks.Load()
+		time.Sleep(20 * time.Millisecond)
+		assert.LessOrEqual(t, c.ticks.Load(), stopped+1)
+	})
+
+	t.Run("owned averages", func(t *testing.T) {
+		s := NewScheduler(time.Hour)
+		defer s.Stop()
+
+		e := s.NewEWMA(0.5)
+		ma := s.NewMovingAverage()
+		assert.Equal(t, 2, s.Len())
+		assert.Equal(t, time.Hour, e.interval)
+
+		e.Add(3600)
+		ma.Add(50)
+		s.Tick()
+
+		assert.Equal(t, 1.0, e.Rate())
+		assert.Equal(t, 10.0, ma.Rate1())
+	})
+
+	t.Run("concurrent register", func(t *testing.T) {
+		s := NewScheduler(time.Millisecond)
+		defer s.Stop()
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 50 {
+					e := s.NewEWMA(0.1)
+					e.Update()
+					s.Unregister(e)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 0, s.Len())
+	})
+}
+
+func TestAutoTick(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("matches manual ticking", func(t *testing.T) {
+		clock := NewManualClock(base)
+		auto := NewAutoTick(0.2, Interval, clock)
+		manual := New(0.2, Interval)
+
+		for i := range 20 {
+			auto.Add(uint64(i * 10))
+			manual.Add(uint64(i * 10))
+			clock.Advance(Interval)
+			manual.Tick()
+			assert.InDelta(t, manual.Rate(), auto.Rate(), 1e-9, "tick %d", i)
+		}
+	})
+
+	t.Run("catches up missed ticks", func(t *testing.T) {
+		clock := NewManualClock(base)
+		e := NewAutoTick(0.5, Interval, clock)
+
+		e.Add(50)
+		clock.Advance(Interval)
+		assert.Equal(t, 10.0, e.Rate())
+
+		// Four intervals pass with no reads: one with events, three idle
+		e.Add(100)
+		clock.Advance(4*Interval + Interval/2)
+		expected := (0.5*20 + 0.5*10) * math.Pow(0.5, 3)
+		assert.InDelta(t, expected, e.Rate(), 1e-9)
+
+		// Partial interval does not tick
+		clock.Advance(Interval / 4)
+		assert.InDelta(t, expected, e.Rate(), 1e-9)
+	})
+
+	t.Run("explicit tick only catches up", func(t *testing.T) {
+		clock := NewManualClock(base)
+		e := NewAutoTick(0.5, Interval, clock)
+		e.Add(50)
+		e.Tick()
+		assert.Equal(t, 0.0, e.Rate())
+		assert.Equal(t, uint64(50), e.Snapshot().Uncounted)
+
+		clock.Advance(Interval)
+		e.Tick()
+		assert.Equal(t, 10.0, e.Snapshot().Rate)
+	})
+
+	t.Run("reset restarts interval", func(t *testing.T) {
+		clock := NewManualClock(base)
+		e := NewAutoTick(0.5, Interval, clock)
+		e.Add(50)
+		clock.Advance(10 * Interval)
+		e.Reset()
+
+		e.Add(5)
+		clock.Advance(Interval)
+		assert.Equal(t, 1.0, e.Rate())
+	})
+
+	t.Run("moving average", func(t *testing.T) {
+		clock := NewManualClock(base)
+		ma := NewAutoTickMovingAverage(clock)
+
+		for range 120 {
+			ma.Add(50)
+			clock.Advance(Interval)
+		}
+		m1, m5, m15 := ma.Rates()
+		assert.InDelta(t, 10.0, m1, 0.01)
+		assert.Greater(t, m5, 0.0)
+		assert.Greater(t, m15, 0.0)
+
+		// Ten idle minutes pass without any ticker
+		clock.Advance(10 * time.Minute)
+		assert.Less(t, ma.Rate1(), 0.01)
+		assert.Less(t, ma.Rate5(), m5)
+	})
+
+	t.Run("nil clock uses system clock", func(t *testing.T) {
+		e := NewAutoTick(0.5, time.Hour, nil)
+		e.Add(1)
+		assert.Equal(t, 0.0, e.Rate())
+	})
+}
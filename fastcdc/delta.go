@@ -0,0 +1,303 @@
+package fastcdc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrInvalidDelta is returned by Delta.Apply for a malformed edit
+var ErrInvalidDelta = errors.New("fastcdc: invalid delta")
+
+// DedupStats describes how much content two streams share at chunk level
+type DedupStats struct {
+	SizeA   uint64 // Size of stream A in bytes
+	SizeB   uint64 // Size of stream B in bytes
+	ChunksA int    // Number of chunks in A
+	ChunksB int    // Number of chunks in B
+
+	SharedChunks  int // Distinct chunks present in both streams
+	UniqueChunksA int // Distinct chunks present only in A
+	UniqueChunksB int // Distinct chunks present only in B
+
+	SharedBytes uint64 // Bytes of B covered by chunks also present in A
+	BytesSaved  uint64 // Bytes of B that need not be stored next to A (shared and repeated chunks)
+}
+
+// DedupRatio returns the fraction of B that need not be stored next to A (0-1)
+func (s DedupStats) DedupRatio() float64 {
+	if s.SizeB == 0 {
+		return 0
+	}
+	return float64(s.BytesSaved) / float64(s.SizeB)
+}
+
+// EditOp is the kind of a delta edit
+type EditOp uint8
+
+const (
+	// EditCopy copies bytes from the base stream
+	EditCopy EditOp = iota
+
+	// EditInsert inserts literal bytes
+	EditInsert
+)
+
+// String returns the string representation of the edit operation
+func (op EditOp) String() string {
+	switch op {
+	case EditCopy:
+		return "copy"
+	case EditInsert:
+		return "insert"
+	default:
+		return "unknown"
+	}
+}
+
+// Edit is one instruction of a delta edit script
+type Edit struct {
+	Op     EditOp
+	Offset uint64 // Source offset in the base stream (EditCopy only)
+	Length uint64 // Number of bytes produced
+	Data   []byte // Literal bytes (EditInsert only)
+}
+
+// Delta is an edit script that rebuilds a target stream from a base stream,
+// together with dedup statistics for the pair (A = base, B = target)
+type Delta struct {
+	Edits []Edit
+	Stats DedupStats
+}
+
+// CopyBytes returns the number of bytes produced by copy edits
+func (d *Delta) CopyBytes() uint64 {
+	var n uint64
+	for _, e := range d.Edits {
+		if e.Op == EditCopy {
+			n += e.Length
+		}
+	}
+	return n
+}
+
+// InsertBytes returns the number of literal bytes in the delta,
+// i.e. the payload of an rsync-style transfer
+func (d *Delta) InsertBytes() uint64 {
+	var n uint64
+	for _, e := range d.Edits {
+		if e.Op == EditInsert {
+			n += e.Length
+		}
+	}
+	return n
+}
+
+// Apply writes the target stream to w, reading copied ranges from base.
+// A copy edit reaching past the end of base fails with io.ErrUnexpectedEOF,
+// and an insert edit whose Data does not match its Length or an unknown
+// operation fails with ErrInvalidDelta. Output written before the failing
+// edit is not rolled back.
+func (d *Delta) Apply(base io.ReaderAt, w io.Writer) error {
+	for _, e := range d.Edits {
+		switch e.Op {
+		case EditCopy:
+			section := io.NewSectionReader(base, int64(e.Offset), int64(e.Length))
+			if _, err := io.CopyN(w, section, int64(e.Length)); err != nil {
+				if errors.Is(err, io.EOF) {
+					return io.ErrUnexpectedEOF
+				}
+				return err
+			}
+		case EditInsert:
+			if uint64(len(e.Data)) != e.Length {
+				return ErrInvalidDelta
+			}
+			if _, err := w.Write(e.Data); err != nil {
+				return err
+			}
+		default:
+			return ErrInvalidDelta
+		}
+	}
+
+	return nil
+}
+
+// chunkRef locates a chunk in a stream
+type chunkRef struct {
+	offset uint64
+	length uint64
+}
+
+// CompareStreams chunks a and b with config and reports how much they share.
+// Only hashes are computed; chunk data is not retained.
+func CompareStreams(a, b io.Reader, config Config) (DedupStats, error) {
+	var stats DedupStats
+
+	index, err := indexStream(a, config, &stats)
+	if err != nil {
+		return DedupStats{}, err
+	}
+
+	chunker, err := NewChunker(b, config)
+	if err != nil {
+		return DedupStats{}, err
+	}
+
+	tracker := newDedupTracker(index, &stats)
+	err = chunker.ForEachHash(func(chunk *Chunk) error {
+		tracker.add(chunk)
+		return nil
+	})
+	if err != nil {
+		return DedupStats{}, err
+	}
+
+	tracker.finish()
+
+	return stats, nil
+}
+
+// ComputeDelta chunks base and target with config and returns an edit script
+// that rebuilds target from base. Chunks of target found anywhere in base
+// become copy edits; everything else becomes insert edits. Adjacent edits
+// are merged.
+//
+// Base is only hashed; target data is retained for insert edits.
+func ComputeDelta(base, target io.Reader, config Config) (*Delta, error) {
+	delta := &Delta{}
+
+	index, err := indexStream(base, config, &delta.Stats)
+	if err != nil {
+		return nil, err
+	}
+
+	chunker, err := NewChunker(target, config)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := newDedupTracker(index, &delta.Stats)
+	err = chunker.ForEach(func(chunk *Chunk) error {
+		ref, shared := tracker.add(chunk)
+		if shared {
+			delta.appendCopy(ref)
+		} else {
+			delta.appendInsert(chunk.Data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tracker.finish()
+
+	return delta, nil
+}
+
+// appendCopy adds a copy edit, extending the previous one when the source
+// ranges are contiguous
+func (d *Delta) appendCopy(ref chunkRef) {
+	if n := len(d.Edits); n > 0 {
+		last := &d.Edits[n-1]
+		if last.Op == EditCopy && last.Offset+last.Length == ref.offset {
+			last.Length += ref.length
+			return
+		}
+	}
+
+	d.Edits = append(d.Edits, Edit{Op: EditCopy, Offset: ref.offset, Length: ref.length})
+}
+
+// appendInsert adds an insert edit with a copy of data, extending the
+// previous insert if any
+func (d *Delta) appendInsert(data []byte) {
+	if n := len(d.Edits); n > 0 {
+		last := &d.Edits[n-1]
+		if last.Op == EditInsert {
+			last.Data = append(last.Data, data...)
+			last.Length += uint64(len(data))
+			return
+		}
+	}
+
+	d.Edits = append(d.Edits, Edit{Op: EditInsert, Length: uint64(len(data)), Data: bytes.Clone(data)})
+}
+
+// indexStream hashes every chunk of r and records the first location of
+// each distinct chunk
+func indexStream(r io.Reader, config Config, stats *DedupStats) (map[string]chunkRef, error) {
+	chunker, err := NewChunker(r, config)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]chunkRef)
+	err = chunker.ForEachHash(func(chunk *Chunk) error {
+		stats.ChunksA++
+		stats.SizeA += chunk.Length
+
+		key := string(chunk.HashBytes())
+		if _, ok := index[key]; !ok {
+			index[key] = chunkRef{offset: chunk.Offset, length: chunk.Length}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// dedupTracker accumulates B-side statistics against an index of A
+type dedupTracker struct {
+	index  map[string]chunkRef
+	stats  *DedupStats
+	seenB  map[string]struct{}
+	shared int
+}
+
+func newDedupTracker(index map[string]chunkRef, stats *DedupStats) *dedupTracker {
+	return &dedupTracker{
+		index: index,
+		stats: stats,
+		seenB: make(map[string]struct{}),
+	}
+}
+
+// add records a chunk of B and returns its location in A if shared
+func (t *dedupTracker) add(chunk *Chunk) (chunkRef, bool) {
+	t.stats.ChunksB++
+	t.stats.SizeB += chunk.Length
+
+	key := string(chunk.HashBytes())
+	ref, inA := t.index[key]
+	_, repeated := t.seenB[key]
+
+	if inA {
+		t.stats.SharedBytes += chunk.Length
+	}
+
+	if inA || repeated {
+		t.stats.BytesSaved += chunk.Length
+	}
+
+	if !repeated {
+		t.seenB[key] = struct{}{}
+		if inA {
+			t.shared++
+		}
+	}
+
+	return ref, inA
+}
+
+// finish computes the distinct chunk counts
+func (t *dedupTracker) finish() {
+	t.stats.SharedChunks = t.shared
+	t.stats.UniqueChunksA = len(t.index) - t.shared
+	t.stats.UniqueChunksB = len(t.seenB) - t.shared
+}
@@ -0,0 +1,219 @@
+package fastcdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	_, _ = rand.Read(data)
+	return data
+}
+
+func applyDelta(t *testing.T, delta *Delta, base []byte) []byte {
+	t.Helper()
+
+	var out bytes.Buffer
+	require.NoError(t, delta.Apply(bytes.NewReader(base), &out))
+	return out.Bytes()
+}
+
+func TestComputeDelta(t *testing.T) {
+	config := SmallChunkConfig()
+	base := randomBytes(512 * 1024)
+
+	t.Run("identical", func(t *testing.T) {
+		delta, err := ComputeDelta(bytes.NewReader(base), bytes.NewReader(base), config)
+		require.NoError(t, err)
+
+		require.Len(t, delta.Edits, 1)
+		assert.Equal(t, Edit{Op: EditCopy, Offset: 0, Length: uint64(len(base))}, delta.Edits[0])
+		assert.Equal(t, uint64(0), delta.InsertBytes())
+		assert.Equal(t, uint64(len(base)), delta.CopyBytes())
+		assert.Equal(t, uint64(len(base)), delta.Stats.BytesSaved)
+		assert.Equal(t, 0, delta.Stats.UniqueChunksA)
+		assert.Equal(t, 0, delta.Stats.UniqueChunksB)
+		assert.InDelta(t, 1.0, delta.Stats.DedupRatio(), 1e-9)
+		assert.Equal(t, base, applyDelta(t, delta, base))
+	})
+
+	t.Run("edited", func(t *testing.T) {
+		target := bytes.Clone(base[:200*1024])
+		target = append(target, randomBytes(1000)...)
+		target = append(target, base[210*1024:]...)
+		target = append(randomBytes(100), target...)
+
+		delta, err := ComputeDelta(bytes.NewReader(base), bytes.NewReader(target), config)
+		require.NoError(t, err)
+
+		assert.Equal(t, target, applyDelta(t, delta, base))
+		assert.Equal(t, uint64(len(target)), delta.CopyBytes()+delta.InsertBytes())
+		assert.Less(t, delta.InsertBytes(), uint64(len(target))/4)
+		assert.Greater(t, delta.Stats.SharedChunks, 0)
+		assert.Greater(t, delta.Stats.UniqueChunksB, 0)
+
+		// Adjacent edits are merged
+		for i := 1; i < len(delta.Edits); i++ {
+			prev, cur := delta.Edits[i-1], delta.Edits[i]
+			if prev.Op == EditInsert {
+				assert.Equal(t, EditCopy, cur.Op)
+			} else if cur.Op == EditCopy {
+				assert.NotEqual(t, prev.Offset+prev.Length, cur.Offset)
+			}
+		}
+	})
+
+	t.Run("unrelated", func(t *testing.T) {
+		target := randomBytes(100 * 1024)
+
+		delta, err := ComputeDelta(bytes.NewReader(base), bytes.NewReader(target), config)
+		require.NoError(t, err)
+
+		require.Len(t, delta.Edits, 1)
+		assert.Equal(t, EditInsert, delta.Edits[0].Op)
+		assert.Equal(t, target, delta.Edits[0].Data)
+		assert.Equal(t, 0, delta.Stats.SharedChunks)
+		assert.Equal(t, uint64(0), delta.Stats.BytesSaved)
+		assert.Equal(t, target, applyDelta(t, delta, base))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		delta, err := ComputeDelta(bytes.NewReader(nil), bytes.NewReader(base), config)
+		require.NoError(t, err)
+		assert.Equal(t, base, applyDelta(t, delta, nil))
+
+		delta, err = ComputeDelta(bytes.NewReader(base), bytes.NewReader(nil), config)
+		require.NoError(t, err)
+		assert.Empty(t, delta.Edits)
+		assert.Equal(t, 0.0, delta.Stats.DedupRatio())
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := ComputeDelta(bytes.NewReader(base), bytes.NewReader(base), Config{})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
+
+func TestDelta_Apply(t *testing.T) {
+	config := SmallChunkConfig()
+	base := randomBytes(256 * 1024)
+
+	t.Run("truncated base", func(t *testing.T) {
+		delta, err := ComputeDelta(bytes.NewReader(base), bytes.NewReader(base), config)
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		err = delta.Apply(bytes.NewReader(base[:len(base)-1]), &out)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("copy past end", func(t *testing.T) {
+		delta := &Delta{Edits: []Edit{{Op: EditCopy, Offset: uint64(len(base)), Length: 1}}}
+
+		var out bytes.Buffer
+		assert.ErrorIs(t, delta.Apply(bytes.NewReader(base), &out), io.ErrUnexpectedEOF)
+		assert.Zero(t, out.Len())
+	})
+
+	t.Run("insert length mismatch", func(t *testing.T) {
+		delta := &Delta{Edits: []Edit{{Op: EditInsert, Length: 4, Data: []byte("abc")}}}
+
+		var out bytes.Buffer
+		assert.ErrorIs(t, delta.Apply(bytes.NewReader(base), &out), ErrInvalidDelta)
+	})
+
+	t.Run("unknown op", func(t *testing.T) {
+		delta := &Delta{Edits: []Edit{{Op: EditOp(9), Length: 1}}}
+
+		var out bytes.Buffer
+		assert.ErrorIs(t, delta.Apply(bytes.NewReader(base), &out), ErrInvalidDelta)
+	})
+
+	t.Run("empty edits", func(t *testing.T) {
+		delta := &Delta{Edits: []Edit{{Op: EditCopy}, {Op: EditInsert}}}
+
+		var out bytes.Buffer
+		require.NoError(t, delta.Apply(bytes.NewReader(nil), &out))
+		assert.Zero(t, out.Len())
+	})
+}
+
+func TestCompareStreams(t *testing.T) {
+	config := SmallChunkConfig()
+
+	shared := randomBytes(128 * 1024)
+	a := append(bytes.Clone(shared), randomBytes(64*1024)...)
+	// B repeats the shared part and adds its own tail
+	b := append(append(bytes.Clone(shared), randomBytes(32*1024)...), shared...)
+
+	stats, err := CompareStreams(bytes.NewReader(a), bytes.NewReader(b), config)
+	require.NoError(t, err)
+
+	// Reference computation from ChunkBytes
+	chunksA, err := ChunkBytes(a, config)
+	require.NoError(t, err)
+	chunksB, err := ChunkBytes(b, config)
+	require.NoError(t, err)
+
+	inA := make(map[[64]byte]bool)
+	for _, c := range chunksA {
+		inA[c.Hash] = true
+	}
+
+	var expected DedupStats
+	expected.SizeA, expected.SizeB = uint64(len(a)), uint64(len(b))
+	expected.ChunksA, expected.ChunksB = len(chunksA), len(chunksB)
+
+	seenB := make(map[[64]byte]bool)
+	for _, c := range chunksB {
+		if inA[c.Hash] {
+			expected.SharedBytes += c.Length
+		}
+		if inA[c.Hash] || seenB[c.Hash] {
+			expected.BytesSaved += c.Length
+		}
+		if !seenB[c.Hash] {
+			seenB[c.Hash] = true
+			if inA[c.Hash] {
+				expected.SharedChunks++
+			} else {
+				expected.UniqueChunksB++
+			}
+		}
+	}
+	expected.UniqueChunksA = len(inA) - expected.SharedChunks
+
+	assert.Equal(t, expected, stats)
+	assert.Greater(t, stats.BytesSaved, stats.SizeB/2)
+
+	delta, err := ComputeDelta(bytes.NewReader(a), bytes.NewReader(b), config)
+	require.NoError(t, err)
+	assert.Equal(t, stats, delta.Stats)
+	assert.Equal(t, b, applyDelta(t, delta, a))
+}
+
+func TestEditOp_String(t *testing.T) {
+	assert.Equal(t, "copy", EditCopy.String())
+	assert.Equal(t, "insert", EditInsert.String())
+	assert.Equal(t, "unknown", EditOp(9).String())
+}
+
+func BenchmarkComputeDelta_10MB(b *testing.B) {
+	base := randomBytes(10 * 1024 * 1024)
+	target := append(randomBytes(4096), base...)
+
+	config := MediumChunkConfig()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(base) + len(target)))
+
+	for b.Loop() {
+		_, _ = ComputeDelta(bytes.NewReader(base), bytes.NewReader(target), config)
+	}
+}
@@ -40,7 +40,11 @@ type Chunk struct {
 	Length   uint64   // Length of the chunk in bytes
 	Data     []byte   // Chunk data (optional, may be nil if not requested)
 	Hash     [64]byte // Hash of the chunk data (full size, up to 64 bytes)
-	HashSize int      // Actual hash size in bytes (32 for SHA256, 48 for SHA384, 64 for SHA512)
+	HashSize int      // Actual hash size in bytes (e.g. 32 for SHA256, 64 for SHA512, 8 for XXH64)
+
+	// Compressed holds the compressed chunk data when produced by a
+	// Pipeline with a Compress function; nil otherwise.
+	Compressed []byte
 }
 
 // Reset clears the chunk for reuse
@@ -50,6 +54,12 @@ func (c *Chunk) Reset() {
 	c.Data = c.Data[:0]
 	c.Hash = [64]byte{}
 	c.HashSize = 0
+	c.Compressed = nil
+}
+
+// HashBytes returns the chunk hash trimmed to its actual size
+func (c *Chunk) HashBytes() []byte {
+	return c.Hash[:c.HashSize]
 }
 
 // GetChunk returns a Chunk from the pool
@@ -71,6 +81,7 @@ type Config struct {
 	Normalization int           // Normalization level (0-3), affects chunk size distribution
 	BufSize       int           // Internal buffer size for streaming
 	HashAlgorithm HashAlgorithm // Hash algorithm to use (default: SHA256)
+	RollTwoBytes  bool          // Use the FastCDC 2020 rolling-two-bytes optimization (same boundaries)
 }
 
 // DefaultConfig returns the default configuration (1MB average chunks)
@@ -124,8 +135,7 @@ func LargeChunkConfig() Config {
 // Chunker performs FastCDC content-defined chunking
 type Chunker struct {
 	config    Config
-	maskS     uint64 // Mask for small chunks (before avg size)
-	maskL     uint64 // Mask for large chunks (after avg size)
+	params    boundaryParams
 	buf       []byte
 	bufStart  int    // Start cursor in buffer
 	bufEnd    int    // End cursor in buffer
@@ -141,12 +151,9 @@ func NewChunker(r io.Reader, config Config) (*Chunker, error) {
 		return nil, err
 	}
 
-	maskS, maskL := computeMasks(config.AvgSize, config.Normalization)
-
 	return &Chunker{
 		config: config,
-		maskS:  maskS,
-		maskL:  maskL,
+		params: newBoundaryParams(config),
 		buf:    make([]byte, config.BufSize),
 		reader: r,
 		hasher: NewHasher(config.HashAlgorithm),
@@ -232,6 +239,18 @@ func (c *Chunker) NextHash() (*Chunk, error) {
 // This is a zero-allocation method when the chunk's Data slice has sufficient capacity.
 // Returns io.EOF when all data has been processed.
 func (c *Chunker) NextInto(chunk *Chunk) error {
+	if err := c.nextData(chunk); err != nil {
+		return err
+	}
+
+	chunk.Hash, chunk.HashSize = c.hasher.SumFull(chunk.Data)
+
+	return nil
+}
+
+// nextData fills the provided Chunk with the next chunk's offset, length
+// and data without hashing it.
+func (c *Chunker) nextData(chunk *Chunk) error {
 	if err := c.fillBuffer(); err != nil && err != io.EOF {
 		return err
 	}
@@ -256,7 +275,6 @@ func (c *Chunker) NextInto(chunk *Chunk) error {
 	}
 
 	copy(chunk.Data, c.buf[c.bufStart:c.bufStart+chunkLen])
-	chunk.Hash, chunk.HashSize = c.hasher.SumFull(chunk.Data)
 
 	// Advance cursor (no copy needed)
 	c.bufStart += chunkLen
@@ -350,7 +368,7 @@ func ChunkBytes(data []byte, config Config) ([]*Chunk, error) {
 		return nil, err
 	}
 
-	maskS, maskL := computeMasks(config.AvgSize, config.Normalization)
+	params := newBoundaryParams(config)
 	hasher := NewHasher(config.HashAlgorithm)
 
 	// Pre-allocate estimated number of chunks
@@ -361,13 +379,7 @@ func ChunkBytes(data []byte, config Config) ([]*Chunk, error) {
 	remaining := len(data)
 
 	for remaining > 0 {
-		chunkLen := findBoundaryInSlice(data[offset:], boundaryParams{
-			minSize: config.MinSize,
-			maxSize: config.MaxSize,
-			avgSize: config.AvgSize,
-			maskS:   maskS,
-			maskL:   maskL,
-		})
+		chunkLen := findBoundaryInSlice(data[offset:], params)
 
 		chunk := &Chunk{
 			Offset: offset,
@@ -433,13 +445,7 @@ func (c *Chunker) fillBuffer() error {
 
 // findBoundary finds the next chunk boundary using FastCDC algorithm
 func (c *Chunker) findBoundary() int {
-	return findBoundaryInSlice(c.buf[c.bufStart:c.bufEnd], boundaryParams{
-		minSize: c.config.MinSize,
-		maxSize: c.config.MaxSize,
-		avgSize: c.config.AvgSize,
-		maskS:   c.maskS,
-		maskL:   c.maskL,
-	})
+	return findBoundaryInSlice(c.buf[c.bufStart:c.bufEnd], c.params)
 }
 
 // bufferLen returns the available data length in the buffer
@@ -452,12 +458,32 @@ type boundaryParams struct {
 	minSize uint64
 	maxSize uint64
 	avgSize uint64
-	maskS   uint64
-	maskL   uint64
+	maskS   uint64 // Mask for small chunks (before avg size)
+	maskL   uint64 // Mask for large chunks (after avg size)
+	rollTwo bool
+}
+
+// newBoundaryParams derives the boundary detection parameters from config.
+// The reader, byte-slice and writer paths all use it, so they cut identical chunks.
+func newBoundaryParams(config Config) boundaryParams {
+	maskS, maskL := computeMasks(config.AvgSize, config.Normalization)
+
+	return boundaryParams{
+		minSize: config.MinSize,
+		maxSize: config.MaxSize,
+		avgSize: config.AvgSize,
+		maskS:   maskS,
+		maskL:   maskL,
+		rollTwo: config.RollTwoBytes,
+	}
 }
 
 // findBoundaryInSlice implements the core FastCDC boundary detection
 func findBoundaryInSlice(data []byte, p boundaryParams) int {
+	if p.rollTwo {
+		return findBoundaryTwoBytes(data, p)
+	}
+
 	dataLen := uint64(len(data))
 
 	if dataLen <= p.minSize {
@@ -496,6 +522,71 @@ func findBoundaryInSlice(data []byte, p boundaryParams) int {
 	return int(searchLen)
 }
 
+// findBoundaryTwoBytes implements the FastCDC 2020 rolling-two-bytes
+// optimization. Each iteration consumes a byte pair: the first byte is added
+// through the pre-shifted gear table and checked against the shifted mask,
+// saving one shift per pair. The fingerprint sequence is the same as in
+// findBoundaryInSlice, so boundaries are identical as long as mask bit 63
+// is clear, which computeMasks guarantees.
+func findBoundaryTwoBytes(data []byte, p boundaryParams) int {
+	dataLen := uint64(len(data))
+
+	if dataLen <= p.minSize {
+		return int(dataLen)
+	}
+
+	searchLen := min(dataLen, p.maxSize)
+	normalPoint := min(p.avgSize, searchLen)
+
+	var fingerprint uint64
+	i := p.minSize
+
+	// Phase 1: stricter mask before average size
+	maskLS := p.maskS << 1
+	for ; i+1 < normalPoint; i += 2 {
+		fingerprint = (fingerprint << 2) + gearTableLS[data[i]]
+		if (fingerprint & maskLS) == 0 {
+			return int(i + 1)
+		}
+
+		fingerprint += gearTable[data[i+1]]
+		if (fingerprint & p.maskS) == 0 {
+			return int(i + 2)
+		}
+	}
+
+	if i < normalPoint {
+		fingerprint = (fingerprint << 1) + gearTable[data[i]]
+		if (fingerprint & p.maskS) == 0 {
+			return int(i + 1)
+		}
+		i++
+	}
+
+	// Phase 2: looser mask after average size
+	maskLS = p.maskL << 1
+	for ; i+1 < searchLen; i += 2 {
+		fingerprint = (fingerprint << 2) + gearTableLS[data[i]]
+		if (fingerprint & maskLS) == 0 {
+			return int(i + 1)
+		}
+
+		fingerprint += gearTable[data[i+1]]
+		if (fingerprint & p.maskL) == 0 {
+			return int(i + 2)
+		}
+	}
+
+	if i < searchLen {
+		fingerprint = (fingerprint << 1) + gearTable[data[i]]
+		if (fingerprint & p.maskL) == 0 {
+			return int(i + 1)
+		}
+	}
+
+	return int(searchLen)
+}
+
 // computeMasks calculates the masks for FastCDC based on average size and normalization
 func computeMasks(avgSize uint64, normalization int) (maskS, maskL uint64) {
 	bits := logarithm2(avgSize)
@@ -37,3 +37,14 @@ var gearTable = [256]uint64{
 	0x37a62a74, 0x51a2f58e, 0x7506358e, 0x5d4ab128, 0x4d4ae17b, 0x41e85924, 0x470c36f7, 0x4741cbe1,
 	0x01bb7f30, 0x617c1de3, 0x2b0c3a1f, 0x50c48f73, 0x21a82d37, 0x6095ace0, 0x419167a0, 0x3caf49b0,
 }
+
+// gearTableLS is gearTable shifted left by one bit, used by the FastCDC
+// 2020 rolling-two-bytes optimization to fold the first of each byte pair
+// into a single shift.
+var gearTableLS = func() [256]uint64 {
+	var table [256]uint64
+	for i, v := range gearTable {
+		table[i] = v << 1
+	}
+	return table
+}()
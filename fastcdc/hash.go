@@ -3,10 +3,30 @@ package fastcdc
 import (
 	"crypto/sha256"
 	"crypto/sha512"
+	"errors"
 	"hash"
+	"sort"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
 )
 
-// HashAlgorithm represents the available hash algorithms
+// MaxHashSize is the largest hash output that fits in Chunk.Hash.
+const MaxHashSize = 64
+
+var (
+	// ErrHashRegistered indicates the hash algorithm ID or name is already in use
+	ErrHashRegistered = errors.New("fastcdc: hash algorithm already registered")
+
+	// ErrInvalidHash indicates an invalid hash registration or unknown hash name
+	ErrInvalidHash = errors.New("fastcdc: invalid hash algorithm")
+)
+
+// HashAlgorithm identifies a registered hash algorithm.
+// IDs are stable and safe to persist; built-in algorithms use IDs below
+// HashCustom.
 type HashAlgorithm uint8
 
 const (
@@ -18,34 +38,146 @@ const (
 
 	// HashSHA512 uses SHA-512
 	HashSHA512
+
+	// HashBLAKE2b256 uses BLAKE2b with a 32-byte digest
+	HashBLAKE2b256
+
+	// HashBLAKE2b512 uses BLAKE2b with a 64-byte digest
+	HashBLAKE2b512
+
+	// HashBLAKE3 uses BLAKE3 with a 32-byte digest
+	HashBLAKE3
+
+	// HashXXH64 uses XXH64, a fast non-cryptographic 8-byte hash.
+	// Suitable for local deduplication, not for untrusted content addressing.
+	HashXXH64
+)
+
+// HashCustom is the first ID available for application-registered algorithms.
+const HashCustom HashAlgorithm = 128
+
+// HashFactory creates a new hash.Hash instance
+type HashFactory func() hash.Hash
+
+type hashInfo struct {
+	name    string
+	size    int
+	factory HashFactory
+}
+
+var (
+	hashMu       sync.RWMutex
+	hashRegistry = map[HashAlgorithm]hashInfo{
+		HashSHA256:     {name: "sha256", size: sha256.Size, factory: sha256.New},
+		HashSHA384:     {name: "sha384", size: sha512.Size384, factory: sha512.New384},
+		HashSHA512:     {name: "sha512", size: sha512.Size, factory: sha512.New},
+		HashBLAKE2b256: {name: "blake2b-256", size: blake2b.Size256, factory: newBLAKE2b256},
+		HashBLAKE2b512: {name: "blake2b-512", size: blake2b.Size, factory: newBLAKE2b512},
+		HashBLAKE3:     {name: "blake3", size: 32, factory: newBLAKE3},
+		HashXXH64:      {name: "xxh64", size: 8, factory: newXXH64},
+	}
 )
 
+// RegisterHash registers a hash algorithm under id so it can be selected
+// via Config.HashAlgorithm. The id must be at least HashCustom, since lower
+// IDs are reserved for built-in algorithms, the name must be unique and the
+// digest size must be between 1 and MaxHashSize bytes. The factory is
+// called once to check that its hashes report the declared size.
+// Returns ErrHashRegistered if id or name is taken.
+func RegisterHash(id HashAlgorithm, name string, size int, factory HashFactory) error {
+	if id < HashCustom || name == "" || factory == nil || size <= 0 || size > MaxHashSize {
+		return ErrInvalidHash
+	}
+
+	if h := factory(); h == nil || h.Size() != size {
+		return ErrInvalidHash
+	}
+
+	hashMu.Lock()
+	defer hashMu.Unlock()
+
+	if _, ok := hashRegistry[id]; ok {
+		return ErrHashRegistered
+	}
+
+	for _, info := range hashRegistry {
+		if info.name == name {
+			return ErrHashRegistered
+		}
+	}
+
+	hashRegistry[id] = hashInfo{name: name, size: size, factory: factory}
+
+	return nil
+}
+
+// ParseHashAlgorithm returns the algorithm registered under name.
+func ParseHashAlgorithm(name string) (HashAlgorithm, error) {
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+
+	for id, info := range hashRegistry {
+		if info.name == name {
+			return id, nil
+		}
+	}
+
+	return 0, ErrInvalidHash
+}
+
+// HashAlgorithms returns the IDs of all registered algorithms in ascending order.
+func HashAlgorithms() []HashAlgorithm {
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+
+	ids := make([]HashAlgorithm, 0, len(hashRegistry))
+	for id := range hashRegistry {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// lookup returns the registration for h, falling back to SHA-256 for
+// unknown IDs.
+func (h HashAlgorithm) lookup() (hashInfo, bool) {
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+
+	if info, ok := hashRegistry[h]; ok {
+		return info, true
+	}
+
+	return hashRegistry[HashSHA256], false
+}
+
+// Available reports whether the hash algorithm is registered
+func (h HashAlgorithm) Available() bool {
+	_, ok := h.lookup()
+	return ok
+}
+
 // String returns the string representation of the hash algorithm
 func (h HashAlgorithm) String() string {
-	switch h {
-	case HashSHA256:
-		return "sha256"
-	case HashSHA384:
-		return "sha384"
-	case HashSHA512:
-		return "sha512"
-	default:
+	info, ok := h.lookup()
+	if !ok {
 		return "unknown"
 	}
+	return info.name
 }
 
 // Size returns the hash output size in bytes
 func (h HashAlgorithm) Size() int {
-	switch h {
-	case HashSHA256:
-		return 32
-	case HashSHA384:
-		return 48
-	case HashSHA512:
-		return 64
-	default:
-		return 32
-	}
+	info, _ := h.lookup()
+	return info.size
+}
+
+// New returns a new hash.Hash for the algorithm.
+// Unknown algorithms fall back to SHA-256.
+func (h HashAlgorithm) New() hash.Hash {
+	info, _ := h.lookup()
+	return info.factory()
 }
 
 // Hasher provides hash computation for chunk data
@@ -63,16 +195,7 @@ func NewHasher(algorithm HashAlgorithm) *Hasher {
 
 // reset initializes or resets the internal hash state
 func (h *Hasher) reset() {
-	switch h.algorithm {
-	case HashSHA256:
-		h.hash = sha256.New()
-	case HashSHA384:
-		h.hash = sha512.New384()
-	case HashSHA512:
-		h.hash = sha512.New()
-	default:
-		h.hash = sha256.New()
-	}
+	h.hash = h.algorithm.New()
 }
 
 // Sum computes the hash of the given data and returns it as a byte slice
@@ -82,25 +205,18 @@ func (h *Hasher) Sum(data []byte) []byte {
 	return h.hash.Sum(nil)
 }
 
-// Sum32 computes a 32-byte hash (truncates SHA-512 to 32 bytes)
+// Sum32 computes a 32-byte hash (truncates longer hashes, pads shorter ones with zeros)
 func (h *Hasher) Sum32(data []byte) [32]byte {
-	h.hash.Reset()
-	h.hash.Write(data)
-	sum := h.hash.Sum(nil)
+	full, _ := h.SumFull(data)
 
 	var result [32]byte
-	copy(result[:], sum[:32])
+	copy(result[:], full[:32])
 	return result
 }
 
 // Sum64 computes a 64-byte hash (pads shorter hashes with zeros)
 func (h *Hasher) Sum64(data []byte) [64]byte {
-	h.hash.Reset()
-	h.hash.Write(data)
-	sum := h.hash.Sum(nil)
-
-	var result [64]byte
-	copy(result[:], sum)
+	result, _ := h.SumFull(data)
 	return result
 }
 
@@ -108,10 +224,9 @@ func (h *Hasher) Sum64(data []byte) [64]byte {
 func (h *Hasher) SumFull(data []byte) ([64]byte, int) {
 	h.hash.Reset()
 	h.hash.Write(data)
-	sum := h.hash.Sum(nil)
 
 	var result [64]byte
-	copy(result[:], sum)
+	sum := h.hash.Sum(result[:0])
 	return result, len(sum)
 }
 
@@ -127,40 +242,21 @@ func (h *Hasher) HashSize() int {
 
 // ComputeHash is a convenience function for one-shot hashing
 func ComputeHash(data []byte, algorithm HashAlgorithm) []byte {
-	switch algorithm {
-	case HashSHA256:
-		sum := sha256.Sum256(data)
-		return sum[:]
-	case HashSHA384:
-		sum := sha512.Sum384(data)
-		return sum[:]
-	case HashSHA512:
-		sum := sha512.Sum512(data)
-		return sum[:]
-	default:
-		sum := sha256.Sum256(data)
-		return sum[:]
-	}
+	result, size := ComputeHashFull(data, algorithm)
+	return result[:size:size]
 }
 
 // ComputeHash32 computes a 32-byte hash using the specified algorithm
 func ComputeHash32(data []byte, algorithm HashAlgorithm) [32]byte {
-	switch algorithm {
-	case HashSHA256:
-		return sha256.Sum256(data)
-	case HashSHA384:
-		sum := sha512.Sum384(data)
-		var result [32]byte
-		copy(result[:], sum[:32])
-		return result
-	case HashSHA512:
-		sum := sha512.Sum512(data)
-		var result [32]byte
-		copy(result[:], sum[:32])
-		return result
-	default:
+	if algorithm == HashSHA256 {
 		return sha256.Sum256(data)
 	}
+
+	full, _ := ComputeHashFull(data, algorithm)
+
+	var result [32]byte
+	copy(result[:], full[:32])
+	return result
 }
 
 // ComputeHashFull computes a full hash and returns both the result and actual size
@@ -170,18 +266,37 @@ func ComputeHashFull(data []byte, algorithm HashAlgorithm) ([64]byte, int) {
 	case HashSHA256:
 		sum := sha256.Sum256(data)
 		copy(result[:], sum[:])
-		return result, 32
+		return result, sha256.Size
 	case HashSHA384:
 		sum := sha512.Sum384(data)
 		copy(result[:], sum[:])
-		return result, 48
+		return result, sha512.Size384
 	case HashSHA512:
 		sum := sha512.Sum512(data)
 		copy(result[:], sum[:])
-		return result, 64
+		return result, sha512.Size
 	default:
-		sum := sha256.Sum256(data)
-		copy(result[:], sum[:])
-		return result, 32
+		h := algorithm.New()
+		h.Write(data)
+		sum := h.Sum(result[:0])
+		return result, len(sum)
 	}
 }
+
+func newBLAKE2b256() hash.Hash {
+	h, _ := blake2b.New256(nil)
+	return h
+}
+
+func newBLAKE2b512() hash.Hash {
+	h, _ := blake2b.New512(nil)
+	return h
+}
+
+func newBLAKE3() hash.Hash {
+	return blake3.New(32, nil)
+}
+
+func newXXH64() hash.Hash {
+	return xxhash.New()
+}
@@ -1,9 +1,15 @@
 package fastcdc
 
 import (
+	"encoding/hex"
+	"hash"
+	"hash/fnv"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"lukechampine.com/blake3"
 )
 
 func TestHashDataSHA256(t *testing.T) {
@@ -121,6 +127,10 @@ func TestHashData(t *testing.T) {
 		{"sha256", HashSHA256},
 		{"sha384", HashSHA384},
 		{"sha512", HashSHA512},
+		{"blake2b-256", HashBLAKE2b256},
+		{"blake2b-512", HashBLAKE2b512},
+		{"blake3", HashBLAKE3},
+		{"xxh64", HashXXH64},
 	}
 
 	for _, tt := range tests {
@@ -156,6 +166,10 @@ func TestHashAlgorithm_String(t *testing.T) {
 		{HashSHA256, "sha256"},
 		{HashSHA384, "sha384"},
 		{HashSHA512, "sha512"},
+		{HashBLAKE2b256, "blake2b-256"},
+		{HashBLAKE2b512, "blake2b-512"},
+		{HashBLAKE3, "blake3"},
+		{HashXXH64, "xxh64"},
 		{HashAlgorithm(99), "unknown"},
 	}
 
@@ -172,6 +186,10 @@ func TestHashAlgorithm_Size(t *testing.T) {
 		{HashSHA256, 32},
 		{HashSHA384, 48},
 		{HashSHA512, 64},
+		{HashBLAKE2b256, 32},
+		{HashBLAKE2b512, 64},
+		{HashBLAKE3, 32},
+		{HashXXH64, 8},
 	}
 
 	for _, tt := range tests {
@@ -189,6 +207,10 @@ func TestHasher(t *testing.T) {
 		{"sha256", HashSHA256},
 		{"sha384", HashSHA384},
 		{"sha512", HashSHA512},
+		{"blake2b-256", HashBLAKE2b256},
+		{"blake2b-512", HashBLAKE2b512},
+		{"blake3", HashBLAKE3},
+		{"xxh64", HashXXH64},
 	}
 
 	for _, tt := range tests {
@@ -214,3 +236,198 @@ func TestHasher(t *testing.T) {
 		})
 	}
 }
+
+func TestHashVectors(t *testing.T) {
+	tests := []struct {
+		algorithm HashAlgorithm
+		input     string
+		expected  string
+	}{
+		{HashBLAKE2b256, "", "0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"},
+		{HashBLAKE2b256, "abc", "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{HashBLAKE3, "", "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{HashBLAKE3, "abc", "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+		{HashXXH64, "", "ef46db3751d8e999"},
+		{HashXXH64, "abc", "44bc2cf5ad770999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm.String()+"/"+tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hex.EncodeToString(ComputeHash([]byte(tt.input), tt.algorithm)))
+		})
+	}
+}
+
+// patternInput returns the input used by the official BLAKE3 test vectors:
+// bytes cycling through 0..250
+func patternInput(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestHashVectors_BLAKE3(t *testing.T) {
+	// Official BLAKE3 test vectors (test_vectors.json, 32-byte hash), covering
+	// chunk boundaries, parent merging and Sum over a partial subtree stack
+	tests := []struct {
+		length   int
+		expected string
+	}{
+		{1, "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{1023, "10108970eeda3eb932baac1428c7a2163b0e924c9a9e25b35bba72b28f70bd11"},
+		{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{1025, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+		{2048, "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+		{2049, "5f4d72f40d7a5f82b15ca2b2e44b1de3c2ef86c426c95c1af0b6879522563030"},
+		{3072, "b98cb0ff3623be03326b373de6b9095218513e64f1ee2edd2525c7ad1e5cffd2"},
+		{8193, "bab6c09cb8ce8cf459261398d2e7aef35700bf488116ceb94a36d0f5f1b7bc3b"},
+		{31744, "62b6960e1a44bcc1eb1a611a8d6235b6b4b78f32e7abc4fb4c6cdcce94895c47"},
+		// Not in the official set; agreed on by two independent implementations
+		{102400, "bc3e3d41a1146b069abffad3c0d44860cf664390afce4d9661f7902e7943e085"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.length), func(t *testing.T) {
+			assert.Equal(t, tt.expected, hex.EncodeToString(ComputeHash(patternInput(tt.length), HashBLAKE3)))
+		})
+	}
+}
+
+func TestHashVectors_XXH64(t *testing.T) {
+	// Reference XXH64 vectors (seed 0); the 63-byte input runs the 32-byte
+	// stripe loop and every tail path
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a", "d24ec4f1a98c6e5b"},
+		{"as", "1c330fb2d66be179"},
+		{"asd", "631c37ce72a97393"},
+		{"asdf", "415872f599cea71e"},
+		{"Call me Ishmael. Some years ago--never mind how long precisely-", "02a2e85470d6fd96"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hex.EncodeToString(ComputeHash([]byte(tt.input), HashXXH64)))
+		})
+	}
+}
+
+func TestHashStreaming(t *testing.T) {
+	// Incremental writes of any size give the one-shot result
+	data := make([]byte, 10*1024+17)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for _, algorithm := range []HashAlgorithm{HashBLAKE3, HashXXH64} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			expected := ComputeHash(data, algorithm)
+
+			for _, step := range []int{1, 13, 64, 1000, 1024} {
+				h := algorithm.New()
+				for i := 0; i < len(data); i += step {
+					h.Write(data[i:min(i+step, len(data))])
+				}
+				assert.Equal(t, expected, h.Sum(nil), "step %d", step)
+			}
+
+			h := algorithm.New()
+			h.Write(data[:100])
+			partial := h.Sum(nil)
+			assert.Equal(t, partial, h.Sum(nil), "Sum must not change state")
+			h.Reset()
+			h.Write(data)
+			assert.Equal(t, expected, h.Sum(nil))
+		})
+	}
+}
+
+func TestRegisterHash(t *testing.T) {
+	id := HashCustom + 1
+	factory := func() hash.Hash { return fnv.New128a() }
+
+	require.NoError(t, RegisterHash(id, "fnv128a", 16, factory))
+	t.Cleanup(func() {
+		hashMu.Lock()
+		delete(hashRegistry, id)
+		hashMu.Unlock()
+	})
+
+	assert.True(t, id.Available())
+	assert.Equal(t, "fnv128a", id.String())
+	assert.Equal(t, 16, id.Size())
+	assert.Contains(t, HashAlgorithms(), id)
+
+	parsed, err := ParseHashAlgorithm("fnv128a")
+	require.NoError(t, err)
+	assert.Equal(t, id, parsed)
+
+	data := make([]byte, 64*1024)
+	config := SmallChunkConfig()
+	config.HashAlgorithm = id
+	chunks, err := ChunkBytes(data, config)
+	require.NoError(t, err)
+	for _, chunk := range chunks {
+		assert.Equal(t, 16, chunk.HashSize)
+		assert.Equal(t, ComputeHash(chunk.Data, id), chunk.HashBytes())
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		assert.ErrorIs(t, RegisterHash(id, "other", 16, factory), ErrHashRegistered)
+		assert.ErrorIs(t, RegisterHash(id+1, "sha256", 16, factory), ErrHashRegistered)
+		assert.ErrorIs(t, RegisterHash(id+1, "", 16, factory), ErrInvalidHash)
+		assert.ErrorIs(t, RegisterHash(id+1, "big", MaxHashSize+1, factory), ErrInvalidHash)
+		assert.ErrorIs(t, RegisterHash(id+1, "nil", 16, nil), ErrInvalidHash)
+	})
+
+	t.Run("reserved ids", func(t *testing.T) {
+		assert.ErrorIs(t, RegisterHash(HashXXH64+1, "reserved", 16, factory), ErrInvalidHash)
+		assert.ErrorIs(t, RegisterHash(HashCustom-1, "reserved", 16, factory), ErrInvalidHash)
+		assert.False(t, (HashXXH64 + 1).Available())
+		assert.False(t, (HashCustom - 1).Available())
+	})
+
+	t.Run("factory output", func(t *testing.T) {
+		// Declared size differs from the hash output
+		assert.ErrorIs(t, RegisterHash(id+1, "fnv128a-8", 8, factory), ErrInvalidHash)
+
+		// Output larger than Chunk.Hash
+		oversized := func() hash.Hash { return blake3.New(MaxHashSize+1, nil) }
+		assert.ErrorIs(t, RegisterHash(id+1, "blake3-65", 16, oversized), ErrInvalidHash)
+
+		assert.ErrorIs(t, RegisterHash(id+1, "nil-hash", 16, func() hash.Hash { return nil }), ErrInvalidHash)
+		assert.False(t, (id + 1).Available())
+	})
+}
+
+func TestParseHashAlgorithm(t *testing.T) {
+	for _, algorithm := range HashAlgorithms() {
+		parsed, err := ParseHashAlgorithm(algorithm.String())
+		require.NoError(t, err)
+		assert.Equal(t, algorithm, parsed)
+	}
+
+	_, err := ParseHashAlgorithm("md5")
+	assert.ErrorIs(t, err, ErrInvalidHash)
+	assert.False(t, HashAlgorithm(99).Available())
+}
+
+func BenchmarkHashAlgorithms(b *testing.B) {
+	data := make([]byte, 64*1024)
+
+	for _, algorithm := range HashAlgorithms() {
+		b.Run(algorithm.String(), func(b *testing.B) {
+			hasher := NewHasher(algorithm)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+
+			for b.Loop() {
+				_, _ = hasher.SumFull(data)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package fastcdc
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// CompressFunc compresses chunk data. It must not retain or modify src.
+// It is called concurrently from pipeline workers.
+type CompressFunc func(src []byte) ([]byte, error)
+
+// PipelineOptions configures a Pipeline.
+type PipelineOptions struct {
+	Workers  int          // Number of hashing workers (default: GOMAXPROCS)
+	Depth    int          // Maximum chunks in flight between reader and consumer (default: 2 * Workers)
+	Compress CompressFunc // Optional compression applied by workers after hashing
+}
+
+// Pipeline chunks a stream with boundary detection on a single goroutine
+// and hashing (and optional compression) fanned out to a worker pool.
+// Chunks are delivered in stream order.
+//
+// Memory is bounded by Depth: at most Depth+2 chunks are held between the
+// reader and the consumer, so a slow consumer throttles reading.
+//
+// A Pipeline processes its reader once; use either ForEach or Chunks.
+type Pipeline struct {
+	chunker *Chunker
+	opts    PipelineOptions
+	err     error
+}
+
+type pipelineJob struct {
+	chunk *Chunk
+	err   error
+	done  chan struct{}
+}
+
+// NewPipeline creates a pipelined chunker for r with the given configuration.
+func NewPipeline(r io.Reader, config Config, opts PipelineOptions) (*Pipeline, error) {
+	chunker, err := NewChunker(r, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	if opts.Depth <= 0 {
+		opts.Depth = 2 * opts.Workers
+	}
+
+	return &Pipeline{
+		chunker: chunker,
+		opts:    opts,
+	}, nil
+}
+
+// ForEach calls fn for every chunk in stream order.
+// Chunk data buffers are recycled after fn returns - do not retain references
+// to the chunk or its Data. If fn returns an error, the pipeline stops and
+// ForEach returns that error.
+func (p *Pipeline) ForEach(fn ChunkFunc) error {
+	free := make(chan []byte, p.opts.Depth+2)
+
+	return p.run(context.Background(), free, func(chunk *Chunk) error {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+
+		select {
+		case free <- chunk.Data[:0]:
+		default:
+		}
+
+		return nil
+	})
+}
+
+// Chunks returns a channel delivering chunks in stream order. The channel is
+// closed when the stream ends, an error occurs or ctx is cancelled; check Err
+// afterwards. The receiver owns each chunk.
+func (p *Pipeline) Chunks(ctx context.Context) <-chan *Chunk {
+	ch := make(chan *Chunk)
+
+	go func() {
+		defer close(ch)
+
+		p.err = p.run(ctx, nil, func(chunk *Chunk) error {
+			select {
+			case ch <- chunk:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	return ch
+}
+
+// Err returns the error that stopped the channel returned by Chunks.
+// It must be called after that channel is closed.
+func (p *Pipeline) Err() error {
+	return p.err
+}
+
+// run reads chunks sequentially, hashes them on the worker pool and passes
+// them to emit in order. Data buffers are taken from free when available.
+func (p *Pipeline) run(ctx context.Context, free chan []byte, emit ChunkFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every queued job is also in order, so jobs never blocks the reader
+	order := make(chan *pipelineJob, p.opts.Depth)
+	jobs := make(chan *pipelineJob, p.opts.Depth+2)
+
+	var wg sync.WaitGroup
+	for range p.opts.Workers {
+		wg.Go(func() {
+			p.work(ctx, jobs)
+		})
+	}
+	defer wg.Wait()
+
+	var readErr error
+
+	go func() {
+		defer close(order)
+		defer close(jobs)
+
+		for {
+			chunk := &Chunk{}
+			select {
+			case chunk.Data = <-free:
+			default:
+			}
+
+			if err := p.chunker.nextData(chunk); err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+
+			job := &pipelineJob{chunk: chunk, done: make(chan struct{})}
+			select {
+			case order <- job:
+			case <-ctx.Done():
+				return
+			}
+			jobs <- job
+		}
+	}()
+
+	var err error
+	for job := range order {
+		<-job.done
+
+		if err != nil {
+			continue
+		}
+
+		if job.err != nil {
+			err = job.err
+		} else {
+			err = emit(job.chunk)
+		}
+
+		if err != nil {
+			cancel()
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if readErr != nil {
+		return readErr
+	}
+
+	return ctx.Err()
+}
+
+// work hashes and compresses jobs until jobs is closed. After cancellation
+// jobs are failed without processing.
+func (p *Pipeline) work(ctx context.Context, jobs <-chan *pipelineJob) {
+	hasher := NewHasher(p.chunker.config.HashAlgorithm)
+
+	for job := range jobs {
+		if job.err = ctx.Err(); job.err == nil {
+			chunk := job.chunk
+			chunk.Hash, chunk.HashSize = hasher.SumFull(chunk.Data)
+
+			if p.opts.Compress != nil {
+				chunk.Compressed, job.err = p.opts.Compress(chunk.Data)
+			}
+		}
+
+		close(job.done)
+	}
+}
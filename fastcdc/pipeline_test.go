@@ -0,0 +1,210 @@
+package fastcdc
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPipeline(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		p, err := NewPipeline(bytes.NewReader(nil), SmallChunkConfig(), PipelineOptions{})
+		require.NoError(t, err)
+		assert.Greater(t, p.opts.Workers, 0)
+		assert.Equal(t, 2*p.opts.Workers, p.opts.Depth)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewPipeline(bytes.NewReader(nil), Config{}, PipelineOptions{})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
+
+func TestPipeline_ForEach(t *testing.T) {
+	data := make([]byte, 2*1024*1024)
+	_, _ = rand.Read(data)
+
+	config := SmallChunkConfig()
+	expected, err := chunkData(data, config)
+	require.NoError(t, err)
+
+	for _, workers := range []int{1, 4} {
+		p, err := NewPipeline(bytes.NewReader(data), config, PipelineOptions{Workers: workers, Depth: 3})
+		require.NoError(t, err)
+
+		var i int
+		err = p.ForEach(func(chunk *Chunk) error {
+			require.Less(t, i, len(expected))
+			assert.Equal(t, expected[i].Offset, chunk.Offset)
+			assert.Equal(t, expected[i].Length, chunk.Length)
+			assert.Equal(t, expected[i].Hash, chunk.Hash)
+			assert.Equal(t, expected[i].HashSize, chunk.HashSize)
+			assert.Equal(t, expected[i].Data, chunk.Data)
+			assert.Nil(t, chunk.Compressed)
+			i++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, len(expected), i)
+	}
+}
+
+func TestPipeline_Chunks(t *testing.T) {
+	data := make([]byte, 1024*1024)
+	_, _ = rand.Read(data)
+
+	config := SmallChunkConfig()
+	config.HashAlgorithm = HashSHA512
+	expected, err := chunkData(data, config)
+	require.NoError(t, err)
+
+	t.Run("in order", func(t *testing.T) {
+		p, err := NewPipeline(bytes.NewReader(data), config, PipelineOptions{Workers: 4})
+		require.NoError(t, err)
+
+		var chunks []*Chunk
+		for chunk := range p.Chunks(context.Background()) {
+			chunks = append(chunks, chunk)
+		}
+		require.NoError(t, p.Err())
+		require.Len(t, chunks, len(expected))
+
+		for i, chunk := range chunks {
+			assert.Equal(t, expected[i].Offset, chunk.Offset)
+			assert.Equal(t, expected[i].Hash, chunk.Hash)
+			assert.Equal(t, 64, chunk.HashSize)
+			assert.Equal(t, expected[i].Data, chunk.Data)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		p, err := NewPipeline(bytes.NewReader(data), config, PipelineOptions{Workers: 2})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := p.Chunks(ctx)
+		<-ch
+		cancel()
+
+		for range ch {
+		}
+		assert.ErrorIs(t, p.Err(), context.Canceled)
+	})
+}
+
+func TestPipeline_Compress(t *testing.T) {
+	data := bytes.Repeat([]byte("fastcdc pipeline compression "), 20000)
+
+	compress := func(src []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.BestSpeed)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(src); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	p, err := NewPipeline(bytes.NewReader(data), SmallChunkConfig(), PipelineOptions{Workers: 2, Compress: compress})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = p.ForEach(func(chunk *Chunk) error {
+		require.NotNil(t, chunk.Compressed)
+		assert.Less(t, len(chunk.Compressed), len(chunk.Data))
+
+		raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(chunk.Compressed)))
+		require.NoError(t, err)
+		assert.Equal(t, chunk.Data, raw)
+
+		out.Write(raw)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, data, out.Bytes())
+}
+
+func TestPipeline_Errors(t *testing.T) {
+	data := make([]byte, 512*1024)
+	_, _ = rand.Read(data)
+
+	t.Run("callback", func(t *testing.T) {
+		p, err := NewPipeline(bytes.NewReader(data), SmallChunkConfig(), PipelineOptions{Workers: 2})
+		require.NoError(t, err)
+
+		expectedErr := errors.New("stop")
+		var calls int
+		err = p.ForEach(func(_ *Chunk) error {
+			calls++
+			if calls == 3 {
+				return expectedErr
+			}
+			return nil
+		})
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("compress", func(t *testing.T) {
+		expectedErr := errors.New("compress failed")
+		p, err := NewPipeline(bytes.NewReader(data), SmallChunkConfig(), PipelineOptions{
+			Workers:  2,
+			Compress: func([]byte) ([]byte, error) { return nil, expectedErr },
+		})
+		require.NoError(t, err)
+
+		err = p.ForEach(func(_ *Chunk) error {
+			t.Fatal("no chunk should be delivered")
+			return nil
+		})
+		assert.Equal(t, expectedErr, err)
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		expectedErr := errors.New("read failed")
+		p, err := NewPipeline(iotest.ErrReader(expectedErr), SmallChunkConfig(), PipelineOptions{})
+		require.NoError(t, err)
+
+		err = p.ForEach(func(_ *Chunk) error { return nil })
+		assert.Equal(t, expectedErr, err)
+	})
+}
+
+func TestPipeline_Empty(t *testing.T) {
+	p, err := NewPipeline(bytes.NewReader(nil), SmallChunkConfig(), PipelineOptions{})
+	require.NoError(t, err)
+
+	err = p.ForEach(func(_ *Chunk) error {
+		t.Fatal("no chunk expected")
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func BenchmarkPipeline_10MB(b *testing.B) {
+	data := make([]byte, 10*1024*1024)
+	_, _ = rand.Read(data)
+
+	config := MediumChunkConfig()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for b.Loop() {
+		p, _ := NewPipeline(bytes.NewReader(data), config, PipelineOptions{})
+		_ = p.ForEach(func(_ *Chunk) error { return nil })
+	}
+}
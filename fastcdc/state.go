@@ -0,0 +1,103 @@
+package fastcdc
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Binary format of ChunkerState
+const (
+	stateVersion = 1
+	stateSize    = 1 + 8 + 8*4 + 1 + 1 + 1 // version + offset + sizes + normalization + hash + flags
+)
+
+const stateFlagRollTwoBytes = 1 << 0
+
+var (
+	// ErrInvalidState indicates a malformed or unsupported chunker state
+	ErrInvalidState = errors.New("fastcdc: invalid chunker state")
+)
+
+// ChunkerState is a checkpoint of a Chunker taken at a chunk boundary.
+// FastCDC restarts its rolling hash at every boundary, so a chunker resumed
+// from a state produces exactly the chunks the original would have.
+type ChunkerState struct {
+	Offset uint64 // Stream offset where the next chunk starts
+	Config Config // Chunker configuration
+}
+
+// State returns a checkpoint at the end of the last returned chunk.
+// To resume, position a new reader at State().Offset and call ResumeChunker.
+func (c *Chunker) State() ChunkerState {
+	return ChunkerState{
+		Offset: c.offset,
+		Config: c.config,
+	}
+}
+
+// ResumeChunker creates a chunker continuing from state. The reader must be
+// positioned at state.Offset; chunk offsets continue from there.
+func ResumeChunker(r io.Reader, state ChunkerState) (*Chunker, error) {
+	c, err := NewChunker(r, state.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.offset = state.Offset
+
+	return c, nil
+}
+
+// Bytes serializes the state to a binary format.
+// Format: version(1) | offset(8) | minSize(8) | maxSize(8) | avgSize(8) |
+// bufSize(8) | normalization(1) | hashAlgorithm(1) | flags(1)
+func (s ChunkerState) Bytes() []byte {
+	buf := make([]byte, stateSize)
+
+	buf[0] = stateVersion
+	binary.BigEndian.PutUint64(buf[1:9], s.Offset)
+	binary.BigEndian.PutUint64(buf[9:17], s.Config.MinSize)
+	binary.BigEndian.PutUint64(buf[17:25], s.Config.MaxSize)
+	binary.BigEndian.PutUint64(buf[25:33], s.Config.AvgSize)
+	binary.BigEndian.PutUint64(buf[33:41], uint64(s.Config.BufSize))
+	buf[41] = uint8(s.Config.Normalization)
+	buf[42] = uint8(s.Config.HashAlgorithm)
+
+	if s.Config.RollTwoBytes {
+		buf[43] |= stateFlagRollTwoBytes
+	}
+
+	return buf
+}
+
+// ParseChunkerState deserializes a state from binary format.
+func ParseChunkerState(data []byte) (ChunkerState, error) {
+	if len(data) != stateSize || data[0] != stateVersion {
+		return ChunkerState{}, ErrInvalidState
+	}
+
+	flags := data[43]
+	if flags&^stateFlagRollTwoBytes != 0 {
+		return ChunkerState{}, ErrInvalidState
+	}
+
+	state := ChunkerState{
+		Offset: binary.BigEndian.Uint64(data[1:9]),
+		Config: Config{
+			MinSize:       binary.BigEndian.Uint64(data[9:17]),
+			MaxSize:       binary.BigEndian.Uint64(data[17:25]),
+			AvgSize:       binary.BigEndian.Uint64(data[25:33]),
+			BufSize:       int(binary.BigEndian.Uint64(data[33:41])),
+			Normalization: int(data[41]),
+			HashAlgorithm: HashAlgorithm(data[42]),
+			RollTwoBytes:  flags&stateFlagRollTwoBytes != 0,
+		},
+	}
+
+	if err := validateConfig(state.Config); err != nil {
+		return ChunkerState{}, ErrInvalidState
+	}
+
+	return state, nil
+}
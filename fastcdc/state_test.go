@@ -0,0 +1,202 @@
+package fastcdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollTwoBytes(t *testing.T) {
+	data := make([]byte, 4*1024*1024+3)
+	_, _ = rand.Read(data)
+
+	configs := map[string]Config{
+		"small":  SmallChunkConfig(),
+		"medium": MediumChunkConfig(),
+		"large":  LargeChunkConfig(),
+		"odd": {
+			MinSize:       1001,
+			MaxSize:       16 * 1024,
+			AvgSize:       4099,
+			Normalization: 1,
+			BufSize:       64 * 1024,
+		},
+	}
+
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			for _, normalization := range []int{0, 1, 2, 3} {
+				config.Normalization = normalization
+
+				expected, err := ChunkBytes(data, config)
+				require.NoError(t, err)
+
+				config.RollTwoBytes = true
+				got, err := ChunkBytes(data, config)
+				require.NoError(t, err)
+				config.RollTwoBytes = false
+
+				require.Equal(t, len(expected), len(got), "normalization %d", normalization)
+				for i := range expected {
+					assert.Equal(t, expected[i].Offset, got[i].Offset)
+					assert.Equal(t, expected[i].Length, got[i].Length)
+				}
+			}
+		})
+	}
+
+	t.Run("streaming", func(t *testing.T) {
+		config := SmallChunkConfig()
+		expected, err := chunkData(data, config)
+		require.NoError(t, err)
+
+		config.RollTwoBytes = true
+		got, err := chunkData(data, config)
+		require.NoError(t, err)
+
+		require.Equal(t, len(expected), len(got))
+		for i := range expected {
+			assert.Equal(t, expected[i].Hash, got[i].Hash)
+		}
+	})
+}
+
+func TestChunkerState_Resume(t *testing.T) {
+	data := make([]byte, 1024*1024)
+	_, _ = rand.Read(data)
+
+	config := SmallChunkConfig()
+	config.RollTwoBytes = true
+	config.HashAlgorithm = HashBLAKE3
+
+	expected, err := chunkData(data, config)
+	require.NoError(t, err)
+	require.Greater(t, len(expected), 20)
+
+	chunker, err := NewChunker(bytes.NewReader(data), config)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), chunker.State().Offset)
+
+	for range 10 {
+		_, err := chunker.Next()
+		require.NoError(t, err)
+	}
+
+	state := chunker.State()
+	assert.Equal(t, expected[10].Offset, state.Offset)
+	assert.Equal(t, config, state.Config)
+
+	// Simulate a restart: persist, parse, reopen the stream at the offset
+	parsed, err := ParseChunkerState(state.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, state, parsed)
+
+	resumed, err := ResumeChunker(bytes.NewReader(data[parsed.Offset:]), parsed)
+	require.NoError(t, err)
+
+	var got []*Chunk
+	for {
+		chunk, err := resumed.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, chunk)
+	}
+
+	require.Equal(t, len(expected)-10, len(got))
+	for i, chunk := range got {
+		assert.Equal(t, expected[10+i].Offset, chunk.Offset)
+		assert.Equal(t, expected[10+i].Length, chunk.Length)
+		assert.Equal(t, expected[10+i].Hash, chunk.Hash)
+	}
+	assert.Equal(t, uint64(len(data)), resumed.State().Offset)
+
+	_, err = ResumeChunker(bytes.NewReader(nil), ChunkerState{})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestParseChunkerState(t *testing.T) {
+	valid := ChunkerState{Offset: 12345, Config: DefaultConfig()}
+	encoded := valid.Bytes()
+	assert.Len(t, encoded, stateSize)
+
+	parsed, err := ParseChunkerState(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, valid, parsed)
+
+	corrupt := func(fn func([]byte)) []byte {
+		b := bytes.Clone(encoded)
+		fn(b)
+		return b
+	}
+
+	tests := map[string][]byte{
+		"empty":         nil,
+		"truncated":     encoded[:stateSize-1],
+		"version":       corrupt(func(b []byte) { b[0] = 99 }),
+		"unknown flags": corrupt(func(b []byte) { b[43] = 0x80 }),
+		"invalid sizes": corrupt(func(b []byte) { b[9], b[17] = 0xff, 0 }),
+		"normalization": corrupt(func(b []byte) { b[41] = 9 }),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseChunkerState(data)
+			assert.ErrorIs(t, err, ErrInvalidState)
+		})
+	}
+}
+
+func BenchmarkFindBoundary_RollTwoBytes(b *testing.B) {
+	data := make([]byte, 10*1024*1024)
+	_, _ = rand.Read(data)
+
+	for _, rollTwo := range []bool{false, true} {
+		name := "one_byte"
+		if rollTwo {
+			name = "two_bytes"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			config := MediumChunkConfig()
+			config.RollTwoBytes = rollTwo
+			params := newBoundaryParams(config)
+
+			b.SetBytes(int64(len(data)))
+
+			for b.Loop() {
+				for offset := 0; offset < len(data); {
+					offset += findBoundaryInSlice(data[offset:], params)
+				}
+			}
+		})
+	}
+}
+
+func FuzzRollTwoBytes(f *testing.F) {
+	f.Add([]byte("hello world"), uint16(64))
+	f.Add(make([]byte, 4096), uint16(100))
+
+	f.Fuzz(func(t *testing.T, data []byte, minSize uint16) {
+		lo := uint64(minSize%1024) + 1
+		p := boundaryParams{
+			minSize: lo,
+			maxSize: lo * 8,
+			avgSize: lo * 2,
+		}
+		p.maskS, p.maskL = computeMasks(p.avgSize, 2)
+
+		one := findBoundaryInSlice(data, p)
+		p.rollTwo = true
+		two := findBoundaryInSlice(data, p)
+
+		if one != two {
+			t.Errorf("boundary mismatch: one-byte %d, two-bytes %d", one, two)
+		}
+	})
+}
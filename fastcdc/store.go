@@ -0,0 +1,392 @@
+package fastcdc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrChunkNotFound indicates the chunk is not in the store
+	ErrChunkNotFound = errors.New("fastcdc: chunk not found")
+
+	// ErrChunkCorrupt indicates stored chunk data does not match its hash or length
+	ErrChunkCorrupt = errors.New("fastcdc: chunk corrupt")
+
+	// ErrInvalidManifest indicates manifest chunks do not cover the recorded size
+	ErrInvalidManifest = errors.New("fastcdc: invalid manifest")
+
+	// ErrInvalidSeek indicates a seek to a negative position or with an invalid whence
+	ErrInvalidSeek = errors.New("fastcdc: invalid seek")
+)
+
+// ChunkStore stores chunk data addressed by hash.
+// Put must be idempotent and must not retain data after returning.
+// Implementations must be safe for concurrent use.
+type ChunkStore interface {
+	Put(hash, data []byte) error
+	Get(hash []byte) ([]byte, error)
+	Has(hash []byte) (bool, error)
+	Delete(hash []byte) error
+}
+
+// MemoryStore is an in-memory ChunkStore
+type MemoryStore struct {
+	mu     sync.RWMutex
+	chunks map[string][]byte
+	size   uint64
+}
+
+// NewMemoryStore creates an empty in-memory chunk store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		chunks: make(map[string][]byte),
+	}
+}
+
+// Put stores a copy of data under hash. Existing chunks are left unchanged.
+func (s *MemoryStore) Put(hash, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.chunks[string(hash)]; ok {
+		return nil
+	}
+
+	s.chunks[string(hash)] = bytes.Clone(data)
+	s.size += uint64(len(data))
+
+	return nil
+}
+
+// Get returns the chunk stored under hash. The returned slice must not be modified.
+func (s *MemoryStore) Get(hash []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.chunks[string(hash)]
+	if !ok {
+		return nil, ErrChunkNotFound
+	}
+
+	return data, nil
+}
+
+// Has reports whether a chunk is stored under hash
+func (s *MemoryStore) Has(hash []byte) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.chunks[string(hash)]
+	return ok, nil
+}
+
+// Delete removes the chunk stored under hash. Deleting a missing chunk is not an error.
+func (s *MemoryStore) Delete(hash []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if data, ok := s.chunks[string(hash)]; ok {
+		s.size -= uint64(len(data))
+		delete(s.chunks, string(hash))
+	}
+
+	return nil
+}
+
+// Len returns the number of stored chunks
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.chunks)
+}
+
+// Size returns the total size of stored chunk data in bytes
+func (s *MemoryStore) Size() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size
+}
+
+// FileStore is a ChunkStore on the local filesystem.
+// Each chunk is a file named by its hex hash inside a directory named by the
+// first two hex digits, e.g. root/ab/abcdef..., keeping directories small.
+// Chunks are written to a temporary file and renamed into place, so readers
+// never observe partial chunks.
+type FileStore struct {
+	root string
+}
+
+// NewFileStore creates a filesystem chunk store rooted at root,
+// creating the directory if needed
+func NewFileStore(root string) (*FileStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{root: root}, nil
+}
+
+// Root returns the store's root directory
+func (s *FileStore) Root() string {
+	return s.root
+}
+
+// Put writes data under hash. Existing chunks are left unchanged.
+func (s *FileStore) Put(hash, data []byte) error {
+	path := s.path(hash)
+
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Get reads the chunk stored under hash
+func (s *FileStore) Get(hash []byte) ([]byte, error) {
+	data, err := os.ReadFile(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrChunkNotFound
+	}
+
+	return data, err
+}
+
+// Has reports whether a chunk is stored under hash
+func (s *FileStore) Has(hash []byte) (bool, error) {
+	_, err := os.Stat(s.path(hash))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// Delete removes the chunk stored under hash. Deleting a missing chunk is not an error.
+func (s *FileStore) Delete(hash []byte) error {
+	err := os.Remove(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// path returns the file path for hash
+func (s *FileStore) path(hash []byte) string {
+	name := hex.EncodeToString(hash)
+	if len(name) < 2 {
+		return filepath.Join(s.root, name)
+	}
+
+	return filepath.Join(s.root, name[:2], name)
+}
+
+// ManifestEntry describes one chunk of a stored stream
+type ManifestEntry struct {
+	Offset uint64 `json:"offset"`
+	Length uint64 `json:"length"`
+	Hash   []byte `json:"hash"`
+}
+
+// Manifest records the chunk sequence of a stored stream
+type Manifest struct {
+	HashAlgorithm HashAlgorithm   `json:"hash_algorithm"`
+	Size          uint64          `json:"size"`
+	Chunks        []ManifestEntry `json:"chunks"`
+}
+
+// Store splits streams into content-defined chunks, keeps each distinct
+// chunk once in a ChunkStore and reassembles streams from manifests.
+type Store struct {
+	chunks ChunkStore
+	config Config
+}
+
+// NewStore creates a store that chunks streams with config and keeps
+// chunk data in chunks
+func NewStore(chunks ChunkStore, config Config) (*Store, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		chunks: chunks,
+		config: config,
+	}, nil
+}
+
+// Chunks returns the underlying chunk store
+func (s *Store) Chunks() ChunkStore {
+	return s.chunks
+}
+
+// Put chunks r, stores chunks not already present and returns the manifest
+func (s *Store) Put(r io.Reader) (Manifest, error) {
+	chunker, err := NewChunker(r, s.config)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{HashAlgorithm: s.config.HashAlgorithm}
+
+	err = chunker.ForEach(func(chunk *Chunk) error {
+		hash := bytes.Clone(chunk.HashBytes())
+
+		if err := s.chunks.Put(hash, chunk.Data); err != nil {
+			return err
+		}
+
+		manifest.Chunks = append(manifest.Chunks, ManifestEntry{
+			Offset: chunk.Offset,
+			Length: chunk.Length,
+			Hash:   hash,
+		})
+		manifest.Size += chunk.Length
+
+		return nil
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// Open returns a reader that reassembles the stream described by m.
+// Chunks are fetched lazily and verified against their hash; read errors
+// such as ErrChunkNotFound and ErrChunkCorrupt are returned from Read.
+func (s *Store) Open(m Manifest) io.ReadSeeker {
+	return &manifestReader{
+		chunks:   s.chunks,
+		manifest: m,
+		hasher:   NewHasher(m.HashAlgorithm),
+		current:  -1,
+	}
+}
+
+// manifestReader reads a stream back from its manifest, caching the
+// current chunk.
+type manifestReader struct {
+	chunks   ChunkStore
+	manifest Manifest
+	hasher   *Hasher
+	pos      uint64
+	current  int // index of the cached chunk, -1 if none
+	data     []byte
+}
+
+func (r *manifestReader) Read(p []byte) (int, error) {
+	if r.pos >= r.manifest.Size {
+		return 0, io.EOF
+	}
+
+	var n int
+	for n < len(p) && r.pos < r.manifest.Size {
+		index := r.find(r.pos)
+		if index == len(r.manifest.Chunks) || r.manifest.Chunks[index].Offset > r.pos {
+			return n, ErrInvalidManifest
+		}
+
+		if err := r.load(index); err != nil {
+			return n, err
+		}
+
+		entry := r.manifest.Chunks[index]
+		c := copy(p[n:], r.data[r.pos-entry.Offset:])
+		n += c
+		r.pos += uint64(c)
+	}
+
+	return n, nil
+}
+
+func (r *manifestReader) Seek(offset int64, whence int) (int64, error) {
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = int64(r.pos)
+	case io.SeekEnd:
+		base = int64(r.manifest.Size)
+	default:
+		return int64(r.pos), ErrInvalidSeek
+	}
+
+	target := base + offset
+	if target < 0 {
+		return int64(r.pos), ErrInvalidSeek
+	}
+
+	r.pos = uint64(target)
+
+	return target, nil
+}
+
+// find returns the index of the chunk containing pos
+func (r *manifestReader) find(pos uint64) int {
+	chunks := r.manifest.Chunks
+	return sort.Search(len(chunks), func(i int) bool {
+		return chunks[i].Offset+chunks[i].Length > pos
+	})
+}
+
+// load fetches and verifies the chunk at index unless it is already cached
+func (r *manifestReader) load(index int) error {
+	if index == r.current {
+		return nil
+	}
+
+	entry := r.manifest.Chunks[index]
+
+	data, err := r.chunks.Get(entry.Hash)
+	if err != nil {
+		return err
+	}
+
+	sum, size := r.hasher.SumFull(data)
+	if uint64(len(data)) != entry.Length || !bytes.Equal(sum[:size], entry.Hash) {
+		return ErrChunkCorrupt
+	}
+
+	r.current = index
+	r.data = data
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package fastcdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkStores(t *testing.T) {
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "chunks"))
+	require.NoError(t, err)
+
+	stores := map[string]ChunkStore{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("chunk data")
+			hash := ComputeHash(data, HashSHA256)
+
+			ok, err := store.Has(hash)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			_, err = store.Get(hash)
+			assert.ErrorIs(t, err, ErrChunkNotFound)
+
+			require.NoError(t, store.Put(hash, data))
+			require.NoError(t, store.Put(hash, data))
+
+			ok, err = store.Has(hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			got, err := store.Get(hash)
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+
+			// Store must not retain the caller's buffer
+			data[0] = 'X'
+			got, err = store.Get(hash)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("chunk data"), got)
+
+			require.NoError(t, store.Delete(hash))
+			require.NoError(t, store.Delete(hash))
+
+			ok, err = store.Has(hash)
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestMemoryStore_Stats(t *testing.T) {
+	store := NewMemoryStore()
+
+	require.NoError(t, store.Put([]byte{1}, make([]byte, 10)))
+	require.NoError(t, store.Put([]byte{2}, make([]byte, 20)))
+	require.NoError(t, store.Put([]byte{2}, make([]byte, 20)))
+
+	assert.Equal(t, 2, store.Len())
+	assert.Equal(t, uint64(30), store.Size())
+
+	require.NoError(t, store.Delete([]byte{1}))
+	assert.Equal(t, 1, store.Len())
+	assert.Equal(t, uint64(20), store.Size())
+}
+
+func TestFileStore_Layout(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFileStore(root)
+	require.NoError(t, err)
+	assert.Equal(t, root, store.Root())
+
+	hash := []byte{0xab, 0xcd, 0xef}
+	require.NoError(t, store.Put(hash, []byte("data")))
+
+	content, err := os.ReadFile(filepath.Join(root, "ab", "abcdef"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), content)
+
+	entries, err := os.ReadDir(filepath.Join(root, "ab"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files left behind")
+}
+
+func TestStore_PutOpen(t *testing.T) {
+	data := make([]byte, 512*1024)
+	_, _ = rand.Read(data)
+
+	for _, algorithm := range []HashAlgorithm{HashSHA256, HashBLAKE3} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			config := SmallChunkConfig()
+			config.HashAlgorithm = algorithm
+
+			store, err := NewStore(NewMemoryStore(), config)
+			require.NoError(t, err)
+
+			manifest, err := store.Put(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, manifest.HashAlgorithm)
+			assert.Equal(t, uint64(len(data)), manifest.Size)
+			assert.Greater(t, len(manifest.Chunks), 1)
+
+			var offset uint64
+			for _, entry := range manifest.Chunks {
+				assert.Equal(t, offset, entry.Offset)
+				assert.Len(t, entry.Hash, algorithm.Size())
+				offset += entry.Length
+			}
+
+			got, err := io.ReadAll(store.Open(manifest))
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+		})
+	}
+}
+
+func TestStore_Dedup(t *testing.T) {
+	data := make([]byte, 256*1024)
+	_, _ = rand.Read(data)
+
+	chunks := NewMemoryStore()
+	store, err := NewStore(chunks, SmallChunkConfig())
+	require.NoError(t, err)
+
+	_, err = store.Put(bytes.NewReader(data))
+	require.NoError(t, err)
+	size := chunks.Size()
+	assert.Equal(t, uint64(len(data)), size)
+
+	// Same content again adds nothing; an appended tail adds only the new chunks
+	_, err = store.Put(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, size, chunks.Size())
+
+	extended := append(bytes.Clone(data), make([]byte, 4096)...)
+	manifest, err := store.Put(bytes.NewReader(extended))
+	require.NoError(t, err)
+	assert.Less(t, chunks.Size(), size+uint64(len(extended))/2)
+
+	got, err := io.ReadAll(store.Open(manifest))
+	require.NoError(t, err)
+	assert.Equal(t, extended, got)
+}
+
+func TestStore_Seek(t *testing.T) {
+	data := make([]byte, 200*1024)
+	_, _ = rand.Read(data)
+
+	fileStore, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	store, err := NewStore(fileStore, SmallChunkConfig())
+	require.NoError(t, err)
+
+	manifest, err := store.Put(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	r := store.Open(manifest)
+
+	tests := []struct {
+		offset int64
+		whence int
+		want   int64
+	}{
+		{100, io.SeekStart, 100},
+		{70000, io.SeekStart, 70000},
+		{-5000, io.SeekEnd, int64(len(data)) - 5000},
+		{-30000, io.SeekCurrent, int64(len(data)) - 32000},
+		{0, io.SeekStart, 0},
+	}
+
+	for _, tt := range tests {
+		pos, err := r.Seek(tt.offset, tt.whence)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, pos)
+
+		buf := make([]byte, 3000)
+		n, err := io.ReadFull(r, buf)
+		require.NoError(t, err)
+		assert.Equal(t, data[pos:pos+int64(n)], buf)
+
+		tt.want += int64(n)
+		current, err := r.Seek(0, io.SeekCurrent)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, current)
+	}
+
+	_, err = r.Seek(0, io.SeekEnd)
+	require.NoError(t, err)
+	n, err := r.Read(make([]byte, 10))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = r.Seek(-1, io.SeekStart)
+	assert.ErrorIs(t, err, ErrInvalidSeek)
+
+	_, err = r.Seek(0, 42)
+	assert.ErrorIs(t, err, ErrInvalidSeek)
+}
+
+func TestStore_Errors(t *testing.T) {
+	data := make([]byte, 64*1024)
+	_, _ = rand.Read(data)
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewStore(NewMemoryStore(), Config{})
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("missing chunk", func(t *testing.T) {
+		chunks := NewMemoryStore()
+		store, err := NewStore(chunks, SmallChunkConfig())
+		require.NoError(t, err)
+
+		manifest, err := store.Put(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.NoError(t, chunks.Delete(manifest.Chunks[1].Hash))
+
+		_, err = io.ReadAll(store.Open(manifest))
+		assert.ErrorIs(t, err, ErrChunkNotFound)
+	})
+
+	t.Run("corrupt chunk", func(t *testing.T) {
+		fileStore, err := NewFileStore(t.TempDir())
+		require.NoError(t, err)
+
+		store, err := NewStore(fileStore, SmallChunkConfig())
+		require.NoError(t, err)
+
+		manifest, err := store.Put(bytes.NewReader(data))
+		require.NoError(t, err)
+
+		path := fileStore.path(manifest.Chunks[0].Hash)
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		content[0] ^= 0xff
+		require.NoError(t, os.WriteFile(path, content, 0o644))
+
+		_, err = io.ReadAll(store.Open(manifest))
+		assert.ErrorIs(t, err, ErrChunkCorrupt)
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		store, err := NewStore(NewMemoryStore(), SmallChunkConfig())
+		require.NoError(t, err)
+
+		manifest, err := store.Put(bytes.NewReader(data))
+		require.NoError(t, err)
+		manifest.Size += 100
+
+		_, err = io.ReadAll(store.Open(manifest))
+		assert.ErrorIs(t, err, ErrInvalidManifest)
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		store, err := NewStore(NewMemoryStore(), SmallChunkConfig())
+		require.NoError(t, err)
+
+		_, err = store.Put(io.MultiReader(bytes.NewReader(data), errReader{}))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+func TestManifest_JSON(t *testing.T) {
+	store, err := NewStore(NewMemoryStore(), SmallChunkConfig())
+	require.NoError(t, err)
+
+	data := make([]byte, 50*1024)
+	_, _ = rand.Read(data)
+
+	manifest, err := store.Put(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	var decoded Manifest
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, manifest, decoded)
+
+	got, err := io.ReadAll(store.Open(decoded))
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
@@ -0,0 +1,163 @@
+package fastcdc
+
+import (
+	"errors"
+)
+
+var (
+	// ErrWriterClosed indicates a write to a closed ChunkWriter
+	ErrWriterClosed = errors.New("fastcdc: writer closed")
+)
+
+// ChunkWriter is a push-style chunker. Data written to it is split into
+// content-defined chunks that are passed to a ChunkFunc as boundaries are
+// found; Close flushes the final chunks.
+//
+// A boundary is only cut once MaxSize bytes are buffered (or on Close), the
+// same lookahead the reader-based Chunker uses, so both produce identical
+// chunks for the same data and Config.
+//
+// The chunk passed to fn, including its Data, is only valid during the call -
+// do not retain references to it. ChunkWriter is not safe for concurrent use.
+type ChunkWriter struct {
+	config   Config
+	params   boundaryParams
+	fn       ChunkFunc
+	hasher   *Hasher
+	chunk    Chunk
+	buf      []byte
+	bufStart int
+	bufEnd   int
+	offset   uint64 // Stream offset of the next chunk
+	err      error  // Sticky error from fn
+	closed   bool
+}
+
+// NewChunkWriter creates a ChunkWriter that calls fn for every chunk.
+// The internal buffer holds max(BufSize, MaxSize) bytes.
+func NewChunkWriter(config Config, fn ChunkFunc) (*ChunkWriter, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	return &ChunkWriter{
+		config: config,
+		params: newBoundaryParams(config),
+		fn:     fn,
+		hasher: NewHasher(config.HashAlgorithm),
+		buf:    make([]byte, max(config.BufSize, int(config.MaxSize))),
+	}, nil
+}
+
+// Write buffers p and emits every chunk whose boundary is now known.
+// If fn returns an error, Write returns it and the writer stays failed.
+func (w *ChunkWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	var n int
+	for len(p) > 0 {
+		if w.bufEnd == len(w.buf) {
+			w.compact()
+		}
+
+		c := copy(w.buf[w.bufEnd:], p)
+		w.bufEnd += c
+		n += c
+		p = p[c:]
+
+		for w.buffered() >= int(w.config.MaxSize) {
+			if err := w.emit(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Close emits the remaining buffered data as final chunks.
+// Calling Close again returns nil.
+func (w *ChunkWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.err != nil {
+		return w.err
+	}
+
+	for w.buffered() > 0 {
+		if err := w.emit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Offset returns the number of bytes emitted as chunks so far
+func (w *ChunkWriter) Offset() uint64 {
+	return w.offset
+}
+
+// Buffered returns the number of bytes written but not yet emitted
+func (w *ChunkWriter) Buffered() int {
+	return w.buffered()
+}
+
+// State returns a checkpoint at the end of the last emitted chunk.
+// Buffered data is not part of the checkpoint.
+func (w *ChunkWriter) State() ChunkerState {
+	return ChunkerState{
+		Offset: w.offset,
+		Config: w.config,
+	}
+}
+
+// Reset discards buffered data and prepares the writer for a new stream
+func (w *ChunkWriter) Reset() {
+	w.bufStart = 0
+	w.bufEnd = 0
+	w.offset = 0
+	w.err = nil
+	w.closed = false
+}
+
+// emit cuts and delivers the next chunk from the buffer
+func (w *ChunkWriter) emit() error {
+	data := w.buf[w.bufStart:w.bufEnd]
+	chunkLen := findBoundaryInSlice(data, w.params)
+	data = data[:chunkLen]
+
+	w.chunk.Offset = w.offset
+	w.chunk.Length = uint64(chunkLen)
+	w.chunk.Data = data
+	w.chunk.Hash, w.chunk.HashSize = w.hasher.SumFull(data)
+
+	w.bufStart += chunkLen
+	w.offset += uint64(chunkLen)
+
+	if err := w.fn(&w.chunk); err != nil {
+		w.err = err
+		return err
+	}
+
+	return nil
+}
+
+// compact moves buffered data to the start of the buffer
+func (w *ChunkWriter) compact() {
+	n := copy(w.buf, w.buf[w.bufStart:w.bufEnd])
+	w.bufStart = 0
+	w.bufEnd = n
+}
+
+func (w *ChunkWriter) buffered() int {
+	return w.bufEnd - w.bufStart
+}
@@ -0,0 +1,218 @@
+package fastcdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chunkRecord struct {
+	Offset uint64
+	Length uint64
+	Hash   [64]byte
+	Data   []byte
+}
+
+func collectWriter(t *testing.T, config Config, data []byte, writeSize int) []chunkRecord {
+	t.Helper()
+
+	var records []chunkRecord
+	w, err := NewChunkWriter(config, func(chunk *Chunk) error {
+		records = append(records, chunkRecord{chunk.Offset, chunk.Length, chunk.Hash, bytes.Clone(chunk.Data)})
+		return nil
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < len(data); i += writeSize {
+		n, err := w.Write(data[i:min(i+writeSize, len(data))])
+		require.NoError(t, err)
+		require.Equal(t, min(writeSize, len(data)-i), n)
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, uint64(len(data)), w.Offset())
+	assert.Equal(t, 0, w.Buffered())
+
+	return records
+}
+
+func TestChunkWriter_MatchesChunker(t *testing.T) {
+	data := make([]byte, 1024*1024+123)
+	_, _ = rand.Read(data)
+
+	small := SmallChunkConfig()
+	tight := SmallChunkConfig()
+	tight.BufSize = int(tight.MaxSize) // Smallest buffer allowed
+	twoBytes := MediumChunkConfig()
+	twoBytes.RollTwoBytes = true
+	twoBytes.HashAlgorithm = HashXXH64
+
+	configs := map[string]Config{"small": small, "tight": tight, "two_bytes": twoBytes}
+
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			expected, err := chunkData(data, config)
+			require.NoError(t, err)
+
+			for _, writeSize := range []int{1, 7, 4096, 65537, len(data)} {
+				if writeSize == 1 && name != "small" {
+					continue
+				}
+
+				got := collectWriter(t, config, data, writeSize)
+				require.Equal(t, len(expected), len(got), "write size %d", writeSize)
+
+				for i := range expected {
+					assert.Equal(t, expected[i].Offset, got[i].Offset)
+					assert.Equal(t, expected[i].Length, got[i].Length)
+					assert.Equal(t, expected[i].Hash, got[i].Hash)
+					assert.Equal(t, expected[i].Data, got[i].Data)
+				}
+			}
+		})
+	}
+}
+
+func TestChunkWriter_IOCopy(t *testing.T) {
+	data := make([]byte, 300*1024)
+	_, _ = rand.Read(data)
+
+	var out bytes.Buffer
+	w, err := NewChunkWriter(SmallChunkConfig(), func(chunk *Chunk) error {
+		out.Write(chunk.Data)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var wc io.WriteCloser = w
+	_, err = io.Copy(wc, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.NoError(t, wc.Close())
+
+	assert.Equal(t, data, out.Bytes())
+}
+
+func TestChunkWriter_Lifecycle(t *testing.T) {
+	config := SmallChunkConfig()
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewChunkWriter(Config{}, func(*Chunk) error { return nil })
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		got := collectWriter(t, config, nil, 1)
+		assert.Empty(t, got)
+	})
+
+	t.Run("buffering", func(t *testing.T) {
+		var calls int
+		w, err := NewChunkWriter(config, func(*Chunk) error {
+			calls++
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = w.Write(make([]byte, config.MinSize))
+		require.NoError(t, err)
+		assert.Equal(t, 0, calls, "no chunk before MaxSize bytes are buffered")
+		assert.Equal(t, int(config.MinSize), w.Buffered())
+
+		require.NoError(t, w.Close())
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, config.MinSize, w.State().Offset)
+
+		require.NoError(t, w.Close())
+		_, err = w.Write([]byte{1})
+		assert.ErrorIs(t, err, ErrWriterClosed)
+
+		w.Reset()
+		_, err = w.Write([]byte{1})
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, uint64(1), w.Offset())
+	})
+
+	t.Run("callback error", func(t *testing.T) {
+		expectedErr := errors.New("stop")
+		w, err := NewChunkWriter(config, func(*Chunk) error { return expectedErr })
+		require.NoError(t, err)
+
+		_, err = w.Write(make([]byte, 2*config.MaxSize))
+		assert.Equal(t, expectedErr, err)
+
+		_, err = w.Write([]byte{1})
+		assert.Equal(t, expectedErr, err)
+		assert.Equal(t, expectedErr, w.Close())
+	})
+
+	t.Run("close error", func(t *testing.T) {
+		expectedErr := errors.New("stop")
+		w, err := NewChunkWriter(config, func(*Chunk) error { return expectedErr })
+		require.NoError(t, err)
+
+		_, err = w.Write([]byte("tail"))
+		require.NoError(t, err)
+		assert.Equal(t, expectedErr, w.Close())
+	})
+}
+
+func BenchmarkChunkWriter_10MB(b *testing.B) {
+	data := make([]byte, 10*1024*1024)
+	_, _ = rand.Read(data)
+
+	config := MediumChunkConfig()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for b.Loop() {
+		w, _ := NewChunkWriter(config, func(*Chunk) error { return nil })
+		for i := 0; i < len(data); i += 32 * 1024 {
+			_, _ = w.Write(data[i:min(i+32*1024, len(data))])
+		}
+		_ = w.Close()
+	}
+}
+
+func FuzzChunkWriter(f *testing.F) {
+	f.Add([]byte("hello world"), uint16(3))
+	f.Add(make([]byte, 100000), uint16(1000))
+
+	f.Fuzz(func(t *testing.T, data []byte, writeSize uint16) {
+		config := SmallChunkConfig()
+		expected, err := ChunkBytes(data, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []uint64
+		w, err := NewChunkWriter(config, func(chunk *Chunk) error {
+			got = append(got, chunk.Length)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		step := int(writeSize) + 1
+		for i := 0; i < len(data); i += step {
+			_, _ = w.Write(data[i:min(i+step, len(data))])
+		}
+		_ = w.Close()
+
+		if len(got) != len(expected) {
+			t.Fatalf("chunk count %d != %d", len(got), len(expected))
+		}
+		for i := range got {
+			if got[i] != expected[i].Length {
+				t.Fatalf("chunk %d length %d != %d", i, got[i], expected[i].Length)
+			}
+		}
+	})
+}
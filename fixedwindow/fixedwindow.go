@@ -1,8 +1,9 @@
 package fixedwindow
 
 import (
-	"sync"
 	"time"
+
+	"github.com/vitalvas/gokit/ewma"
 )
 
 // Counter implements the Fixed Window Counter algorithm for rate limiting.
@@ -13,24 +14,22 @@ import (
 // Properties:
 //   - O(1) per operation (map lookup + counter increment)
 //   - O(n) memory where n = number of tracked keys
-//   - Per-key independent windows (start on first access, not wall-clock aligned)
+//   - Per-key independent windows (start on first access), or calendar-aligned
+//     windows shared by all keys (see NewAligned)
 //   - Hard lockout on exceed (all subsequent Allow calls denied until window reset)
 //   - Thread-safe
 //   - Max tracked keys with oldest-window eviction
+//
+// By default counters live in a MemoryStore owned by the Counter; use
+// NewWithStore to share them between processes through a Store.
 type Counter struct {
-	mu       sync.Mutex
-	entries  map[string]*entry
 	window   time.Duration
-	maxKeys  int
-	cleanup  *time.Ticker
-	stopOnce sync.Once
-	done     chan struct{}
-}
-
-type entry struct {
-	count     int
-	lockedOut bool
-	windowEnd time.Time
+	period   Period         // Calendar alignment; zero for windows starting on first access
+	location *time.Location // Time zone of aligned windows
+	clock    ewma.Clock
+	local    *MemoryStore // Owned in-process counters; nil when store is set
+	store    Store        // Shared counter store
+	onError  func(error)  // Store error callback
 }
 
 // New creates a new Counter with the specified window duration and maximum number
@@ -41,60 +40,70 @@ type entry struct {
 // and expired entries are only cleaned up lazily during eviction.
 // Call Stop() to release the background goroutine when done.
 func New(window time.Duration, maxKeys int, cleanupInterval time.Duration) *Counter {
-	if window <= 0 {
-		window = time.Minute
-	}
+	return NewWithClock(window, maxKeys, cleanupInterval, ewma.SystemClock)
+}
 
-	if maxKeys <= 0 {
-		maxKeys = 1000
-	}
+// NewWithClock creates a new Counter that reads the current time from clock.
+// If clock is nil, ewma.SystemClock is used. The cleanup interval is still
+// measured in real time, but expiry is decided by clock.
+func NewWithClock(window time.Duration, maxKeys int, cleanupInterval time.Duration, clock ewma.Clock) *Counter {
+	c := newCounter(window, 0, nil, clock)
+	c.local = NewMemoryStoreWithClock(maxKeys, cleanupInterval, c.clock)
 
-	c := &Counter{
-		entries: make(map[string]*entry, maxKeys),
-		window:  window,
-		maxKeys: maxKeys,
-		done:    make(chan struct{}),
-	}
+	return c
+}
 
-	if cleanupInterval > 0 {
-		c.cleanup = time.NewTicker(cleanupInterval)
-		go c.cleanupLoop()
+// NewAligned creates a new Counter whose windows follow the calendar in loc:
+// every key shares the same windows, which start on the minute, hour or day
+// rather than on first access. This suits quotas such as "1000 requests per
+// calendar day". Invalid periods default to PeriodMinute, a nil loc to UTC and
+// a nil clock to ewma.SystemClock. maxKeys and cleanupInterval behave as in New.
+func NewAligned(period Period, loc *time.Location, maxKeys int, cleanupInterval time.Duration, clock ewma.Clock) *Counter {
+	if period == 0 {
+		period = PeriodMinute
 	}
 
+	c := newCounter(0, period, loc, clock)
+	c.local = NewMemoryStoreWithClock(maxKeys, cleanupInterval, c.clock)
+
 	return c
 }
 
-// Stop stops the background cleanup goroutine if one was started.
-// It is safe to call multiple times.
-func (c *Counter) Stop() {
-	c.stopOnce.Do(func() {
-		if c.cleanup != nil {
-			c.cleanup.Stop()
+// newCounter creates a Counter without a backend, substituting defaults for
+// the window settings. A zero period keeps windows starting on first access.
+func newCounter(window time.Duration, period Period, loc *time.Location, clock ewma.Clock) *Counter {
+	if period != 0 {
+		if period < PeriodMinute || period > PeriodDay {
+			period = PeriodMinute
 		}
-		close(c.done)
-	})
-}
 
-func (c *Counter) cleanupLoop() {
-	for {
-		select {
-		case <-c.done:
-			return
-		case <-c.cleanup.C:
-			c.removeExpired()
+		if loc == nil {
+			loc = time.UTC
 		}
+
+		window = period.duration()
+	} else if window <= 0 {
+		window = time.Minute
 	}
-}
 
-func (c *Counter) removeExpired() {
-	now := time.Now()
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	if clock == nil {
+		clock = ewma.SystemClock
+	}
 
-	for key, e := range c.entries {
-		if now.After(e.windowEnd) {
-			delete(c.entries, key)
-		}
+	return &Counter{
+		window:   window,
+		period:   period,
+		location: loc,
+		clock:    clock,
+	}
+}
+
+// Stop stops the background cleanup goroutine if one was started.
+// It is safe to call multiple times. A Counter created with NewWithStore
+// does not stop its store.
+func (c *Counter) Stop() {
+	if c.local != nil {
+		c.local.Stop()
 	}
 }
 
@@ -105,18 +114,17 @@ func (c *Counter) removeExpired() {
 // Once a key exceeds the limit, it is locked out and all subsequent calls
 // to Allow return false until the window resets.
 func (c *Counter) Allow(key string, limit int) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	e := c.getOrCreate(key)
-
-	e.count++
+	allowed, _, _ := c.allow(key, limit)
+	return allowed
+}
 
-	if !e.lockedOut && e.count > limit {
-		e.lockedOut = true
+// allow implements Allow and also returns the new count and window end.
+func (c *Counter) allow(key string, limit int) (bool, int, time.Time) {
+	if c.store != nil {
+		return c.storeAllow(key, limit)
 	}
 
-	return !e.lockedOut
+	return c.local.allow(key, limit, c.windowEnd)
 }
 
 // Add increments the counter for the given key by delta and returns the new count.
@@ -128,10 +136,15 @@ func (c *Counter) Add(key string, delta int) int {
 		return c.Count(key)
 	}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	if c.store != nil {
+		return c.storeAdd(key, delta)
+	}
+
+	now := c.clock.Now()
+	c.local.mu.Lock()
+	defer c.local.mu.Unlock()
 
-	e := c.getOrCreate(key)
+	e := c.local.getOrCreate(key, now, c.windowEnd)
 	e.count += delta
 
 	return e.count
@@ -140,16 +153,17 @@ func (c *Counter) Add(key string, delta int) int {
 // Count returns the current counter value for the key.
 // Returns 0 if the key is not tracked or its window has expired.
 func (c *Counter) Count(key string) int {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	e, ok := c.entries[key]
-	if !ok {
-		return 0
+	if c.store != nil {
+		count, _ := c.storeGet(key)
+		return count
 	}
 
-	if time.Now().After(e.windowEnd) {
-		delete(c.entries, key)
+	now := c.clock.Now()
+	c.local.mu.Lock()
+	defer c.local.mu.Unlock()
+
+	e := c.local.lookup(key, now)
+	if e == nil {
 		return 0
 	}
 
@@ -159,109 +173,80 @@ func (c *Counter) Count(key string) int {
 // IsLockedOut returns whether the key is currently locked out.
 // Returns false if the key is not tracked or its window has expired.
 func (c *Counter) IsLockedOut(key string) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	e, ok := c.entries[key]
-	if !ok {
-		return false
-	}
-
-	if time.Now().After(e.windowEnd) {
-		delete(c.entries, key)
-		return false
+	if c.store != nil {
+		return c.storeIsLockedOut(key)
 	}
 
-	return e.lockedOut
+	return c.local.isLockedOut(key)
 }
 
 // WindowExpiry returns the time when the current window for the given key
 // expires. Returns zero time if the key has no active window.
 func (c *Counter) WindowExpiry(key string) time.Time {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	e, ok := c.entries[key]
-	if !ok {
-		return time.Time{}
+	if c.store != nil {
+		_, windowEnd := c.storeGet(key)
+		return windowEnd
 	}
 
-	if time.Now().After(e.windowEnd) {
-		delete(c.entries, key)
+	now := c.clock.Now()
+	c.local.mu.Lock()
+	defer c.local.mu.Unlock()
+
+	e := c.local.lookup(key, now)
+	if e == nil {
 		return time.Time{}
 	}
 
 	return e.windowEnd
 }
 
-// Reset clears all tracked keys and their counters.
-func (c *Counter) Reset() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.entries = make(map[string]*entry, c.maxKeys)
-}
-
-// Len returns the number of currently tracked keys (including expired but not yet cleaned up).
-func (c *Counter) Len() int {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// Delete removes the counter and lockout state of a single key.
+func (c *Counter) Delete(key string) {
+	if c.store != nil {
+		c.storeDelete(key)
+		return
+	}
 
-	return len(c.entries)
+	_ = c.local.Delete(key)
 }
 
-// getOrCreate returns the entry for the key, creating it if it doesn't exist
-// or resetting it if its window has expired. Caller must hold c.mu.
-func (c *Counter) getOrCreate(key string) *entry {
-	now := time.Now()
-
-	if e, ok := c.entries[key]; ok {
-		if now.After(e.windowEnd) {
-			e.count = 0
-			e.lockedOut = false
-			e.windowEnd = now.Add(c.window)
+// Reset clears all tracked keys and their counters.
+// With a Store, Reset only has an effect if the store has a Reset method.
+func (c *Counter) Reset() {
+	if c.store != nil {
+		if r, ok := c.store.(interface{ Reset() }); ok {
+			r.Reset()
 		}
-		return e
-	}
-
-	c.evictIfNeeded(now)
-
-	e := &entry{
-		windowEnd: now.Add(c.window),
+		return
 	}
-	c.entries[key] = e
 
-	return e
+	c.local.Reset()
 }
 
-// evictIfNeeded removes expired entries first, then evicts the oldest window
-// if still at capacity. Caller must hold c.mu.
-func (c *Counter) evictIfNeeded(now time.Time) {
-	if len(c.entries) < c.maxKeys {
-		return
-	}
-
-	// First pass: remove expired entries
-	for key, e := range c.entries {
-		if now.After(e.windowEnd) {
-			delete(c.entries, key)
+// Len returns the number of currently tracked keys (including expired but not yet cleaned up).
+// With a MemoryStore, Len counts the counter keys in the store. Other stores
+// report their Len method if they have one, which may include lockout
+// markers, or 0 otherwise.
+func (c *Counter) Len() int {
+	if c.store != nil {
+		switch s := c.store.(type) {
+		case *MemoryStore:
+			return s.lenPrefix(countKeyPrefix)
+		case interface{ Len() int }:
+			return s.Len()
 		}
+		return 0
 	}
 
-	if len(c.entries) < c.maxKeys {
-		return
-	}
-
-	// Evict the key with the oldest (earliest) window end
-	var oldestKey string
-	var oldestEnd time.Time
+	return c.local.Len()
+}
 
-	for key, e := range c.entries {
-		if oldestKey == "" || e.windowEnd.Before(oldestEnd) {
-			oldestKey = key
-			oldestEnd = e.windowEnd
-		}
+// windowEnd returns the end of a window starting at now: one window length
+// later, or the next period boundary for aligned counters.
+func (c *Counter) windowEnd(now time.Time) time.Time {
+	if c.period == 0 {
+		return now.Add(c.window)
 	}
 
-	delete(c.entries, oldestKey)
+	return c.period.next(now.In(c.location))
 }
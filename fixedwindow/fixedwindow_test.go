@@ -7,8 +7,21 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/vitalvas/gokit/ewma"
 )
 
+// newTestCounter returns a counter whose clock only moves when advanced
+func newTestCounter(t *testing.T, window time.Duration, maxKeys int) (*Counter, *ewma.ManualClock) {
+	t.Helper()
+
+	clock := ewma.NewManualClock(time.Unix(1700000000, 0))
+	c := NewWithClock(window, maxKeys, 0, clock)
+	t.Cleanup(c.Stop)
+
+	return c, clock
+}
+
 func TestNew(t *testing.T) {
 	t.Run("default values", func(t *testing.T) {
 		c := New(time.Second, 100, 0)
@@ -37,28 +50,37 @@ func TestNew(t *testing.T) {
 		c := New(time.Second, 0, 0)
 		defer c.Stop()
 
-		assert.Equal(t, 1000, c.maxKeys)
+		assert.Equal(t, 1000, c.local.maxKeys)
 	})
 
 	t.Run("negative maxKeys uses default", func(t *testing.T) {
 		c := New(time.Second, -5, 0)
 		defer c.Stop()
 
-		assert.Equal(t, 1000, c.maxKeys)
+		assert.Equal(t, 1000, c.local.maxKeys)
 	})
 
 	t.Run("with cleanup interval", func(t *testing.T) {
 		c := New(time.Second, 100, 50*time.Millisecond)
 		defer c.Stop()
 
-		assert.NotNil(t, c.cleanup)
+		assert.NotNil(t, c.local.cleanup)
 	})
 
 	t.Run("without cleanup interval", func(t *testing.T) {
 		c := New(time.Second, 100, 0)
 		defer c.Stop()
 
-		assert.Nil(t, c.cleanup)
+		assert.Nil(t, c.local.cleanup)
+	})
+
+	t.Run("nil clock uses system clock", func(t *testing.T) {
+		c := NewWithClock(0, 0, 0, nil)
+		defer c.Stop()
+
+		assert.Equal(t, ewma.SystemClock, c.clock)
+		assert.Equal(t, time.Minute, c.window)
+		assert.Zero(t, c.period)
 	})
 }
 
@@ -125,8 +147,7 @@ func TestAllow(t *testing.T) {
 	})
 
 	t.Run("window expiry resets lockout", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 100)
 
 		// Lock out key
 		for range 3 {
@@ -134,8 +155,11 @@ func TestAllow(t *testing.T) {
 		}
 		assert.False(t, c.Allow("key1", 1))
 
-		// Wait for window to expire
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(59 * time.Second)
+		assert.False(t, c.Allow("key1", 1))
+
+		// Window end is exclusive
+		clock.Advance(time.Second)
 
 		// Should be allowed again
 		assert.True(t, c.Allow("key1", 1))
@@ -217,11 +241,10 @@ func TestAdd(t *testing.T) {
 	})
 
 	t.Run("window expiry resets counter", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 100)
 
 		c.Add("key1", 10)
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(time.Minute)
 
 		count := c.Add("key1", 1)
 		assert.Equal(t, 1, count)
@@ -245,23 +268,21 @@ func TestCount(t *testing.T) {
 	})
 
 	t.Run("expired key returns zero", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 100)
 
 		c.Add("key1", 5)
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(time.Minute)
 
 		assert.Equal(t, 0, c.Count("key1"))
 	})
 
 	t.Run("expired key is removed", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 100)
 
 		c.Add("key1", 5)
 		assert.Equal(t, 1, c.Len())
 
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(time.Minute)
 		c.Count("key1") // Triggers lazy cleanup
 
 		assert.Equal(t, 0, c.Len())
@@ -295,15 +316,14 @@ func TestIsLockedOut(t *testing.T) {
 	})
 
 	t.Run("lockout resets after window expiry", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 100)
 
 		for range 3 {
 			c.Allow("key1", 1)
 		}
 		assert.True(t, c.IsLockedOut("key1"))
 
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(time.Minute)
 		assert.False(t, c.IsLockedOut("key1"))
 	})
 
@@ -338,39 +358,34 @@ func TestWindowExpiry(t *testing.T) {
 	})
 
 	t.Run("expired key returns zero time", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 100)
 
 		c.Add("key1", 1)
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(time.Minute)
 
 		assert.True(t, c.WindowExpiry("key1").IsZero())
 	})
 
 	t.Run("expired key is removed", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 100)
 
 		c.Add("key1", 1)
 		assert.Equal(t, 1, c.Len())
 
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(time.Minute)
 		c.WindowExpiry("key1")
 
 		assert.Equal(t, 0, c.Len())
 	})
 
 	t.Run("consistent with window duration", func(t *testing.T) {
-		window := 200 * time.Millisecond
-		c := New(window, 100, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, 200*time.Millisecond, 100)
 
-		before := time.Now()
+		start := clock.Now()
 		c.Add("key1", 1)
-		expiry := c.WindowExpiry("key1")
+		clock.Advance(50 * time.Millisecond)
 
-		expected := before.Add(window)
-		assert.InDelta(t, expected.UnixMilli(), expiry.UnixMilli(), 50)
+		assert.Equal(t, start.Add(200*time.Millisecond), c.WindowExpiry("key1"))
 	})
 }
 
@@ -405,6 +420,24 @@ func TestReset(t *testing.T) {
 	})
 }
 
+func TestDelete(t *testing.T) {
+	c := New(time.Minute, 100, 0)
+	defer c.Stop()
+
+	for range 3 {
+		c.Allow("key1", 2)
+	}
+	c.Add("key2", 1)
+	assert.True(t, c.IsLockedOut("key1"))
+
+	c.Delete("key1")
+	assert.Equal(t, 0, c.Count("key1"))
+	assert.False(t, c.IsLockedOut("key1"))
+	assert.Equal(t, 1, c.Len())
+
+	c.Delete("missing") // Should not panic
+}
+
 func TestLen(t *testing.T) {
 	t.Run("empty counter", func(t *testing.T) {
 		c := New(time.Minute, 100, 0)
@@ -431,13 +464,12 @@ func TestLen(t *testing.T) {
 
 func TestEviction(t *testing.T) {
 	t.Run("evicts oldest when at capacity", func(t *testing.T) {
-		c := New(time.Minute, 3, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 3)
 
 		c.Add("key1", 1)
-		time.Sleep(time.Millisecond)
+		clock.Advance(time.Millisecond)
 		c.Add("key2", 1)
-		time.Sleep(time.Millisecond)
+		clock.Advance(time.Millisecond)
 		c.Add("key3", 1)
 
 		assert.Equal(t, 3, c.Len())
@@ -450,11 +482,10 @@ func TestEviction(t *testing.T) {
 	})
 
 	t.Run("evicts expired before active", func(t *testing.T) {
-		c := New(50*time.Millisecond, 3, 0)
-		defer c.Stop()
+		c, clock := newTestCounter(t, time.Minute, 3)
 
 		c.Add("key1", 1)
-		time.Sleep(80 * time.Millisecond)
+		clock.Advance(time.Minute)
 
 		// key1 is now expired
 		c.Add("key2", 1)
@@ -483,17 +514,21 @@ func TestEviction(t *testing.T) {
 
 func TestBackgroundCleanup(t *testing.T) {
 	t.Run("removes expired entries", func(t *testing.T) {
-		c := New(50*time.Millisecond, 100, 30*time.Millisecond)
+		clock := ewma.NewManualClock(time.Unix(1700000000, 0))
+		c := NewWithClock(time.Minute, 100, 10*time.Millisecond, clock)
 		defer c.Stop()
 
 		c.Add("key1", 1)
 		c.Add("key2", 1)
 		assert.Equal(t, 2, c.Len())
 
-		// Wait for entries to expire and cleanup to run
-		time.Sleep(120 * time.Millisecond)
+		// Cleanup runs on real time but decides expiry by the clock
+		clock.Advance(30 * time.Second)
+		c.Add("key3", 1)
+		clock.Advance(30 * time.Second)
 
-		assert.Equal(t, 0, c.Len())
+		assert.Eventually(t, func() bool { return c.Len() == 1 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 1, c.Count("key3"))
 	})
 }
 
@@ -0,0 +1,188 @@
+package fixedwindow
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vitalvas/gokit/xnet"
+)
+
+type connContextKey struct{}
+
+// ConnContext stores the connection in the request context so that ClientIP
+// can see through PROXY protocol connections. Assign it to http.Server.ConnContext.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// ClientIP returns the address of the client that sent r, walking the chain of
+// forwarding hops from the nearest to the farthest and stopping at the first
+// hop that is not in trusted:
+//
+//  1. The TCP peer: xnet.ProxyProtoConn.RealRemoteAddr when the connection was
+//     stored by ConnContext, otherwise r.RemoteAddr
+//  2. The PROXY protocol source address, if the connection carried a header
+//  3. X-Forwarded-For entries from right to left
+//
+// With a nil or empty matcher no hop is trusted, so the TCP peer is returned.
+// Returns nil if no address can be parsed.
+func ClientIP(r *http.Request, trusted *xnet.CIDRMatcher) net.IP {
+	var hops []net.IP // Farthest first
+
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for entry := range strings.SplitSeq(header, ",") {
+			ip := net.ParseIP(strings.TrimSpace(entry))
+			if ip == nil {
+				// Nothing left of a malformed entry can be trusted
+				hops = hops[:0]
+				continue
+			}
+			hops = append(hops, ip)
+		}
+	}
+
+	if conn, ok := r.Context().Value(connContextKey{}).(*xnet.ProxyProtoConn); ok {
+		if conn.ProxyHeader() != nil {
+			hops = append(hops, addrIP(conn.RemoteAddr()))
+		}
+		hops = append(hops, addrIP(conn.RealRemoteAddr()))
+	} else {
+		hops = append(hops, hostIP(r.RemoteAddr))
+	}
+
+	i := len(hops) - 1
+	for i > 0 && hops[i] != nil && trusted != nil && trusted.Contains(hops[i]) {
+		i--
+	}
+
+	return hops[i]
+}
+
+// ClientIPKey returns a key function using ClientIP with the given trusted
+// proxies. Requests without a parseable address get an empty key.
+func ClientIPKey(trusted *xnet.CIDRMatcher) func(*http.Request) string {
+	return func(r *http.Request) string {
+		ip := ClientIP(r, trusted)
+		if ip == nil {
+			return ""
+		}
+
+		return ip.String()
+	}
+}
+
+// HeaderKey returns a key function using the value of a request header,
+// such as an API key. Requests without the header get an empty key.
+func HeaderKey(name string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// MiddlewareConfig configures Middleware.
+type MiddlewareConfig struct {
+	// Limit is the number of requests allowed per key and window.
+	// Zero or negative defaults to 60.
+	Limit int
+
+	// KeyFunc extracts the rate limit key from a request. Requests with an
+	// empty key are not limited. Defaults to ClientIPKey(nil).
+	KeyFunc func(*http.Request) string
+
+	// Body is written with 429 responses. Defaults to "Too Many Requests\n".
+	Body []byte
+
+	// ContentType of Body. Defaults to "text/plain; charset=utf-8".
+	ContentType string
+}
+
+// Middleware returns net/http middleware that limits requests per key with c.
+// Every limited response carries RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers (seconds until the window ends); rejected requests
+// get 429 Too Many Requests with a Retry-After header and the configured body.
+func Middleware(c *Counter, config MiddlewareConfig) func(http.Handler) http.Handler {
+	if config.Limit <= 0 {
+		config.Limit = 60
+	}
+
+	if config.KeyFunc == nil {
+		config.KeyFunc = ClientIPKey(nil)
+	}
+
+	if config.Body == nil {
+		config.Body = []byte(http.StatusText(http.StatusTooManyRequests) + "\n")
+	}
+
+	if config.ContentType == "" {
+		config.ContentType = "text/plain; charset=utf-8"
+	}
+
+	limit := strconv.Itoa(config.Limit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := config.KeyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, count, windowEnd := c.allow(key, config.Limit)
+
+			reset := "0"
+			if !windowEnd.IsZero() {
+				reset = strconv.Itoa(ceilSeconds(windowEnd.Sub(c.clock.Now())))
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", limit)
+			h.Set("RateLimit-Remaining", strconv.Itoa(max(config.Limit-count, 0)))
+			h.Set("RateLimit-Reset", reset)
+
+			if !allowed {
+				h.Set("Retry-After", reset)
+				h.Set("Content-Type", config.ContentType)
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write(config.Body)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds, never below zero.
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// hostIP parses the IP of a host:port or bare host address.
+func hostIP(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	return net.ParseIP(addr)
+}
+
+// addrIP extracts the IP of a network address.
+func addrIP(addr net.Addr) net.IP {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+
+	if addr == nil {
+		return nil
+	}
+
+	return hostIP(addr.String())
+}
@@ -0,0 +1,253 @@
+package fixedwindow
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vitalvas/gokit/xnet"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := xnet.NewCIDRMatcherFromStrings([]string{"10.0.0.0/8", "fd00::/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		trusted    *xnet.CIDRMatcher
+		expected   string
+	}{
+		{"remote addr", "192.0.2.1:1234", nil, trusted, "192.0.2.1"},
+		{"remote addr without port", "192.0.2.1", nil, trusted, "192.0.2.1"},
+		{"ipv6 remote addr", "[2001:db8::1]:1234", nil, trusted, "2001:db8::1"},
+		{"untrusted peer ignores xff", "192.0.2.1:1234", []string{"198.51.100.1"}, trusted, "192.0.2.1"},
+		{"no trusted proxies ignores xff", "10.0.0.1:1234", []string{"198.51.100.1"}, nil, "10.0.0.1"},
+		{"trusted peer uses xff", "10.0.0.1:1234", []string{"198.51.100.1"}, trusted, "198.51.100.1"},
+		{"trusted chain", "10.0.0.1:1234", []string{"198.51.100.1, 10.0.0.2", "10.0.0.3"}, trusted, "198.51.100.1"},
+		{"spoofed leftmost entry", "10.0.0.1:1234", []string{"203.0.113.9, 198.51.100.1"}, trusted, "198.51.100.1"},
+		{"all hops trusted", "10.0.0.1:1234", []string{"10.0.0.2"}, trusted, "10.0.0.2"},
+		{"malformed entry", "10.0.0.1:1234", []string{"198.51.100.1, garbage, 10.0.0.2"}, trusted, "10.0.0.2"},
+		{"ipv6 xff", "[fd00::1]:1234", []string{"2001:db8::2"}, trusted, "2001:db8::2"},
+		{"invalid remote addr", "invalid", nil, trusted, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			ip := ClientIP(r, tt.trusted)
+			if tt.expected == "" {
+				assert.Nil(t, ip)
+				assert.Empty(t, ClientIPKey(tt.trusted)(r))
+				return
+			}
+
+			assert.Equal(t, tt.expected, ip.String())
+			assert.Equal(t, tt.expected, ClientIPKey(tt.trusted)(r))
+		})
+	}
+}
+
+func TestClientIP_ProxyProtocol(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	type result struct {
+		untrusted string
+		trusted   string
+	}
+	results := make(chan result, 1)
+
+	loopback, err := xnet.NewCIDRMatcherFromStrings([]string{"127.0.0.0/8", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	srv := &http.Server{
+		ConnContext: ConnContext,
+		Handler: http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			results <- result{
+				untrusted: ClientIPKey(nil)(r),
+				trusted:   ClientIPKey(loopback)(r),
+			}
+		}),
+	}
+	go srv.Serve(xnet.NewProxyProtoListener(ln, xnet.ProxyProtoConfig{}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = fmt.Fprint(conn,
+		"PROXY TCP4 10.0.0.5 10.0.0.1 40000 80\r\n"+
+			"GET / HTTP/1.1\r\nHost: example.com\r\nX-Forwarded-For: 198.51.100.7\r\nConnection: close\r\n\r\n")
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	select {
+	case res := <-results:
+		// Without trusted proxies the TCP peer is the client
+		assert.Equal(t, "127.0.0.1", res.untrusted)
+		// Trusting the peer and the PROXY source reaches X-Forwarded-For
+		assert.Equal(t, "198.51.100.7", res.trusted)
+	case <-time.After(time.Second):
+		t.Fatal("handler was not called")
+	}
+}
+
+func TestHeaderKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, HeaderKey("X-API-Key")(r))
+
+	r.Header.Set("X-API-Key", "secret")
+	assert.Equal(t, "secret", HeaderKey("X-API-Key")(r))
+}
+
+func TestMiddleware(t *testing.T) {
+	newHandler := func(c *Counter, config MiddlewareConfig) http.Handler {
+		return Middleware(c, config)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "ok")
+		}))
+	}
+
+	serve := func(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("headers and limiting", func(t *testing.T) {
+		c := New(time.Minute, 100, 0)
+		defer c.Stop()
+
+		h := newHandler(c, MiddlewareConfig{Limit: 2})
+
+		for i := range 2 {
+			w := serve(h, "192.0.2.1:1234")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "ok", w.Body.String())
+			assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, fmt.Sprint(1-i), w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+			assert.Empty(t, w.Header().Get("Retry-After"))
+		}
+
+		w := serve(h, "192.0.2.1:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "Too Many Requests\n", w.Body.String())
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		// Other clients are not affected
+		assert.Equal(t, http.StatusOK, serve(h, "192.0.2.2:1234").Code)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		c := New(time.Minute, 100, 0)
+		defer c.Stop()
+
+		w := serve(newHandler(c, MiddlewareConfig{}), "192.0.2.1:1234")
+		assert.Equal(t, "60", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "59", w.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("custom body", func(t *testing.T) {
+		c := New(time.Minute, 100, 0)
+		defer c.Stop()
+
+		h := newHandler(c, MiddlewareConfig{
+			Limit:       1,
+			Body:        []byte(`{"error":"rate limited"}`),
+			ContentType: "application/json",
+		})
+
+		serve(h, "192.0.2.1:1234")
+		w := serve(h, "192.0.2.1:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, `{"error":"rate limited"}`, w.Body.String())
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	})
+
+	t.Run("empty key is not limited", func(t *testing.T) {
+		c := New(time.Minute, 100, 0)
+		defer c.Stop()
+
+		h := newHandler(c, MiddlewareConfig{Limit: 1, KeyFunc: HeaderKey("X-API-Key")})
+
+		for range 3 {
+			w := serve(h, "192.0.2.1:1234")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+		}
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("store", func(t *testing.T) {
+		s := NewMemoryStore(100, 0)
+		defer s.Stop()
+
+		h := newHandler(NewWithStore(time.Minute, s, nil), MiddlewareConfig{Limit: 1})
+
+		assert.Equal(t, http.StatusOK, serve(h, "192.0.2.1:1234").Code)
+		w := serve(h, "192.0.2.1:1234")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("store failure fails open", func(t *testing.T) {
+		h := newHandler(NewWithStore(time.Minute, failingStore{}, nil), MiddlewareConfig{Limit: 1})
+
+		for range 2 {
+			w := serve(h, "192.0.2.1:1234")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+			assert.Equal(t, "0", w.Header().Get("RateLimit-Reset"))
+		}
+	})
+}
+
+type failingStore struct{}
+
+var errFailingStore = errors.New("store unavailable")
+
+func (failingStore) Increment(string, int, time.Duration) (int, time.Time, error) {
+	return 0, time.Time{}, errFailingStore
+}
+
+func (failingStore) Get(string) (int, time.Time, error) { return 0, time.Time{}, errFailingStore }
+
+func (failingStore) Delete(string) error { return errFailingStore }
+
+func BenchmarkMiddleware(b *testing.B) {
+	c := New(time.Minute, 10000, 0)
+	defer c.Stop()
+
+	h := Middleware(c, MiddlewareConfig{Limit: 1000000})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	b.ReportAllocs()
+	for b.Loop() {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
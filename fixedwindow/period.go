@@ -0,0 +1,59 @@
+package fixedwindow
+
+import "time"
+
+// Period is the length of a calendar-aligned window; see NewAligned.
+type Period int
+
+// Calendar periods for aligned windows. Windows follow the wall clock of the
+// counter's time zone, so a day runs from midnight to midnight and lasts 23 or
+// 25 hours across daylight saving changes.
+const (
+	PeriodMinute Period = iota + 1 // Windows start at second zero of every minute
+	PeriodHour                     // Windows start at minute zero of every hour
+	PeriodDay                      // Windows start at midnight
+)
+
+// String returns the name of the period
+func (p Period) String() string {
+	switch p {
+	case PeriodMinute:
+		return "minute"
+	case PeriodHour:
+		return "hour"
+	case PeriodDay:
+		return "day"
+	default:
+		return "unknown"
+	}
+}
+
+// duration returns the nominal length of the period.
+func (p Period) duration() time.Duration {
+	switch p {
+	case PeriodHour:
+		return time.Hour
+	case PeriodDay:
+		return 24 * time.Hour
+	default:
+		return time.Minute
+	}
+}
+
+// next returns the start of the period following the one containing t,
+// in t's location.
+func (p Period) next(t time.Time) time.Time {
+	sinceMinute := time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+
+	switch p {
+	case PeriodHour:
+		// Offsets are subtracted rather than rebuilt with time.Date, which
+		// is ambiguous in the repeated hour of a daylight saving change
+		return t.Add(-time.Duration(t.Minute())*time.Minute - sinceMinute + time.Hour)
+	case PeriodDay:
+		year, month, day := t.Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+	default:
+		return t.Add(-sinceMinute + time.Minute)
+	}
+}
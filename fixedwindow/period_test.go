@@ -0,0 +1,148 @@
+package fixedwindow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vitalvas/gokit/ewma"
+)
+
+func TestPeriod_Next(t *testing.T) {
+	kathmandu := time.FixedZone("NPT", 5*3600+45*60)
+
+	tests := []struct {
+		name     string
+		period   Period
+		t        time.Time
+		expected time.Time
+	}{
+		{"minute", PeriodMinute, time.Date(2026, 3, 1, 10, 15, 30, 500, time.UTC), time.Date(2026, 3, 1, 10, 16, 0, 0, time.UTC)},
+		{"minute boundary", PeriodMinute, time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC), time.Date(2026, 3, 1, 10, 16, 0, 0, time.UTC)},
+		{"hour", PeriodHour, time.Date(2026, 3, 1, 10, 15, 30, 0, time.UTC), time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{"hour with quarter offset", PeriodHour, time.Date(2026, 3, 1, 10, 15, 30, 0, kathmandu), time.Date(2026, 3, 1, 11, 0, 0, 0, kathmandu)},
+		{"day", PeriodDay, time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"day end of year", PeriodDay, time.Date(2026, 12, 31, 12, 0, 0, 0, kathmandu), time.Date(2027, 1, 1, 0, 0, 0, 0, kathmandu)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(tt.period.next(tt.t)), "got %v", tt.period.next(tt.t))
+		})
+	}
+
+	t.Run("daylight saving", func(t *testing.T) {
+		loc, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skip("time zone database not available")
+		}
+
+		// Clocks go forward at 2:00 on 2026-03-08, so the day lasts 23 hours
+		start := time.Date(2026, 3, 8, 0, 0, 0, 0, loc)
+		end := PeriodDay.next(start)
+		assert.Equal(t, 23*time.Hour, end.Sub(start))
+
+		// Clocks go back at 2:00 on 2026-11-01: the repeated hour is its own window
+		first := time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC).In(loc) // 1:30 EDT
+		second := first.Add(time.Hour)                                 // 1:30 EST
+		assert.Equal(t, 30*time.Minute, PeriodHour.next(first).Sub(first))
+		assert.Equal(t, 30*time.Minute, PeriodHour.next(second).Sub(second))
+		day := time.Date(2026, 11, 1, 0, 0, 0, 0, loc)
+		assert.Equal(t, 25*time.Hour, PeriodDay.next(day).Sub(day))
+	})
+}
+
+func TestPeriod_String(t *testing.T) {
+	assert.Equal(t, "minute", PeriodMinute.String())
+	assert.Equal(t, "hour", PeriodHour.String())
+	assert.Equal(t, "day", PeriodDay.String())
+	assert.Equal(t, "unknown", Period(0).String())
+}
+
+func TestNewAligned(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		c := NewAligned(0, nil, 0, 0, nil)
+		defer c.Stop()
+
+		assert.Equal(t, PeriodMinute, c.period)
+		assert.Equal(t, time.Minute, c.window)
+		assert.Equal(t, time.UTC, c.location)
+		assert.Equal(t, ewma.SystemClock, c.clock)
+		assert.Equal(t, 1000, c.local.maxKeys)
+	})
+
+	t.Run("keys share calendar windows", func(t *testing.T) {
+		clock := ewma.NewManualClock(time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC))
+		c := NewAligned(PeriodHour, time.UTC, 100, 0, clock)
+		defer c.Stop()
+
+		end := time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)
+
+		assert.True(t, c.Allow("key1", 1))
+		clock.Advance(30 * time.Minute)
+		assert.True(t, c.Allow("key2", 1))
+
+		assert.Equal(t, end, c.WindowExpiry("key1"))
+		assert.Equal(t, end, c.WindowExpiry("key2"))
+		assert.False(t, c.Allow("key1", 1))
+
+		// Both keys reset on the hour
+		clock.Set(end)
+		assert.True(t, c.Allow("key1", 1))
+		assert.Equal(t, 1, c.Add("key2", 1))
+		assert.Equal(t, end.Add(time.Hour), c.WindowExpiry("key1"))
+	})
+
+	t.Run("day in time zone", func(t *testing.T) {
+		loc := time.FixedZone("UTC+3", 3*3600)
+		clock := ewma.NewManualClock(time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)) // 23:00 local
+		c := NewAligned(PeriodDay, loc, 100, 0, clock)
+		defer c.Stop()
+
+		c.Add("key1", 5)
+		assert.True(t, time.Date(2026, 3, 2, 0, 0, 0, 0, loc).Equal(c.WindowExpiry("key1")))
+
+		clock.Advance(time.Hour)
+		assert.Equal(t, 0, c.Count("key1"))
+	})
+
+	t.Run("store", func(t *testing.T) {
+		s := &recordingStore{}
+
+		c := NewWithStoreOptions(s, StoreOptions{
+			Period: PeriodHour,
+			Clock:  ewma.NewManualClock(time.Date(2026, 3, 1, 10, 45, 0, 0, time.UTC)),
+		})
+
+		c.Allow("key1", 10)
+		c.Add("key1", 2)
+		require.Len(t, s.ttls, 2)
+		assert.Equal(t, []time.Duration{15 * time.Minute, 15 * time.Minute}, s.ttls)
+	})
+}
+
+// recordingStore records the ttl of every increment
+type recordingStore struct {
+	ttls []time.Duration
+}
+
+func (s *recordingStore) Increment(_ string, delta int, ttl time.Duration) (int, time.Time, error) {
+	s.ttls = append(s.ttls, ttl)
+	return delta, time.Time{}, nil
+}
+
+func (s *recordingStore) Get(string) (int, time.Time, error) { return 0, time.Time{}, nil }
+
+func (s *recordingStore) Delete(string) error { return nil }
+
+func BenchmarkAligned_Allow(b *testing.B) {
+	c := NewAligned(PeriodDay, time.UTC, 10000, 0, nil)
+	defer c.Stop()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		c.Allow("bench-key", 1000000)
+	}
+}
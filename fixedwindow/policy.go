@@ -0,0 +1,437 @@
+package fixedwindow
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLockouts is the lockout escalation used when PolicyConfig.Lockouts is empty:
+// 1 minute for the first offense, 5 minutes for the second and 1 hour from then on.
+var DefaultLockouts = []time.Duration{time.Minute, 5 * time.Minute, time.Hour}
+
+// Reason explains a Policy decision.
+type Reason int
+
+// Decision reasons returned by Policy.Check.
+const (
+	ReasonAllowed   Reason = iota // Request is within the limit
+	ReasonLimited                 // Request exceeded the limit and started a lockout, if MaxKeys allows
+	ReasonLockedOut               // Key is serving a lockout from an earlier offense
+	ReasonBanned                  // Key is banned
+)
+
+// String returns the name of the reason
+func (r Reason) String() string {
+	switch r {
+	case ReasonAllowed:
+		return "allowed"
+	case ReasonLimited:
+		return "limited"
+	case ReasonLockedOut:
+		return "locked_out"
+	case ReasonBanned:
+		return "banned"
+	default:
+		return "unknown"
+	}
+}
+
+// PolicyConfig configures a Policy.
+type PolicyConfig struct {
+	// Limits maps a key class (for example "login" or "api") to the number of
+	// requests allowed per counter window.
+	Limits map[string]int
+
+	// DefaultLimit applies to classes missing from Limits. As with Counter.Allow,
+	// a limit of zero or less denies every request.
+	DefaultLimit int
+
+	// Lockouts lists lockout durations for the first, second, ... offense;
+	// the last entry repeats. Empty uses DefaultLockouts.
+	Lockouts []time.Duration
+
+	// OffenseTTL is how long an offense counts towards escalation after the
+	// lockout it caused ends. Zero or negative defaults to 24 hours.
+	OffenseTTL time.Duration
+
+	// MaxKeys bounds the number of tracked offenders. Only expired offenders are
+	// evicted: when all are still active, a new offender is denied by its
+	// counter for the rest of the window without a lockout. Zero or negative
+	// defaults to 1000.
+	MaxKeys int
+
+	// CleanupInterval enables background removal of expired offenders and bans.
+	CleanupInterval time.Duration
+
+	// OnLockout, if set, is called for every automatic lockout and manual ban,
+	// outside of any lock, for audit logging.
+	OnLockout func(LockoutEvent)
+}
+
+// LockoutEvent describes a lockout or ban passed to PolicyConfig.OnLockout.
+type LockoutEvent struct {
+	Class    string        // Key class; empty for manual bans
+	Key      string        // Offending key
+	Offense  int           // Offense number for automatic lockouts; zero for manual bans
+	Duration time.Duration // Lockout length; zero for permanent bans
+	Until    time.Time     // End of the lockout; zero for permanent bans
+	Manual   bool          // True for bans issued with Ban
+}
+
+// Decision is the result of Policy.Check.
+type Decision struct {
+	Allowed bool
+	Reason  Reason
+	Limit   int       // Limit of the key class
+	Until   time.Time // End of the lockout or ban; zero if allowed or permanently banned
+}
+
+// Policy adds brute-force protection on top of a Counter: per-class limits,
+// lockouts that escalate on repeat offenses, and a ban list.
+//
+// Counts are kept in the Counter under "class:key", so classes do not share
+// counts. Lockouts apply to a class and key; bans apply to a key in every class.
+// When a key exceeds its limit, its counter is cleared and the key is locked
+// out for the next duration in the escalation, so counting restarts when the
+// lockout ends.
+type Policy struct {
+	counter  *Counter
+	config   PolicyConfig
+	mu       sync.Mutex
+	entries  map[policyKey]*offender
+	bans     map[string]time.Time // Ban expiry per key; zero for permanent
+	now      func() time.Time
+	cleanup  *time.Ticker
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type policyKey struct {
+	class string
+	key   string
+}
+
+type offender struct {
+	offenses    int
+	lockedUntil time.Time
+}
+
+// NewPolicy creates a Policy counting requests with counter. Lockouts and bans
+// are timed with the counter's clock.
+// Call Stop() to release the background goroutine when done; Stop does not
+// stop the counter.
+func NewPolicy(counter *Counter, config PolicyConfig) *Policy {
+	if len(config.Lockouts) == 0 {
+		config.Lockouts = DefaultLockouts
+	}
+
+	if config.OffenseTTL <= 0 {
+		config.OffenseTTL = 24 * time.Hour
+	}
+
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = 1000
+	}
+
+	p := &Policy{
+		counter: counter,
+		config:  config,
+		entries: make(map[policyKey]*offender, config.MaxKeys),
+		bans:    make(map[string]time.Time),
+		now:     counter.clock.Now,
+		done:    make(chan struct{}),
+	}
+
+	if config.CleanupInterval > 0 {
+		p.cleanup = time.NewTicker(config.CleanupInterval)
+		go p.cleanupLoop()
+	}
+
+	return p
+}
+
+// Stop stops the background cleanup goroutine if one was started.
+// It is safe to call multiple times.
+func (p *Policy) Stop() {
+	p.stopOnce.Do(func() {
+		if p.cleanup != nil {
+			p.cleanup.Stop()
+		}
+		close(p.done)
+	})
+}
+
+func (p *Policy) cleanupLoop() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.cleanup.C:
+			p.removeExpired()
+		}
+	}
+}
+
+func (p *Policy) removeExpired() {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for pk, o := range p.entries {
+		if p.expired(o, now) {
+			delete(p.entries, pk)
+		}
+	}
+
+	for key, until := range p.bans {
+		if !until.IsZero() && !now.Before(until) {
+			delete(p.bans, key)
+		}
+	}
+}
+
+// Limit returns the limit of a key class.
+func (p *Policy) Limit(class string) int {
+	if limit, ok := p.config.Limits[class]; ok {
+		return limit
+	}
+
+	return p.config.DefaultLimit
+}
+
+// Allow reports whether a request for key in class is allowed; see Check.
+func (p *Policy) Allow(class, key string) bool {
+	return p.Check(class, key).Allowed
+}
+
+// Check counts a request for key in class and decides whether it is allowed.
+// Banned and locked out keys are denied without being counted. A request that
+// exceeds the class limit is denied and starts the next lockout; see
+// PolicyConfig.MaxKeys for what happens when the offender table is full.
+func (p *Policy) Check(class, key string) Decision {
+	limit := p.Limit(class)
+	pk := policyKey{class, key}
+	now := p.now()
+
+	if d, denied := p.denied(pk, limit, now); denied {
+		return d
+	}
+
+	// The counter may be remote, so it is not called under p.mu
+	id := class + ":" + key
+	if p.counter.Allow(id, limit) {
+		return Decision{Allowed: true, Reason: ReasonAllowed, Limit: limit}
+	}
+
+	p.mu.Lock()
+
+	o := p.entries[pk]
+	if o != nil && now.Before(o.lockedUntil) {
+		// A concurrent request already started this lockout
+		p.mu.Unlock()
+		return Decision{Reason: ReasonLockedOut, Limit: limit, Until: o.lockedUntil}
+	}
+
+	if o == nil {
+		if !p.evictIfNeeded(now) {
+			// Evicting an active offender would let a caller rotating through
+			// MaxKeys keys clear a real lockout, so the counter, left over its
+			// limit, denies this key until the window ends
+			p.mu.Unlock()
+			return Decision{Reason: ReasonLimited, Limit: limit, Until: p.counter.WindowExpiry(id)}
+		}
+		o = &offender{}
+		p.entries[pk] = o
+	} else if p.expired(o, now) {
+		o.offenses = 0
+	}
+
+	o.offenses++
+	duration := p.config.Lockouts[min(o.offenses, len(p.config.Lockouts))-1]
+	o.lockedUntil = now.Add(duration)
+
+	event := LockoutEvent{
+		Class:    class,
+		Key:      key,
+		Offense:  o.offenses,
+		Duration: duration,
+		Until:    o.lockedUntil,
+	}
+
+	p.mu.Unlock()
+
+	p.counter.Delete(id)
+
+	if p.config.OnLockout != nil {
+		p.config.OnLockout(event)
+	}
+
+	return Decision{Reason: ReasonLimited, Limit: limit, Until: event.Until}
+}
+
+// denied returns the decision for a banned or locked out key.
+func (p *Policy) denied(pk policyKey, limit int, now time.Time) (Decision, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if until, banned := p.banned(pk.key, now); banned {
+		return Decision{Reason: ReasonBanned, Limit: limit, Until: until}, true
+	}
+
+	if o := p.entries[pk]; o != nil && now.Before(o.lockedUntil) {
+		return Decision{Reason: ReasonLockedOut, Limit: limit, Until: o.lockedUntil}, true
+	}
+
+	return Decision{}, false
+}
+
+// LockedUntil returns when the lockout of key in class ends.
+// Returns zero time if the key is not locked out.
+func (p *Policy) LockedUntil(class, key string) time.Time {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	o, ok := p.entries[policyKey{class, key}]
+	if !ok || !now.Before(o.lockedUntil) {
+		return time.Time{}
+	}
+
+	return o.lockedUntil
+}
+
+// Offenses returns the number of offenses of key in class that still count
+// towards escalation.
+func (p *Policy) Offenses(class, key string) int {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	o, ok := p.entries[policyKey{class, key}]
+	if !ok || p.expired(o, now) {
+		return 0
+	}
+
+	return o.offenses
+}
+
+// Ban denies all requests for key in every class for duration.
+// Zero or negative duration bans the key permanently. Banning an already
+// banned key replaces the previous ban.
+func (p *Policy) Ban(key string, duration time.Duration) {
+	var until time.Time
+	if duration > 0 {
+		until = p.now().Add(duration)
+	} else {
+		duration = 0
+	}
+
+	p.mu.Lock()
+	p.bans[key] = until
+	p.mu.Unlock()
+
+	if p.config.OnLockout != nil {
+		p.config.OnLockout(LockoutEvent{
+			Key:      key,
+			Duration: duration,
+			Until:    until,
+			Manual:   true,
+		})
+	}
+}
+
+// Unban lifts the ban on key and clears its lockouts and offense history in
+// every class. Request counts are cleared in the classes listed in Limits
+// and those key has offended in; counts in other classes expire with their
+// window.
+func (p *Policy) Unban(key string) {
+	classes := make(map[string]struct{}, len(p.config.Limits))
+	for class := range p.config.Limits {
+		classes[class] = struct{}{}
+	}
+
+	p.mu.Lock()
+
+	delete(p.bans, key)
+
+	for pk := range p.entries {
+		if pk.key == key {
+			classes[pk.class] = struct{}{}
+			delete(p.entries, pk)
+		}
+	}
+
+	p.mu.Unlock()
+
+	// The counter may be remote, so it is not called under p.mu
+	for class := range classes {
+		p.counter.Delete(class + ":" + key)
+	}
+}
+
+// IsBanned reports whether key is currently banned.
+func (p *Policy) IsBanned(key string) bool {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, banned := p.banned(key, now)
+
+	return banned
+}
+
+// Bans returns the currently banned keys and their ban expiry
+// (zero for permanent bans).
+func (p *Policy) Bans() map[string]time.Time {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bans := make(map[string]time.Time, len(p.bans))
+	for key := range p.bans {
+		if until, ok := p.banned(key, now); ok {
+			bans[key] = until
+		}
+	}
+
+	return bans
+}
+
+// banned reports whether key is banned, removing an expired ban.
+// Caller must hold p.mu.
+func (p *Policy) banned(key string, now time.Time) (time.Time, bool) {
+	until, ok := p.bans[key]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if !until.IsZero() && !now.Before(until) {
+		delete(p.bans, key)
+		return time.Time{}, false
+	}
+
+	return until, true
+}
+
+// expired reports whether an offender's lockout has ended and its last
+// offense no longer counts towards escalation.
+func (p *Policy) expired(o *offender, now time.Time) bool {
+	return !now.Before(o.lockedUntil.Add(p.config.OffenseTTL))
+}
+
+// evictIfNeeded removes expired offenders when at capacity and reports
+// whether there is room for a new one. Active offenders are never evicted.
+// Caller must hold p.mu.
+func (p *Policy) evictIfNeeded(now time.Time) bool {
+	if len(p.entries) < p.config.MaxKeys {
+		return true
+	}
+
+	for pk, o := range p.entries {
+		if p.expired(o, now) {
+			delete(p.entries, pk)
+		}
+	}
+
+	return len(p.entries) < p.config.MaxKeys
+}
@@ -0,0 +1,331 @@
+package fixedwindow
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPolicy returns a policy whose clock is controlled by the returned pointer
+func newTestPolicy(t *testing.T, config PolicyConfig) (*Policy, *time.Time) {
+	t.Helper()
+
+	c := New(time.Hour, 1000, 0)
+	t.Cleanup(c.Stop)
+
+	p := NewPolicy(c, config)
+	t.Cleanup(p.Stop)
+
+	now := time.Unix(1700000000, 0)
+	p.now = func() time.Time { return now }
+
+	return p, &now
+}
+
+func TestNewPolicy(t *testing.T) {
+	p, _ := newTestPolicy(t, PolicyConfig{})
+
+	assert.Equal(t, DefaultLockouts, p.config.Lockouts)
+	assert.Equal(t, 24*time.Hour, p.config.OffenseTTL)
+	assert.Equal(t, 1000, p.config.MaxKeys)
+	assert.Nil(t, p.cleanup)
+
+	c := New(time.Minute, 100, 0)
+	defer c.Stop()
+
+	p = NewPolicy(c, PolicyConfig{CleanupInterval: 10 * time.Millisecond})
+	assert.NotNil(t, p.cleanup)
+	p.Stop()
+	p.Stop() // Should not panic
+}
+
+func TestPolicy_Limits(t *testing.T) {
+	p, _ := newTestPolicy(t, PolicyConfig{
+		Limits:       map[string]int{"login": 2, "api": 5},
+		DefaultLimit: 3,
+	})
+
+	assert.Equal(t, 2, p.Limit("login"))
+	assert.Equal(t, 5, p.Limit("api"))
+	assert.Equal(t, 3, p.Limit("other"))
+
+	for class, limit := range map[string]int{"login": 2, "api": 5, "other": 3} {
+		for i := range limit {
+			assert.True(t, p.Allow(class, "user-1"), "%s request %d", class, i)
+		}
+		d := p.Check(class, "user-1")
+		assert.False(t, d.Allowed)
+		assert.Equal(t, ReasonLimited, d.Reason)
+		assert.Equal(t, limit, d.Limit)
+	}
+
+	// Classes and keys are independent
+	assert.True(t, p.Allow("login", "user-2"))
+}
+
+func TestPolicy_EscalatingLockouts(t *testing.T) {
+	var events []LockoutEvent
+	p, now := newTestPolicy(t, PolicyConfig{
+		Limits:    map[string]int{"login": 3},
+		OnLockout: func(e LockoutEvent) { events = append(events, e) },
+	})
+
+	offend := func() Decision {
+		t.Helper()
+		for range 3 {
+			require.True(t, p.Allow("login", "alice"))
+		}
+		return p.Check("login", "alice")
+	}
+
+	for i, expected := range []time.Duration{time.Minute, 5 * time.Minute, time.Hour, time.Hour} {
+		d := offend()
+		assert.Equal(t, ReasonLimited, d.Reason)
+		assert.Equal(t, now.Add(expected), d.Until)
+		assert.Equal(t, i+1, p.Offenses("login", "alice"))
+
+		// Denied without counting for the whole lockout
+		*now = now.Add(expected - time.Second)
+		d = p.Check("login", "alice")
+		assert.Equal(t, ReasonLockedOut, d.Reason)
+		assert.Equal(t, now.Add(time.Second), d.Until)
+		assert.Equal(t, d.Until, p.LockedUntil("login", "alice"))
+		assert.Equal(t, 0, p.counter.Count("login:alice"))
+
+		*now = now.Add(time.Second)
+		assert.True(t, p.LockedUntil("login", "alice").IsZero())
+	}
+
+	require.Len(t, events, 4)
+	assert.Equal(t, LockoutEvent{
+		Class:    "login",
+		Key:      "alice",
+		Offense:  2,
+		Duration: 5 * time.Minute,
+		Until:    time.Unix(1700000000, 0).Add(time.Minute + 5*time.Minute),
+	}, events[1])
+
+	// Offenses are forgotten OffenseTTL after the last lockout ended
+	*now = now.Add(24 * time.Hour)
+	assert.Equal(t, 0, p.Offenses("login", "alice"))
+	assert.Equal(t, time.Minute, offend().Until.Sub(*now))
+	assert.Equal(t, 1, events[len(events)-1].Offense)
+}
+
+func TestPolicy_CustomLockouts(t *testing.T) {
+	p, now := newTestPolicy(t, PolicyConfig{
+		DefaultLimit: 1,
+		Lockouts:     []time.Duration{10 * time.Second},
+		OffenseTTL:   time.Minute,
+	})
+
+	for range 3 {
+		assert.True(t, p.Allow("api", "key"))
+		d := p.Check("api", "key")
+		assert.Equal(t, ReasonLimited, d.Reason)
+		assert.Equal(t, now.Add(10*time.Second), d.Until)
+		*now = now.Add(10 * time.Second)
+	}
+
+	assert.Equal(t, 3, p.Offenses("api", "key"))
+}
+
+func TestPolicy_Bans(t *testing.T) {
+	var events []LockoutEvent
+	p, now := newTestPolicy(t, PolicyConfig{
+		DefaultLimit: 1,
+		OnLockout:    func(e LockoutEvent) { events = append(events, e) },
+	})
+
+	t.Run("temporary", func(t *testing.T) {
+		p.Ban("mallory", time.Hour)
+		assert.True(t, p.IsBanned("mallory"))
+
+		for _, class := range []string{"login", "api"} {
+			d := p.Check(class, "mallory")
+			assert.False(t, d.Allowed)
+			assert.Equal(t, ReasonBanned, d.Reason)
+			assert.Equal(t, now.Add(time.Hour), d.Until)
+		}
+		assert.Equal(t, map[string]time.Time{"mallory": now.Add(time.Hour)}, p.Bans())
+
+		*now = now.Add(time.Hour)
+		assert.False(t, p.IsBanned("mallory"))
+		assert.True(t, p.Allow("login", "mallory"))
+		assert.Empty(t, p.Bans())
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		p.Ban("eve", 0)
+
+		*now = now.Add(365 * 24 * time.Hour)
+		d := p.Check("api", "eve")
+		assert.Equal(t, ReasonBanned, d.Reason)
+		assert.True(t, d.Until.IsZero())
+	})
+
+	t.Run("unban clears lockouts", func(t *testing.T) {
+		assert.True(t, p.Allow("login", "bob"))
+		assert.Equal(t, ReasonLimited, p.Check("login", "bob").Reason)
+		p.Ban("bob", time.Minute)
+
+		p.Unban("bob")
+		p.Unban("eve")
+
+		assert.False(t, p.IsBanned("bob"))
+		assert.False(t, p.IsBanned("eve"))
+		assert.Equal(t, 0, p.Offenses("login", "bob"))
+		assert.True(t, p.Allow("login", "bob"))
+	})
+
+	t.Run("unban clears counts", func(t *testing.T) {
+		p, _ := newTestPolicy(t, PolicyConfig{Limits: map[string]int{"login": 2}})
+
+		assert.True(t, p.Allow("login", "carol"))
+		assert.True(t, p.Allow("login", "carol"))
+		assert.Equal(t, 2, p.counter.Count("login:carol"))
+
+		p.Unban("carol")
+		assert.Equal(t, 0, p.counter.Count("login:carol"))
+		assert.True(t, p.Allow("login", "carol"))
+		assert.True(t, p.Allow("login", "carol"))
+	})
+
+	manual := 0
+	for _, e := range events {
+		if e.Manual {
+			manual++
+			assert.Empty(t, e.Class)
+			assert.Zero(t, e.Offense)
+		}
+	}
+	assert.Equal(t, 3, manual)
+	assert.Equal(t, LockoutEvent{Key: "eve", Manual: true}, events[1])
+}
+
+func TestPolicy_Cleanup(t *testing.T) {
+	t.Run("eviction keeps active lockouts", func(t *testing.T) {
+		p, now := newTestPolicy(t, PolicyConfig{DefaultLimit: 0, MaxKeys: 2, OffenseTTL: time.Hour})
+
+		for i := range 3 {
+			p.Check("api", fmt.Sprintf("key%d", i))
+			*now = now.Add(time.Second)
+		}
+
+		// Rotating keys cannot push out a live lockout
+		assert.Len(t, p.entries, 2)
+		assert.Equal(t, 1, p.Offenses("api", "key0"))
+		assert.Equal(t, 0, p.Offenses("api", "key2"))
+		assert.False(t, p.LockedUntil("api", "key0").IsZero())
+
+		d := p.Check("api", "key2")
+		assert.False(t, d.Allowed)
+		assert.Equal(t, ReasonLimited, d.Reason)
+
+		// Expired offenders make room
+		*now = now.Add(time.Minute + time.Hour)
+		assert.Equal(t, ReasonLimited, p.Check("api", "key2").Reason)
+		assert.Len(t, p.entries, 1)
+		assert.Equal(t, 1, p.Offenses("api", "key2"))
+	})
+
+	t.Run("full table denies until the window ends", func(t *testing.T) {
+		p, _ := newTestPolicy(t, PolicyConfig{DefaultLimit: 1, MaxKeys: 1})
+
+		p.Check("api", "key0")
+		assert.Equal(t, ReasonLimited, p.Check("api", "key0").Reason)
+
+		assert.True(t, p.Allow("api", "key1"))
+		d := p.Check("api", "key1")
+		assert.Equal(t, ReasonLimited, d.Reason)
+		assert.Equal(t, p.counter.WindowExpiry("api:key1"), d.Until)
+		assert.False(t, p.Allow("api", "key1"))
+		assert.Equal(t, 0, p.Offenses("api", "key1"))
+	})
+
+	t.Run("background", func(t *testing.T) {
+		c := New(time.Minute, 100, 0)
+		defer c.Stop()
+
+		p := NewPolicy(c, PolicyConfig{
+			Lockouts:        []time.Duration{time.Millisecond},
+			OffenseTTL:      time.Millisecond,
+			CleanupInterval: 10 * time.Millisecond,
+		})
+		defer p.Stop()
+
+		p.Check("api", "key")
+		p.Ban("temp", time.Millisecond)
+		p.Ban("perm", 0)
+
+		time.Sleep(60 * time.Millisecond)
+
+		p.mu.Lock()
+		assert.Empty(t, p.entries)
+		assert.Len(t, p.bans, 1)
+		p.mu.Unlock()
+	})
+}
+
+func TestPolicy_Concurrency(t *testing.T) {
+	var mu sync.Mutex
+	lockouts := 0
+
+	p, _ := newTestPolicy(t, PolicyConfig{
+		DefaultLimit: 50,
+		OnLockout: func(LockoutEvent) {
+			mu.Lock()
+			lockouts++
+			mu.Unlock()
+		},
+	})
+
+	var wg sync.WaitGroup
+	allowed := make([]int, 10)
+	for i := range 10 {
+		wg.Go(func() {
+			for range 20 {
+				if p.Allow("api", "shared") {
+					allowed[i]++
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range allowed {
+		total += n
+	}
+
+	// Requests racing with the lockout may be counted in the fresh window
+	assert.GreaterOrEqual(t, total, 50)
+	assert.Less(t, total, 200)
+	assert.GreaterOrEqual(t, lockouts, 1)
+	assert.False(t, p.Allow("api", "shared"))
+}
+
+func TestReason_String(t *testing.T) {
+	assert.Equal(t, "allowed", ReasonAllowed.String())
+	assert.Equal(t, "limited", ReasonLimited.String())
+	assert.Equal(t, "locked_out", ReasonLockedOut.String())
+	assert.Equal(t, "banned", ReasonBanned.String())
+	assert.Equal(t, "unknown", Reason(9).String())
+}
+
+func BenchmarkPolicy_Check(b *testing.B) {
+	c := New(time.Minute, 10000, 0)
+	defer c.Stop()
+
+	p := NewPolicy(c, PolicyConfig{DefaultLimit: 1000000})
+	defer p.Stop()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		p.Check("api", "bench-key")
+	}
+}
@@ -0,0 +1,399 @@
+package fixedwindow
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrStoreClosed indicates an operation on a closed store
+	ErrStoreClosed = errors.New("fixedwindow: store closed")
+
+	// ErrProtocol indicates a malformed or unexpected reply from a Redis server
+	ErrProtocol = errors.New("fixedwindow: redis protocol error")
+)
+
+// RedisError is an error reply returned by a Redis server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "fixedwindow: redis: " + string(e)
+}
+
+// RedisOptions configures a RedisStore.
+type RedisOptions struct {
+	Prefix   string        // Prepended to every key
+	Password string        // AUTH password; empty disables authentication
+	DB       int           // Database selected on connect; 0 is the server default
+	Timeout  time.Duration // Dial and per-operation timeout (default 1 second)
+	PoolSize int           // Maximum idle connections kept open (default 4)
+}
+
+// RedisStore is a Store backed by a server speaking the Redis protocol (RESP),
+// such as Redis, Valkey or KeyDB. Counters are plain integer keys with a
+// millisecond expiry, so every process using the same server and prefix
+// shares the same limits.
+//
+// Each increment is a single MULTI/EXEC transaction of SET NX PX, INCRBY and
+// PTTL, which requires Redis 2.6.12 or later. Connections are dialed on
+// demand and pooled.
+type RedisStore struct {
+	addr   string
+	opts   RedisOptions
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+	dialer net.Dialer
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// NewRedisStore creates a RedisStore for the server at addr (host:port).
+// No connection is made until the first operation.
+func NewRedisStore(addr string, opts RedisOptions) *RedisStore {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 4
+	}
+
+	return &RedisStore{
+		addr:   addr,
+		opts:   opts,
+		dialer: net.Dialer{Timeout: opts.Timeout},
+	}
+}
+
+// Close closes all idle connections. Operations after Close return ErrStoreClosed.
+func (s *RedisStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+
+	var err error
+	for _, c := range s.idle {
+		if cerr := c.conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	s.idle = nil
+
+	return err
+}
+
+// Increment implements Store.
+func (s *RedisStore) Increment(key string, delta int, ttl time.Duration) (int, time.Time, error) {
+	key = s.opts.Prefix + key
+	ms := strconv.FormatInt(max(ttl.Milliseconds(), 1), 10)
+
+	now := time.Now()
+	reply, err := s.transaction(
+		[]string{"SET", key, "0", "PX", ms, "NX"},
+		[]string{"INCRBY", key, strconv.Itoa(delta)},
+		[]string{"PTTL", key},
+	)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	count, ok1 := reply[1].(int64)
+	pttl, ok2 := reply[2].(int64)
+	if !ok1 || !ok2 {
+		return 0, time.Time{}, ErrProtocol
+	}
+
+	return int(count), expiryFromPTTL(now, pttl), nil
+}
+
+// Get implements Store.
+func (s *RedisStore) Get(key string) (int, time.Time, error) {
+	key = s.opts.Prefix + key
+
+	now := time.Now()
+	reply, err := s.transaction(
+		[]string{"GET", key},
+		[]string{"PTTL", key},
+	)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	if reply[0] == nil {
+		return 0, time.Time{}, nil
+	}
+
+	value, ok1 := reply[0].([]byte)
+	pttl, ok2 := reply[1].(int64)
+	if !ok1 || !ok2 {
+		return 0, time.Time{}, ErrProtocol
+	}
+
+	count, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0, time.Time{}, ErrProtocol
+	}
+
+	return count, expiryFromPTTL(now, pttl), nil
+}
+
+// Delete implements Store.
+func (s *RedisStore) Delete(key string) error {
+	_, err := s.do([]string{"DEL", s.opts.Prefix + key})
+	return err
+}
+
+// expiryFromPTTL converts a PTTL reply into an absolute time; keys without an
+// expiry report zero time.
+func expiryFromPTTL(now time.Time, pttl int64) time.Time {
+	if pttl < 0 {
+		return time.Time{}
+	}
+
+	return now.Add(time.Duration(pttl) * time.Millisecond)
+}
+
+// transaction runs cmds inside MULTI/EXEC and returns the EXEC reply.
+func (s *RedisStore) transaction(cmds ...[]string) ([]any, error) {
+	all := make([][]string, 0, len(cmds)+2)
+	all = append(all, []string{"MULTI"})
+	all = append(all, cmds...)
+	all = append(all, []string{"EXEC"})
+
+	replies, err := s.do(all...)
+	if err != nil {
+		return nil, err
+	}
+
+	exec, ok := replies[len(replies)-1].([]any)
+	if !ok || len(exec) != len(cmds) {
+		return nil, ErrProtocol
+	}
+
+	for _, r := range exec {
+		if rerr, ok := r.(RedisError); ok {
+			return nil, rerr
+		}
+	}
+
+	return exec, nil
+}
+
+// do pipelines cmds on one connection and returns their replies. A server
+// error reply to any command is returned as a RedisError.
+func (s *RedisStore) do(cmds ...[]string) ([]any, error) {
+	c, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := c.pipeline(cmds, s.opts.Timeout)
+	if err != nil {
+		_ = c.conn.Close()
+		return nil, err
+	}
+
+	s.put(c)
+
+	for _, r := range replies {
+		if rerr, ok := r.(RedisError); ok {
+			return nil, rerr
+		}
+	}
+
+	return replies, nil
+}
+
+// get returns an idle connection or dials a new one.
+func (s *RedisStore) get() (*redisConn, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrStoreClosed
+	}
+
+	if n := len(s.idle); n > 0 {
+		c := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return c, nil
+	}
+	s.mu.Unlock()
+
+	conn, err := s.dialer.Dial("tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &redisConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+
+	var setup [][]string
+	if s.opts.Password != "" {
+		setup = append(setup, []string{"AUTH", s.opts.Password})
+	}
+	if s.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.opts.DB)})
+	}
+
+	if len(setup) > 0 {
+		replies, err := c.pipeline(setup, s.opts.Timeout)
+		if err == nil {
+			for _, r := range replies {
+				if rerr, ok := r.(RedisError); ok {
+					err = rerr
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// put returns a healthy connection to the pool or closes it if the pool is full.
+func (s *RedisStore) put(c *redisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || len(s.idle) >= s.opts.PoolSize {
+		_ = c.conn.Close()
+		return
+	}
+
+	s.idle = append(s.idle, c)
+}
+
+// pipeline writes all commands and reads one reply per command.
+func (c *redisConn) pipeline(cmds [][]string, timeout time.Duration) ([]any, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range cmds {
+		writeCommand(c.w, cmd)
+	}
+
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]any, len(cmds))
+	for i := range cmds {
+		reply, err := readReply(c.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+
+	return replies, nil
+}
+
+// writeCommand encodes cmd as a RESP array of bulk strings.
+func writeCommand(w *bufio.Writer, cmd []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(cmd))
+	for _, arg := range cmd {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// readReply decodes one RESP2 reply: simple strings as string, errors as
+// RedisError, integers as int64, bulk strings as []byte and arrays as []any.
+// Null bulk strings and arrays are returned as nil.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, ErrProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+
+	case '-':
+		return RedisError(line[1:]), nil
+
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		return n, nil
+
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, ErrProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[n] != '\r' || buf[n+1] != '\n' {
+			return nil, ErrProtocol
+		}
+		return buf[:n], nil
+
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, ErrProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, ErrProtocol
+}
+
+// readLine reads a CRLF-terminated line without the terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", ErrProtocol
+	}
+
+	return line[:len(line)-2], nil
+}
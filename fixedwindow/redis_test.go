@@ -0,0 +1,492 @@
+package fixedwindow
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is a minimal in-process server speaking the subset of RESP
+// used by RedisStore.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu    sync.Mutex
+	data  map[string]fakeValue
+	conns []net.Conn
+	dials int
+}
+
+type fakeValue struct {
+	value   string
+	expires time.Time
+}
+
+func newFakeRedis(t testing.TB, password string) *fakeRedis {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeRedis{
+		ln:       ln,
+		password: password,
+		data:     make(map[string]fakeValue),
+	}
+
+	go f.serve()
+	t.Cleanup(f.close)
+
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.ln.Addr().String()
+}
+
+func (f *fakeRedis) close() {
+	_ = f.ln.Close()
+	f.dropConns()
+}
+
+// dropConns closes all client connections from the server side
+func (f *fakeRedis) dropConns() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range f.conns {
+		_ = c.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeRedis) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []string
+	for k := range f.data {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.conns = append(f.conns, conn)
+		f.dials++
+		f.mu.Unlock()
+
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	authed := f.password == ""
+	var queued [][]string
+	inMulti := false
+
+	for {
+		req, err := readReply(r)
+		if err != nil {
+			return
+		}
+
+		items, ok := req.([]any)
+		if !ok || len(items) == 0 {
+			return
+		}
+
+		cmd := make([]string, len(items))
+		for i, item := range items {
+			cmd[i] = string(item.([]byte))
+		}
+		name := strings.ToUpper(cmd[0])
+
+		switch {
+		case name == "AUTH":
+			if cmd[1] != f.password {
+				writeFakeReply(w, RedisError("WRONGPASS invalid password"))
+			} else {
+				authed = true
+				writeFakeReply(w, "OK")
+			}
+		case !authed:
+			writeFakeReply(w, RedisError("NOAUTH Authentication required."))
+		case name == "MULTI":
+			inMulti = true
+			queued = nil
+			writeFakeReply(w, "OK")
+		case name == "EXEC":
+			replies := make([]any, len(queued))
+			f.mu.Lock()
+			for i, q := range queued {
+				replies[i] = f.exec(q)
+			}
+			f.mu.Unlock()
+			inMulti = false
+			writeFakeReply(w, replies)
+		case inMulti:
+			queued = append(queued, cmd)
+			writeFakeReply(w, "QUEUED")
+		default:
+			f.mu.Lock()
+			reply := f.exec(cmd)
+			f.mu.Unlock()
+			writeFakeReply(w, reply)
+		}
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs a single command. Caller must hold f.mu.
+func (f *fakeRedis) exec(cmd []string) any {
+	now := time.Now()
+	get := func(key string) (fakeValue, bool) {
+		v, ok := f.data[key]
+		if ok && !v.expires.IsZero() && !now.Before(v.expires) {
+			delete(f.data, key)
+			return fakeValue{}, false
+		}
+		return v, ok
+	}
+
+	switch strings.ToUpper(cmd[0]) {
+	case "PING", "SELECT":
+		return "OK"
+	case "SET": // SET key value PX ms NX
+		if _, ok := get(cmd[1]); ok {
+			return nil
+		}
+		ms, _ := strconv.Atoi(cmd[4])
+		f.data[cmd[1]] = fakeValue{value: cmd[2], expires: now.Add(time.Duration(ms) * time.Millisecond)}
+		return "OK"
+	case "INCRBY":
+		v, _ := get(cmd[1])
+		n, err := strconv.Atoi(v.value)
+		if v.value != "" && err != nil {
+			return RedisError("ERR value is not an integer or out of range")
+		}
+		delta, _ := strconv.Atoi(cmd[2])
+		v.value = strconv.Itoa(n + delta)
+		f.data[cmd[1]] = v
+		return int64(n + delta)
+	case "GET":
+		if v, ok := get(cmd[1]); ok {
+			return []byte(v.value)
+		}
+		return nil
+	case "PTTL":
+		v, ok := get(cmd[1])
+		if !ok {
+			return int64(-2)
+		}
+		if v.expires.IsZero() {
+			return int64(-1)
+		}
+		return v.expires.Sub(now).Milliseconds()
+	case "DEL":
+		if _, ok := get(cmd[1]); ok {
+			delete(f.data, cmd[1])
+			return int64(1)
+		}
+		return int64(0)
+	}
+
+	return RedisError("ERR unknown command '" + cmd[0] + "'")
+}
+
+func writeFakeReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		w.WriteString("+" + v + "\r\n")
+	case RedisError:
+		w.WriteString("-" + string(v) + "\r\n")
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case []byte:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeFakeReply(w, item)
+		}
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Run("increment get delete", func(t *testing.T) {
+		f := newFakeRedis(t, "")
+		s := NewRedisStore(f.addr(), RedisOptions{Prefix: "rl:"})
+		defer s.Close()
+
+		count, end, err := s.Get("key1")
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+		assert.True(t, end.IsZero())
+
+		before := time.Now()
+		count, end, err = s.Increment("key1", 1, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.WithinDuration(t, before.Add(time.Minute), end, time.Second)
+
+		// The window of a live counter is kept
+		count, end2, err := s.Increment("key1", 5, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, 6, count)
+		assert.WithinDuration(t, end, end2, time.Second)
+
+		count, _, err = s.Get("key1")
+		require.NoError(t, err)
+		assert.Equal(t, 6, count)
+		assert.Equal(t, []string{"rl:key1"}, f.keys())
+
+		require.NoError(t, s.Delete("key1"))
+		count, _, err = s.Get("key1")
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("window expires", func(t *testing.T) {
+		f := newFakeRedis(t, "")
+		s := NewRedisStore(f.addr(), RedisOptions{})
+		defer s.Close()
+
+		_, _, err := s.Increment("key1", 3, 30*time.Millisecond)
+		require.NoError(t, err)
+
+		time.Sleep(50 * time.Millisecond)
+
+		count, _, err := s.Increment("key1", 1, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("authentication", func(t *testing.T) {
+		f := newFakeRedis(t, "secret")
+
+		s := NewRedisStore(f.addr(), RedisOptions{Password: "secret", DB: 2})
+		defer s.Close()
+
+		_, _, err := s.Increment("key1", 1, time.Minute)
+		assert.NoError(t, err)
+
+		bad := NewRedisStore(f.addr(), RedisOptions{Password: "wrong"})
+		defer bad.Close()
+
+		_, _, err = bad.Get("key1")
+		var rerr RedisError
+		require.ErrorAs(t, err, &rerr)
+		assert.Contains(t, rerr.Error(), "WRONGPASS")
+
+		none := NewRedisStore(f.addr(), RedisOptions{})
+		defer none.Close()
+
+		assert.Error(t, none.Delete("key1"))
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		f := newFakeRedis(t, "")
+		s := NewRedisStore(f.addr(), RedisOptions{})
+		defer s.Close()
+
+		f.mu.Lock()
+		f.data["key1"] = fakeValue{value: "not a number"}
+		f.mu.Unlock()
+
+		_, _, err := s.Increment("key1", 1, time.Minute)
+		var rerr RedisError
+		assert.ErrorAs(t, err, &rerr)
+
+		_, _, err = s.Get("key1")
+		assert.ErrorIs(t, err, ErrProtocol)
+	})
+
+	t.Run("connection pooling and reconnect", func(t *testing.T) {
+		f := newFakeRedis(t, "")
+		s := NewRedisStore(f.addr(), RedisOptions{PoolSize: 1})
+		defer s.Close()
+
+		for range 5 {
+			_, _, err := s.Increment("key1", 1, time.Minute)
+			require.NoError(t, err)
+		}
+
+		f.mu.Lock()
+		assert.Equal(t, 1, f.dials)
+		f.mu.Unlock()
+
+		f.dropConns()
+
+		// The stale pooled connection fails once, then a new one is dialed
+		_, _, err := s.Get("key1")
+		assert.Error(t, err)
+
+		count, _, err := s.Get("key1")
+		require.NoError(t, err)
+		assert.Equal(t, 5, count)
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		s := NewRedisStore(addr, RedisOptions{Timeout: 100 * time.Millisecond})
+		defer s.Close()
+
+		_, _, err = s.Increment("key1", 1, time.Minute)
+		assert.Error(t, err)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		f := newFakeRedis(t, "")
+		s := NewRedisStore(f.addr(), RedisOptions{})
+
+		_, _, err := s.Get("key1")
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		_, _, err = s.Get("key1")
+		assert.ErrorIs(t, err, ErrStoreClosed)
+	})
+}
+
+func TestCounterWithRedisStore(t *testing.T) {
+	t.Run("replicas share limits", func(t *testing.T) {
+		f := newFakeRedis(t, "")
+
+		var replicas []*Counter
+		for range 3 {
+			s := NewRedisStore(f.addr(), RedisOptions{Prefix: "api:"})
+			defer s.Close()
+
+			c := NewWithStore(time.Minute, s, nil)
+			defer c.Stop()
+
+			replicas = append(replicas, c)
+		}
+
+		allowed := 0
+		for i := range 30 {
+			if replicas[i%3].Allow("user-1", 10) {
+				allowed++
+			}
+		}
+
+		assert.Equal(t, 10, allowed)
+		for _, c := range replicas {
+			assert.True(t, c.IsLockedOut("user-1"))
+			assert.Equal(t, 30, c.Count("user-1"))
+			assert.WithinDuration(t, time.Now().Add(time.Minute), c.WindowExpiry("user-1"), time.Second)
+		}
+
+		assert.False(t, replicas[0].IsLockedOut("user-2"))
+		assert.Equal(t, 0, replicas[0].Len())
+		assert.ElementsMatch(t, []string{"api:c:user-1", "api:l:user-1"}, f.keys())
+	})
+
+	t.Run("fails open on store errors", func(t *testing.T) {
+		f := newFakeRedis(t, "")
+		s := NewRedisStore(f.addr(), RedisOptions{})
+		f.close()
+
+		var errs []error
+		c := NewWithStore(time.Minute, s, func(err error) { errs = append(errs, err) })
+		defer c.Stop()
+
+		assert.True(t, c.Allow("key1", 1))
+		assert.Equal(t, 0, c.Add("key1", 1))
+		assert.Equal(t, 0, c.Count("key1"))
+		assert.False(t, c.IsLockedOut("key1"))
+		assert.True(t, c.WindowExpiry("key1").IsZero())
+		assert.Len(t, errs, 5)
+
+		// A nil error callback is allowed
+		c = NewWithStore(time.Minute, s, nil)
+		assert.True(t, c.Allow("key1", 1))
+	})
+}
+
+func TestReadReply(t *testing.T) {
+	valid := map[string]any{
+		"+OK\r\n":                    "OK",
+		"-ERR bad\r\n":               RedisError("ERR bad"),
+		":42\r\n":                    int64(42),
+		"$3\r\nfoo\r\n":              []byte("foo"),
+		"$0\r\n\r\n":                 []byte{},
+		"$-1\r\n":                    nil,
+		"*-1\r\n":                    nil,
+		"*2\r\n:1\r\n$1\r\na\r\n":    []any{int64(1), []byte("a")},
+		"*1\r\n*1\r\n+QUEUED\r\n":    []any{[]any{"QUEUED"}},
+		"*3\r\n$-1\r\n:-2\r\n+x\r\n": []any{nil, int64(-2), "x"},
+	}
+
+	for input, expected := range valid {
+		reply, err := readReply(bufio.NewReader(strings.NewReader(input)))
+		require.NoError(t, err, "%q", input)
+		assert.Equal(t, expected, reply, "%q", input)
+	}
+
+	invalid := []string{
+		"",
+		"\r\n",
+		"OK\r\n",
+		"+OK\n",
+		":abc\r\n",
+		"$x\r\n",
+		"$-2\r\n",
+		"$3\r\nfoo",
+		"$3\r\nfooXX",
+		"*x\r\n",
+		"*2\r\n:1\r\n",
+	}
+
+	for _, input := range invalid {
+		_, err := readReply(bufio.NewReader(strings.NewReader(input)))
+		assert.Error(t, err, "%q", input)
+	}
+}
+
+func BenchmarkCounterWithRedisStore_Allow(b *testing.B) {
+	f := newFakeRedis(b, "")
+
+	s := NewRedisStore(f.addr(), RedisOptions{})
+	defer s.Close()
+
+	c := NewWithStore(time.Minute, s, nil)
+	defer c.Stop()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		c.Allow("bench-key", 1000000)
+	}
+}
@@ -0,0 +1,452 @@
+package fixedwindow
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingCounter implements the Sliding Window Counter algorithm for rate limiting.
+// It keeps the counts of the current and previous window per key and estimates
+// the number of events in the trailing window by weighting the previous count
+// with the fraction of it that still overlaps:
+//
+//	estimate = previous * (1 - elapsed/window) + current
+//
+// Unlike Counter, a key cannot burst to 2x the limit across a window boundary,
+// and denied requests are not counted, so a key recovers gradually instead of
+// being locked out for the rest of the window.
+//
+// Properties:
+//   - O(1) per operation (map lookup + two counters)
+//   - O(n) memory where n = number of tracked keys
+//   - Per-key independent windows (start on first access, not wall-clock aligned)
+//   - Approximate: assumes events in the previous window were evenly spread
+//   - Thread-safe
+//   - Max tracked keys with oldest-window eviction
+type SlidingCounter struct {
+	mu       sync.Mutex
+	entries  map[string]*slidingEntry
+	window   time.Duration
+	maxKeys  int
+	cleanup  *time.Ticker
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type slidingEntry struct {
+	previous    int
+	current     int
+	windowStart time.Time
+}
+
+// NewSlidingCounter creates a new SlidingCounter with the specified window duration
+// and maximum number of tracked keys. Parameters and cleanup behave as in New.
+// Call Stop() to release the background goroutine when done.
+func NewSlidingCounter(window time.Duration, maxKeys int, cleanupInterval time.Duration) *SlidingCounter {
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	c := &SlidingCounter{
+		entries: make(map[string]*slidingEntry, maxKeys),
+		window:  window,
+		maxKeys: maxKeys,
+		done:    make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		c.cleanup = time.NewTicker(cleanupInterval)
+		go c.cleanupLoop()
+	}
+
+	return c
+}
+
+// Stop stops the background cleanup goroutine if one was started.
+// It is safe to call multiple times.
+func (c *SlidingCounter) Stop() {
+	c.stopOnce.Do(func() {
+		if c.cleanup != nil {
+			c.cleanup.Stop()
+		}
+		close(c.done)
+	})
+}
+
+func (c *SlidingCounter) cleanupLoop() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.cleanup.C:
+			c.removeExpired(time.Now())
+		}
+	}
+}
+
+func (c *SlidingCounter) removeExpired(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, e := range c.entries {
+		if c.expired(e, now) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Allow checks whether the key is within its rate limit and, if so, consumes
+// one unit. Returns false without counting the request when the estimated
+// count in the trailing window has already reached the limit.
+func (c *SlidingCounter) Allow(key string, limit int) bool {
+	return c.allow(key, limit, time.Now())
+}
+
+func (c *SlidingCounter) allow(key string, limit int, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.getOrCreate(key, now)
+
+	if c.estimate(e, now) >= float64(limit) {
+		return false
+	}
+
+	e.current++
+
+	return true
+}
+
+// Count returns the estimated number of events for the key in the trailing
+// window, rounded down. Returns 0 if the key is not tracked or has expired.
+func (c *SlidingCounter) Count(key string) int {
+	return c.count(key, time.Now())
+}
+
+func (c *SlidingCounter) count(key string, now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return 0
+	}
+
+	if c.expired(e, now) {
+		delete(c.entries, key)
+		return 0
+	}
+
+	c.advance(e, now)
+
+	return int(c.estimate(e, now))
+}
+
+// Reset clears all tracked keys and their counters.
+func (c *SlidingCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*slidingEntry, c.maxKeys)
+}
+
+// Len returns the number of currently tracked keys (including expired but not yet cleaned up).
+func (c *SlidingCounter) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// expired reports whether neither window of the entry overlaps the trailing
+// window ending at now.
+func (c *SlidingCounter) expired(e *slidingEntry, now time.Time) bool {
+	return now.Sub(e.windowStart) >= 2*c.window
+}
+
+// advance rolls the entry forward so that its current window contains now.
+// Caller must hold c.mu.
+func (c *SlidingCounter) advance(e *slidingEntry, now time.Time) {
+	elapsed := now.Sub(e.windowStart)
+
+	switch {
+	case elapsed >= 2*c.window:
+		e.previous = 0
+		e.current = 0
+		e.windowStart = now
+	case elapsed >= c.window:
+		e.previous = e.current
+		e.current = 0
+		e.windowStart = e.windowStart.Add(c.window)
+	}
+}
+
+// estimate returns the weighted count of an advanced entry. Caller must hold c.mu.
+func (c *SlidingCounter) estimate(e *slidingEntry, now time.Time) float64 {
+	elapsed := now.Sub(e.windowStart)
+	weight := 1 - float64(elapsed)/float64(c.window)
+
+	return float64(e.previous)*weight + float64(e.current)
+}
+
+// getOrCreate returns the advanced entry for the key, creating it if it
+// doesn't exist. Caller must hold c.mu.
+func (c *SlidingCounter) getOrCreate(key string, now time.Time) *slidingEntry {
+	if e, ok := c.entries[key]; ok {
+		c.advance(e, now)
+		return e
+	}
+
+	c.evictIfNeeded(now)
+
+	e := &slidingEntry{
+		windowStart: now,
+	}
+	c.entries[key] = e
+
+	return e
+}
+
+// evictIfNeeded removes expired entries first, then evicts the oldest window
+// if still at capacity. Caller must hold c.mu.
+func (c *SlidingCounter) evictIfNeeded(now time.Time) {
+	if len(c.entries) < c.maxKeys {
+		return
+	}
+
+	for key, e := range c.entries {
+		if c.expired(e, now) {
+			delete(c.entries, key)
+		}
+	}
+
+	if len(c.entries) < c.maxKeys {
+		return
+	}
+
+	var oldestKey string
+	var oldestStart time.Time
+
+	for key, e := range c.entries {
+		if oldestKey == "" || e.windowStart.Before(oldestStart) {
+			oldestKey = key
+			oldestStart = e.windowStart
+		}
+	}
+
+	delete(c.entries, oldestKey)
+}
+
+// SlidingLog implements the Sliding Window Log algorithm for rate limiting.
+// It records the timestamp of every allowed event per key and allows a request
+// only if fewer than limit events happened in the trailing window.
+//
+// Properties:
+//   - Exact: no boundary bursts and no approximation
+//   - O(limit) memory per key (one timestamp per allowed event)
+//   - Amortized O(1) per operation (expired timestamps are skipped by advancing
+//     a head index; the log is compacted once half of it is expired)
+//   - Denied requests are not recorded
+//   - Thread-safe
+//   - Max tracked keys with least-recently-active eviction; inserting a new key
+//     at capacity prunes every tracked key first, which is O(keys)
+type SlidingLog struct {
+	mu       sync.Mutex
+	entries  map[string]*logEntry
+	window   time.Duration
+	maxKeys  int
+	cleanup  *time.Ticker
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type logEntry struct {
+	events []time.Time // Ascending timestamps of allowed events; live from head on
+	head   int         // Index of the oldest event still in the window
+}
+
+// NewSlidingLog creates a new SlidingLog with the specified window duration
+// and maximum number of tracked keys. Parameters and cleanup behave as in New.
+// Call Stop() to release the background goroutine when done.
+func NewSlidingLog(window time.Duration, maxKeys int, cleanupInterval time.Duration) *SlidingLog {
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	l := &SlidingLog{
+		entries: make(map[string]*logEntry, maxKeys),
+		window:  window,
+		maxKeys: maxKeys,
+		done:    make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		l.cleanup = time.NewTicker(cleanupInterval)
+		go l.cleanupLoop()
+	}
+
+	return l
+}
+
+// Stop stops the background cleanup goroutine if one was started.
+// It is safe to call multiple times.
+func (l *SlidingLog) Stop() {
+	l.stopOnce.Do(func() {
+		if l.cleanup != nil {
+			l.cleanup.Stop()
+		}
+		close(l.done)
+	})
+}
+
+func (l *SlidingLog) cleanupLoop() {
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-l.cleanup.C:
+			l.removeExpired(time.Now())
+		}
+	}
+}
+
+func (l *SlidingLog) removeExpired(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, e := range l.entries {
+		if l.prune(e, now) == 0 {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// Allow checks whether fewer than limit events were allowed for the key in
+// the trailing window and, if so, records a new event.
+func (l *SlidingLog) Allow(key string, limit int) bool {
+	return l.allow(key, limit, time.Now())
+}
+
+func (l *SlidingLog) allow(key string, limit int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		if limit <= 0 {
+			return false
+		}
+
+		l.evictIfNeeded(now)
+
+		e = &logEntry{}
+		l.entries[key] = e
+	}
+
+	if l.prune(e, now) >= limit {
+		return false
+	}
+
+	e.events = append(e.events, now)
+
+	return true
+}
+
+// Count returns the number of events allowed for the key in the trailing window.
+func (l *SlidingLog) Count(key string) int {
+	return l.count(key, time.Now())
+}
+
+func (l *SlidingLog) count(key string, now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok {
+		return 0
+	}
+
+	n := l.prune(e, now)
+	if n == 0 {
+		delete(l.entries, key)
+	}
+
+	return n
+}
+
+// Reset clears all tracked keys and their logs.
+func (l *SlidingLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = make(map[string]*logEntry, l.maxKeys)
+}
+
+// Len returns the number of currently tracked keys (including expired but not yet cleaned up).
+func (l *SlidingLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.entries)
+}
+
+// prune drops events outside the trailing window ending at now and returns
+// the number of remaining events. Expired events are skipped by moving the
+// head; the live events are moved to the front only once the head passes the
+// middle, so each event is stepped over and moved at most once on average.
+// Caller must hold l.mu.
+func (l *SlidingLog) prune(e *logEntry, now time.Time) int {
+	cutoff := now.Add(-l.window)
+
+	for e.head < len(e.events) && !e.events[e.head].After(cutoff) {
+		e.head++
+	}
+
+	if e.head > 0 && e.head >= len(e.events)/2 {
+		n := copy(e.events, e.events[e.head:])
+		e.events = e.events[:n]
+		e.head = 0
+	}
+
+	return len(e.events) - e.head
+}
+
+// evictIfNeeded removes expired entries first, then evicts the key whose
+// latest event is the oldest if still at capacity. The first pass prunes every
+// tracked key, so an insert at capacity costs O(keys). Caller must hold l.mu.
+func (l *SlidingLog) evictIfNeeded(now time.Time) {
+	if len(l.entries) < l.maxKeys {
+		return
+	}
+
+	for key, e := range l.entries {
+		if l.prune(e, now) == 0 {
+			delete(l.entries, key)
+		}
+	}
+
+	if len(l.entries) < l.maxKeys {
+		return
+	}
+
+	var oldestKey string
+	var oldestLast time.Time
+
+	for key, e := range l.entries {
+		last := e.events[len(e.events)-1]
+		if oldestKey == "" || last.Before(oldestLast) {
+			oldestKey = key
+			oldestLast = last
+		}
+	}
+
+	delete(l.entries, oldestKey)
+}
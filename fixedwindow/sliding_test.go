@@ -0,0 +1,381 @@
+package fixedwindow
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSlidingCounter(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		c := NewSlidingCounter(0, -1, 0)
+		defer c.Stop()
+
+		assert.Equal(t, time.Minute, c.window)
+		assert.Equal(t, 1000, c.maxKeys)
+		assert.Nil(t, c.cleanup)
+	})
+
+	t.Run("with cleanup interval", func(t *testing.T) {
+		c := NewSlidingCounter(time.Second, 100, 50*time.Millisecond)
+		defer c.Stop()
+
+		assert.NotNil(t, c.cleanup)
+		c.Stop()
+		c.Stop() // Should not panic
+	})
+}
+
+func TestSlidingCounter_Allow(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	t.Run("within limit", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		for range 5 {
+			assert.True(t, c.allow("key1", 5, start))
+		}
+		assert.False(t, c.allow("key1", 5, start))
+		assert.Equal(t, 5, c.count("key1", start), "denied requests are not counted")
+	})
+
+	t.Run("no burst across window boundary", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		assert.True(t, c.allow("key1", 10, start))
+		for range 9 {
+			assert.True(t, c.allow("key1", 10, start.Add(59*time.Second)))
+		}
+
+		// Just after the boundary almost the whole previous window still counts
+		now := start.Add(61 * time.Second)
+		assert.True(t, c.allow("key1", 10, now))
+		assert.False(t, c.allow("key1", 10, now))
+
+		// A quarter into the next window, 7.5 of the previous 10 remain
+		now = start.Add(75 * time.Second)
+		assert.Equal(t, 8, c.count("key1", now))
+		for range 2 {
+			assert.True(t, c.allow("key1", 10, now))
+		}
+		assert.False(t, c.allow("key1", 10, now))
+	})
+
+	t.Run("recovers gradually", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		for range 4 {
+			c.allow("key1", 4, start)
+		}
+
+		assert.False(t, c.allow("key1", 4, start.Add(30*time.Second)))
+		assert.True(t, c.allow("key1", 4, start.Add(80*time.Second)))
+	})
+
+	t.Run("expires after two windows", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		c.allow("key1", 10, start)
+		assert.Equal(t, 0, c.count("key1", start.Add(2*time.Minute)))
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("independent keys", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		assert.True(t, c.allow("key1", 1, start))
+		assert.False(t, c.allow("key1", 1, start))
+		assert.True(t, c.allow("key2", 1, start))
+	})
+
+	t.Run("zero limit", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		assert.False(t, c.Allow("key1", 0))
+		assert.Equal(t, 0, c.Count("key1"))
+	})
+}
+
+func TestSlidingCounter_Eviction(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	t.Run("evicts oldest when at capacity", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 2, 0)
+		defer c.Stop()
+
+		c.allow("key1", 10, start)
+		c.allow("key2", 10, start.Add(time.Second))
+		c.allow("key3", 10, start.Add(2*time.Second))
+
+		assert.Equal(t, 2, c.Len())
+		assert.Equal(t, 0, c.count("key1", start.Add(2*time.Second)))
+		assert.Equal(t, 1, c.count("key3", start.Add(2*time.Second)))
+	})
+
+	t.Run("evicts expired before active", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 2, 0)
+		defer c.Stop()
+
+		c.allow("key1", 10, start)
+		c.allow("key2", 10, start.Add(90*time.Second))
+		c.allow("key3", 10, start.Add(120*time.Second))
+
+		now := start.Add(120 * time.Second)
+		assert.Equal(t, 2, c.Len())
+		assert.Equal(t, 1, c.count("key2", now))
+		assert.Equal(t, 1, c.count("key3", now))
+	})
+
+	t.Run("background cleanup", func(t *testing.T) {
+		c := NewSlidingCounter(20*time.Millisecond, 100, 20*time.Millisecond)
+		defer c.Stop()
+
+		c.Allow("key1", 10)
+		c.Allow("key2", 10)
+		assert.Equal(t, 2, c.Len())
+
+		time.Sleep(120 * time.Millisecond)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		c.Allow("key1", 10)
+		c.Reset()
+		assert.Equal(t, 0, c.Len())
+	})
+}
+
+func TestNewSlidingLog(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		l := NewSlidingLog(-time.Second, 0, 0)
+		defer l.Stop()
+
+		assert.Equal(t, time.Minute, l.window)
+		assert.Equal(t, 1000, l.maxKeys)
+		assert.Nil(t, l.cleanup)
+	})
+
+	t.Run("with cleanup interval", func(t *testing.T) {
+		l := NewSlidingLog(time.Second, 100, 50*time.Millisecond)
+		defer l.Stop()
+
+		assert.NotNil(t, l.cleanup)
+		l.Stop()
+		l.Stop() // Should not panic
+	})
+}
+
+func TestSlidingLog_Allow(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	t.Run("exact trailing window", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 100, 0)
+		defer l.Stop()
+
+		assert.True(t, l.allow("key1", 3, start))
+		assert.True(t, l.allow("key1", 3, start.Add(20*time.Second)))
+		assert.True(t, l.allow("key1", 3, start.Add(40*time.Second)))
+		assert.False(t, l.allow("key1", 3, start.Add(59*time.Second)))
+
+		// The first event leaves the window exactly one window later
+		assert.True(t, l.allow("key1", 3, start.Add(60*time.Second)))
+		assert.False(t, l.allow("key1", 3, start.Add(79*time.Second)))
+		assert.Equal(t, 3, l.count("key1", start.Add(79*time.Second)))
+		assert.Equal(t, 1, l.count("key1", start.Add(100*time.Second)))
+	})
+
+	t.Run("no burst across window boundary", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 100, 0)
+		defer l.Stop()
+
+		for range 10 {
+			assert.True(t, l.allow("key1", 10, start.Add(59*time.Second)))
+		}
+		assert.False(t, l.allow("key1", 10, start.Add(61*time.Second)))
+	})
+
+	t.Run("expired key is removed", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 100, 0)
+		defer l.Stop()
+
+		l.allow("key1", 10, start)
+		assert.Equal(t, 0, l.count("key1", start.Add(time.Minute)))
+		assert.Equal(t, 0, l.Len())
+	})
+
+	t.Run("zero limit", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 100, 0)
+		defer l.Stop()
+
+		assert.False(t, l.Allow("key1", 0))
+		assert.Equal(t, 0, l.Len())
+	})
+
+	t.Run("steady stream stays bounded", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 100, 0)
+		defer l.Stop()
+
+		// One event per second with a limit above the rate: the log holds
+		// one window of events and expired ones are compacted away
+		now := start
+		for range 1000 {
+			require.True(t, l.allow("key1", 100, now))
+			now = now.Add(time.Second)
+		}
+
+		e := l.entries["key1"]
+		assert.Equal(t, 59, l.count("key1", now))
+		assert.LessOrEqual(t, len(e.events), 120)
+		assert.Equal(t, now.Add(-time.Second), e.events[len(e.events)-1])
+		assert.True(t, e.events[e.head].After(now.Add(-time.Minute)))
+	})
+}
+
+func TestSlidingLog_Eviction(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	t.Run("evicts least recently active", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 2, 0)
+		defer l.Stop()
+
+		l.allow("key1", 10, start)
+		l.allow("key2", 10, start.Add(time.Second))
+		l.allow("key1", 10, start.Add(2*time.Second))
+		l.allow("key3", 10, start.Add(3*time.Second))
+
+		now := start.Add(3 * time.Second)
+		assert.Equal(t, 2, l.Len())
+		assert.Equal(t, 2, l.count("key1", now))
+		assert.Equal(t, 0, l.count("key2", now))
+		assert.Equal(t, 1, l.count("key3", now))
+	})
+
+	t.Run("evicts expired before active", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 2, 0)
+		defer l.Stop()
+
+		l.allow("key1", 10, start)
+		l.allow("key2", 10, start.Add(30*time.Second))
+		l.allow("key3", 10, start.Add(61*time.Second))
+
+		now := start.Add(61 * time.Second)
+		assert.Equal(t, 1, l.count("key2", now))
+		assert.Equal(t, 1, l.count("key3", now))
+	})
+
+	t.Run("background cleanup", func(t *testing.T) {
+		l := NewSlidingLog(20*time.Millisecond, 100, 20*time.Millisecond)
+		defer l.Stop()
+
+		l.Allow("key1", 10)
+		l.Allow("key2", 10)
+		assert.Equal(t, 2, l.Len())
+
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, 0, l.Len())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		l := NewSlidingLog(time.Minute, 100, 0)
+		defer l.Stop()
+
+		l.Allow("key1", 10)
+		l.Reset()
+		assert.Equal(t, 0, l.Len())
+	})
+}
+
+func TestSliding_Concurrency(t *testing.T) {
+	c := NewSlidingCounter(time.Minute, 1000, 0)
+	defer c.Stop()
+
+	l := NewSlidingLog(time.Minute, 1000, 0)
+	defer l.Stop()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			key := fmt.Sprintf("key-%d", i%5)
+			for range 100 {
+				c.Allow(key, 150)
+				l.Allow(key, 150)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Each of 5 keys got 200 requests, 150 of which were allowed
+	for i := range 5 {
+		key := fmt.Sprintf("key-%d", i)
+		assert.Equal(t, 150, c.Count(key))
+		assert.Equal(t, 150, l.Count(key))
+	}
+}
+
+func BenchmarkSlidingCounter_Allow(b *testing.B) {
+	c := NewSlidingCounter(time.Minute, 10000, 0)
+	defer c.Stop()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		c.Allow("bench-key", 1000000)
+	}
+}
+
+func BenchmarkSlidingLog_Allow(b *testing.B) {
+	l := NewSlidingLog(time.Second, 10000, 0)
+	defer l.Stop()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		l.Allow("bench-key", 1000)
+	}
+}
+
+func FuzzSlidingCounter_Allow(f *testing.F) {
+	f.Add(10, int64(0), int64(30))
+	f.Add(1, int64(59), int64(61))
+	f.Add(0, int64(0), int64(0))
+
+	f.Fuzz(func(t *testing.T, limit int, first, second int64) {
+		if first < 0 || first >= 120 || second < first || second-first >= 60 {
+			t.Skip()
+		}
+
+		start := time.Unix(1700000000, 0)
+		c := NewSlidingCounter(time.Minute, 100, 0)
+		defer c.Stop()
+
+		l := NewSlidingLog(time.Minute, 100, 0)
+		defer l.Stop()
+
+		// Neither limiter may allow more than limit events in one window
+		allowedC, allowedL := 0, 0
+		for _, offset := range []int64{first, first, second, second} {
+			now := start.Add(time.Duration(offset) * time.Second)
+			if c.allow("key", limit, now) {
+				allowedC++
+			}
+			if l.allow("key", limit, now) {
+				allowedL++
+			}
+		}
+
+		if limit >= 0 && (allowedC > limit || allowedL > limit) {
+			t.Errorf("allowed %d/%d events with limit %d", allowedC, allowedL, limit)
+		}
+	})
+}
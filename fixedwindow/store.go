@@ -0,0 +1,423 @@
+package fixedwindow
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vitalvas/gokit/ewma"
+)
+
+// Key prefixes used by a Counter backed by a Store. Counts and lockout markers
+// live in separate namespaces so that user keys cannot collide with markers.
+// A MemoryStore keeps the lockout on the count entry and needs no markers.
+const (
+	countKeyPrefix   = "c:"
+	lockoutKeyPrefix = "l:"
+)
+
+// Store holds fixed window counters for a Counter. Implementations shared
+// between processes (such as RedisStore) enforce limits cluster-wide.
+type Store interface {
+	// Increment adds delta to the counter for key and returns the new count and
+	// the time its window ends. A missing or expired counter starts from zero
+	// with a window ending ttl from now; the window of a live counter is kept.
+	Increment(key string, delta int, ttl time.Duration) (int, time.Time, error)
+
+	// Get returns the counter for key and the time its window ends.
+	// Returns 0 and zero time if the key is missing or expired.
+	Get(key string) (int, time.Time, error)
+
+	// Delete removes the counter for key.
+	Delete(key string) error
+}
+
+// MemoryStore is an in-process Store with per-key windows starting on first
+// increment, a maximum number of tracked keys with oldest-window eviction, and
+// optional background cleanup. Counters created with New keep their keys in a
+// MemoryStore of their own.
+type MemoryStore struct {
+	mu       sync.Mutex
+	entries  map[string]*entry
+	maxKeys  int
+	clock    ewma.Clock
+	cleanup  *time.Ticker
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type entry struct {
+	count     int
+	lockedOut bool // Set by Counter.Allow; not visible through the Store interface
+	windowEnd time.Time
+}
+
+// expired reports whether the entry's window has ended; the window end is exclusive.
+func (e *entry) expired(now time.Time) bool {
+	return !now.Before(e.windowEnd)
+}
+
+// NewMemoryStore creates a new MemoryStore tracking at most maxKeys keys.
+// Zero or negative maxKeys defaults to 1000. If cleanupInterval is positive,
+// a background goroutine removes expired entries at that interval.
+// Call Stop() to release the background goroutine when done.
+func NewMemoryStore(maxKeys int, cleanupInterval time.Duration) *MemoryStore {
+	return NewMemoryStoreWithClock(maxKeys, cleanupInterval, ewma.SystemClock)
+}
+
+// NewMemoryStoreWithClock creates a new MemoryStore that reads the current time
+// from clock. If clock is nil, ewma.SystemClock is used. Share the clock with
+// the Counter so that windows and expiry are measured on the same time line.
+func NewMemoryStoreWithClock(maxKeys int, cleanupInterval time.Duration, clock ewma.Clock) *MemoryStore {
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	if clock == nil {
+		clock = ewma.SystemClock
+	}
+
+	s := &MemoryStore{
+		entries: make(map[string]*entry, maxKeys),
+		maxKeys: maxKeys,
+		clock:   clock,
+		done:    make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		s.cleanup = time.NewTicker(cleanupInterval)
+		go s.cleanupLoop()
+	}
+
+	return s
+}
+
+// Stop stops the background cleanup goroutine if one was started.
+// It is safe to call multiple times.
+func (s *MemoryStore) Stop() {
+	s.stopOnce.Do(func() {
+		if s.cleanup != nil {
+			s.cleanup.Stop()
+		}
+		close(s.done)
+	})
+}
+
+func (s *MemoryStore) cleanupLoop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.cleanup.C:
+			s.removeExpired()
+		}
+	}
+}
+
+func (s *MemoryStore) removeExpired() {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, e := range s.entries {
+		if e.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Increment implements Store.
+func (s *MemoryStore) Increment(key string, delta int, ttl time.Duration) (int, time.Time, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.getOrCreate(key, now, func(now time.Time) time.Time { return now.Add(ttl) })
+	e.count += delta
+
+	return e.count, e.windowEnd, nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(key string) (int, time.Time, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.lookup(key, now)
+	if e == nil {
+		return 0, time.Time{}, nil
+	}
+
+	return e.count, e.windowEnd, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+// Reset removes all keys.
+func (s *MemoryStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = make(map[string]*entry, s.maxKeys)
+}
+
+// Len returns the number of stored keys (including expired but not yet cleaned up).
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// allow counts a request for key and locks the key out for the rest of its
+// window once the count exceeds limit. New windows end at windowEnd(now).
+func (s *MemoryStore) allow(key string, limit int, windowEnd func(time.Time) time.Time) (bool, int, time.Time) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.getOrCreate(key, now, windowEnd)
+
+	e.count++
+
+	if !e.lockedOut && e.count > limit {
+		e.lockedOut = true
+	}
+
+	return !e.lockedOut, e.count, e.windowEnd
+}
+
+// isLockedOut reports whether key is locked out in its current window.
+func (s *MemoryStore) isLockedOut(key string) bool {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.lookup(key, now)
+
+	return e != nil && e.lockedOut
+}
+
+// lenPrefix returns the number of stored keys starting with prefix.
+func (s *MemoryStore) lenPrefix(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// lookup returns the live entry for the key, removing it if its window has
+// expired. Returns nil if the key is not tracked. Caller must hold s.mu.
+func (s *MemoryStore) lookup(key string, now time.Time) *entry {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+
+	if e.expired(now) {
+		delete(s.entries, key)
+		return nil
+	}
+
+	return e
+}
+
+// getOrCreate returns the entry for the key, creating it if it doesn't exist
+// or resetting it if its window has expired. New windows end at windowEnd(now).
+// Caller must hold s.mu.
+func (s *MemoryStore) getOrCreate(key string, now time.Time, windowEnd func(time.Time) time.Time) *entry {
+	if e, ok := s.entries[key]; ok {
+		if e.expired(now) {
+			e.count = 0
+			e.lockedOut = false
+			e.windowEnd = windowEnd(now)
+		}
+		return e
+	}
+
+	s.evictIfNeeded(now)
+
+	e := &entry{
+		windowEnd: windowEnd(now),
+	}
+	s.entries[key] = e
+
+	return e
+}
+
+// evictIfNeeded removes expired entries first, then evicts the oldest window
+// if still at capacity. Caller must hold s.mu.
+func (s *MemoryStore) evictIfNeeded(now time.Time) {
+	if len(s.entries) < s.maxKeys {
+		return
+	}
+
+	for key, e := range s.entries {
+		if e.expired(now) {
+			delete(s.entries, key)
+		}
+	}
+
+	if len(s.entries) < s.maxKeys {
+		return
+	}
+
+	var oldestKey string
+	var oldestEnd time.Time
+	found := false
+
+	for key, e := range s.entries {
+		if !found || e.windowEnd.Before(oldestEnd) {
+			oldestKey = key
+			oldestEnd = e.windowEnd
+			found = true
+		}
+	}
+
+	delete(s.entries, oldestKey)
+}
+
+// StoreOptions configures a Counter created with NewWithStoreOptions.
+type StoreOptions struct {
+	// Window is the window length. Zero or negative defaults to 1 minute.
+	// Ignored when Period is set.
+	Window time.Duration
+
+	// Period enables calendar-aligned windows as in NewAligned.
+	// Zero keeps windows starting on first access.
+	Period Period
+
+	// Location is the time zone of aligned windows. Nil defaults to UTC.
+	Location *time.Location
+
+	// Clock is used to compute window ends. Nil defaults to ewma.SystemClock.
+	// The store judges expiry with its own time source, so share the clock
+	// with a MemoryStore (see NewMemoryStoreWithClock) in tests.
+	Clock ewma.Clock
+
+	// OnError, if set, receives store errors.
+	OnError func(error)
+}
+
+// NewWithStore creates a Counter that keeps its counters in store instead of
+// a local map, so that several processes sharing the store enforce limits
+// together. Eviction, cleanup and key limits are the responsibility of the store.
+//
+// Counter methods do not return errors. When the store fails, Allow fails open
+// (returns true), queries report an untracked key, and the error is passed to
+// onError if it is not nil.
+func NewWithStore(window time.Duration, store Store, onError func(error)) *Counter {
+	return NewWithStoreOptions(store, StoreOptions{Window: window, OnError: onError})
+}
+
+// NewWithStoreOptions creates a Counter backed by store as NewWithStore does,
+// with optional calendar-aligned windows and an injected clock. Invalid
+// periods default to PeriodMinute.
+func NewWithStoreOptions(store Store, opts StoreOptions) *Counter {
+	c := newCounter(opts.Window, opts.Period, opts.Location, opts.Clock)
+	c.store = store
+	c.onError = opts.OnError
+
+	return c
+}
+
+func (c *Counter) storeError(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+// storeAllow counts the request and marks the key as locked out once its count
+// exceeds the limit. The marker expires with the counter window and denies
+// every request until then, whatever limit later calls pass, as with the
+// lockout flag of the local map. A MemoryStore sets that flag on the count
+// entry instead, so markers neither take key slots nor get evicted apart
+// from their counter.
+func (c *Counter) storeAllow(key string, limit int) (bool, int, time.Time) {
+	if ms, ok := c.store.(*MemoryStore); ok {
+		return ms.allow(countKeyPrefix+key, limit, c.windowEnd)
+	}
+
+	now := c.clock.Now()
+
+	count, windowEnd, err := c.store.Increment(countKeyPrefix+key, 1, c.windowEnd(now).Sub(now))
+	if err != nil {
+		c.storeError(err)
+		return true, 0, time.Time{}
+	}
+
+	if count <= limit {
+		return !c.storeIsLockedOut(key), count, windowEnd
+	}
+
+	ttl := windowEnd.Sub(now)
+	if ttl > 0 {
+		if _, _, err := c.store.Increment(lockoutKeyPrefix+key, 1, ttl); err != nil {
+			c.storeError(err)
+		}
+	}
+
+	return false, count, windowEnd
+}
+
+func (c *Counter) storeAdd(key string, delta int) int {
+	now := c.clock.Now()
+
+	count, _, err := c.store.Increment(countKeyPrefix+key, delta, c.windowEnd(now).Sub(now))
+	if err != nil {
+		c.storeError(err)
+		return 0
+	}
+
+	return count
+}
+
+func (c *Counter) storeGet(key string) (int, time.Time) {
+	count, windowEnd, err := c.store.Get(countKeyPrefix + key)
+	if err != nil {
+		c.storeError(err)
+		return 0, time.Time{}
+	}
+
+	return count, windowEnd
+}
+
+func (c *Counter) storeIsLockedOut(key string) bool {
+	if ms, ok := c.store.(*MemoryStore); ok {
+		return ms.isLockedOut(countKeyPrefix + key)
+	}
+
+	marker, _, err := c.store.Get(lockoutKeyPrefix + key)
+	if err != nil {
+		c.storeError(err)
+		return false
+	}
+
+	return marker > 0
+}
+
+func (c *Counter) storeDelete(key string) {
+	if err := c.store.Delete(countKeyPrefix + key); err != nil {
+		c.storeError(err)
+	}
+
+	if err := c.store.Delete(lockoutKeyPrefix + key); err != nil {
+		c.storeError(err)
+	}
+}
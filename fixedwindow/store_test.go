@@ -0,0 +1,221 @@
+package fixedwindow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vitalvas/gokit/ewma"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		s := NewMemoryStore(0, 0)
+		defer s.Stop()
+
+		assert.Equal(t, 1000, s.maxKeys)
+		assert.Nil(t, s.cleanup)
+		assert.Equal(t, ewma.SystemClock, s.clock)
+
+		s = NewMemoryStoreWithClock(0, 0, nil)
+		assert.Equal(t, ewma.SystemClock, s.clock)
+	})
+
+	t.Run("increment get delete", func(t *testing.T) {
+		s := NewMemoryStore(100, 0)
+		defer s.Stop()
+
+		count, end, err := s.Get("key1")
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+		assert.True(t, end.IsZero())
+
+		count, end, err = s.Increment("key1", 2, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		count, end2, err := s.Increment("key1", 3, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, 5, count)
+		assert.Equal(t, end, end2, "window of a live counter is kept")
+
+		require.NoError(t, s.Delete("key1"))
+		count, _, err = s.Get("key1")
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("window expires", func(t *testing.T) {
+		clock := ewma.NewManualClock(time.Unix(1700000000, 0))
+		s := NewMemoryStoreWithClock(100, 0, clock)
+		defer s.Stop()
+
+		_, _, _ = s.Increment("key1", 3, 20*time.Millisecond)
+		clock.Advance(40 * time.Millisecond)
+
+		count, _, _ := s.Get("key1")
+		assert.Equal(t, 0, count)
+
+		_, _, _ = s.Increment("key2", 3, 20*time.Millisecond)
+		clock.Advance(40 * time.Millisecond)
+
+		count, _, _ = s.Increment("key2", 1, time.Minute)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("window end is exclusive", func(t *testing.T) {
+		clock := ewma.NewManualClock(time.Unix(1700000000, 0))
+		s := NewMemoryStoreWithClock(100, 0, clock)
+		defer s.Stop()
+
+		_, end, _ := s.Increment("key1", 3, time.Minute)
+		clock.Set(end.Add(-time.Nanosecond))
+		count, _, _ := s.Get("key1")
+		assert.Equal(t, 3, count)
+
+		clock.Set(end)
+		count, _, _ = s.Get("key1")
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		s := NewMemoryStore(2, 0)
+		defer s.Stop()
+
+		_, _, _ = s.Increment("key1", 1, time.Minute)
+		_, _, _ = s.Increment("key2", 1, 2*time.Minute)
+		_, _, _ = s.Increment("key3", 1, 3*time.Minute)
+
+		assert.Equal(t, 2, s.Len())
+		count, _, _ := s.Get("key1")
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("background cleanup and reset", func(t *testing.T) {
+		clock := ewma.NewManualClock(time.Unix(1700000000, 0))
+		s := NewMemoryStoreWithClock(100, 10*time.Millisecond, clock)
+		defer s.Stop()
+
+		_, _, _ = s.Increment("key1", 1, time.Second)
+		_, _, _ = s.Increment("key2", 1, time.Minute)
+
+		// Cleanup runs on real time but decides expiry by the clock
+		clock.Advance(time.Second)
+		assert.Eventually(t, func() bool { return s.Len() == 1 }, time.Second, 5*time.Millisecond)
+
+		s.Reset()
+		assert.Equal(t, 0, s.Len())
+
+		s.Stop()
+		s.Stop() // Should not panic
+	})
+}
+
+func TestCounterWithMemoryStore(t *testing.T) {
+	store := NewMemoryStore(100, 0)
+	defer store.Stop()
+
+	c := NewWithStore(0, store, nil)
+	defer c.Stop()
+
+	local := New(time.Minute, 100, 0)
+	defer local.Stop()
+
+	assert.Equal(t, time.Minute, c.window)
+
+	// Same sequence of calls gives the same results as the local map
+	for range 5 {
+		assert.Equal(t, local.Allow("key1", 3), c.Allow("key1", 3))
+	}
+	assert.Equal(t, local.Add("key1", 4), c.Add("key1", 4))
+	assert.Equal(t, local.Add("key1", 0), c.Add("key1", 0))
+	assert.Equal(t, local.Count("key1"), c.Count("key1"))
+	assert.Equal(t, local.IsLockedOut("key1"), c.IsLockedOut("key1"))
+	assert.WithinDuration(t, local.WindowExpiry("key1"), c.WindowExpiry("key1"), 10*time.Millisecond)
+
+	assert.Equal(t, 5, c.Add("key2", 5))
+	assert.False(t, c.IsLockedOut("key2"), "Add does not trigger lockout")
+
+	// Lockouts are kept on the count entries, so only count keys are stored
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 2, store.Len())
+
+	c.Delete("key1")
+	assert.Equal(t, 0, c.Count("key1"))
+	assert.False(t, c.IsLockedOut("key1"))
+	assert.Equal(t, 1, c.Len())
+
+	// Lockout is sticky until the window ends, even with a higher limit
+	for range 3 {
+		assert.Equal(t, local.Allow("key3", 2), c.Allow("key3", 2))
+	}
+	assert.False(t, local.Allow("key3", 10))
+	assert.False(t, c.Allow("key3", 10))
+	assert.True(t, c.IsLockedOut("key3"))
+
+	c.Reset()
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, 0, c.Count("key1"))
+	assert.False(t, c.IsLockedOut("key1"))
+	assert.True(t, c.Allow("key1", 3))
+}
+
+func TestCounterWithMemoryStore_Capacity(t *testing.T) {
+	store := NewMemoryStore(2, 0)
+	defer store.Stop()
+
+	c := NewWithStore(time.Minute, store, nil)
+
+	assert.True(t, c.Allow("key1", 1))
+	assert.False(t, c.Allow("key1", 1))
+
+	// A new key fits next to the locked out key without evicting its lockout
+	assert.True(t, c.Allow("key2", 1))
+	assert.Equal(t, 2, c.Len())
+	assert.True(t, c.IsLockedOut("key1"))
+	assert.False(t, c.Allow("key1", 10))
+}
+
+func TestNewWithStoreOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		c := NewWithStoreOptions(NewMemoryStore(0, 0), StoreOptions{})
+
+		assert.Equal(t, time.Minute, c.window)
+		assert.Zero(t, c.period)
+		assert.Equal(t, ewma.SystemClock, c.clock)
+		assert.Nil(t, c.local)
+
+		c = NewWithStoreOptions(NewMemoryStore(0, 0), StoreOptions{Window: time.Hour, Period: Period(9)})
+		assert.Equal(t, PeriodMinute, c.period)
+		assert.Equal(t, time.Minute, c.window)
+		assert.Equal(t, time.UTC, c.location)
+	})
+
+	t.Run("aligned with shared clock", func(t *testing.T) {
+		clock := ewma.NewManualClock(time.Date(2026, 3, 1, 10, 45, 0, 0, time.UTC))
+		store := NewMemoryStoreWithClock(100, 0, clock)
+		defer store.Stop()
+
+		c := NewWithStoreOptions(store, StoreOptions{Period: PeriodHour, Clock: clock})
+		end := time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)
+
+		assert.True(t, c.Allow("key1", 2))
+		assert.True(t, c.Allow("key1", 2))
+		assert.False(t, c.Allow("key1", 2))
+		assert.Equal(t, end, c.WindowExpiry("key1"))
+
+		clock.Advance(10 * time.Minute)
+		assert.Equal(t, 3, c.Add("key2", 3))
+		assert.Equal(t, end, c.WindowExpiry("key2"))
+		assert.True(t, c.IsLockedOut("key1"))
+
+		// Counts and lockout markers reset on the hour
+		clock.Set(end)
+		assert.Equal(t, 0, c.Count("key1"))
+		assert.False(t, c.IsLockedOut("key1"))
+		assert.True(t, c.Allow("key1", 2))
+		assert.Equal(t, end.Add(time.Hour), c.WindowExpiry("key1"))
+	})
+}
@@ -0,0 +1,150 @@
+package hyperloglog
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/vitalvas/gokit/ewma"
+)
+
+// Decayed pairs a HyperLogLog with a decaying rate of newly seen items.
+// Every time an insert changes the cardinality estimate, the signed change
+// is recorded with exponential decay, so DecayedCount answers "how many
+// distinct items appeared recently" while Count still reports the
+// all-time cardinality. The estimate can drop, for example at the switch
+// from linear counting; the drop is subtracted so the next rise is not
+// counted twice, and the decayed count is clamped at zero.
+//
+// The estimate is maintained incrementally: an insert that does not change
+// a register costs one hash and no estimate recomputation.
+//
+// Unlike HyperLogLog, Decayed is safe for concurrent use.
+type Decayed struct {
+	mu       sync.Mutex
+	hll      *HyperLogLog
+	sum      float64 // harmonic sum of registers
+	zeros    int     // number of zero registers
+	estimate float64
+	rate     *ewma.Rate
+	clock    ewma.Clock
+}
+
+// NewDecayed creates a decaying distinct counter with the given precision
+// (see New) and half-life.
+func NewDecayed(precision uint8, halfLife time.Duration) *Decayed {
+	return NewDecayedWithClock(precision, halfLife, ewma.SystemClock)
+}
+
+// NewDecayedWithClock creates a decaying distinct counter that reads the
+// current time from clock. If clock is nil, ewma.SystemClock is used.
+func NewDecayedWithClock(precision uint8, halfLife time.Duration, clock ewma.Clock) *Decayed {
+	if clock == nil {
+		clock = ewma.SystemClock
+	}
+
+	d := &Decayed{
+		hll:   New(precision),
+		rate:  ewma.NewRateWithClock(halfLife, clock),
+		clock: clock,
+	}
+	d.reset()
+
+	return d
+}
+
+// Add adds raw bytes at the current time.
+func (d *Decayed) Add(data []byte) {
+	d.AddAt(data, d.clock.Now())
+}
+
+// AddAt adds raw bytes at time t.
+func (d *Decayed) AddAt(data []byte, t time.Time) {
+	h := d.hll
+	hash := hash64(data)
+	index := hash >> (64 - h.precision)
+	w := hash<<h.precision | (1 << (h.precision - 1))
+	leadingZeros := uint8(bits.LeadingZeros64(w)) + 1
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	old := h.registers[index]
+	if leadingZeros <= old {
+		return
+	}
+
+	h.registers[index] = leadingZeros
+	d.sum += math.Ldexp(1, -int(leadingZeros)) - math.Ldexp(1, -int(old))
+	if old == 0 {
+		d.zeros--
+	}
+
+	estimate := h.estimate(d.sum, d.zeros)
+	if delta := estimate - d.estimate; delta != 0 {
+		d.rate.AddAt(delta, t)
+		if d.rate.RateAt(t) < 0 {
+			d.rate.SetAt(0, t)
+		}
+	}
+	d.estimate = estimate
+}
+
+// AddString adds a string element at the current time.
+func (d *Decayed) AddString(s string) {
+	d.Add(stringToBytes(s))
+}
+
+// AddStringAt adds a string element at time t.
+func (d *Decayed) AddStringAt(s string, t time.Time) {
+	d.AddAt(stringToBytes(s), t)
+}
+
+// Count returns the all-time estimated cardinality.
+func (d *Decayed) Count() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return uint64(d.estimate)
+}
+
+// DecayedCount returns the decayed number of distinct items at the
+// current time: each item contributes exp(-ln2/halfLife * age) where age
+// is the time since it was first seen.
+func (d *Decayed) DecayedCount() float64 {
+	return d.rate.Rate()
+}
+
+// DecayedCountAt returns the decayed number of distinct items at time t.
+func (d *Decayed) DecayedCountAt(t time.Time) float64 {
+	return d.rate.RateAt(t)
+}
+
+// Sketch returns a copy of the underlying HyperLogLog.
+func (d *Decayed) Sketch() *HyperLogLog {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.hll.Clone()
+}
+
+// HalfLife returns the decay half-life.
+func (d *Decayed) HalfLife() time.Duration {
+	return d.rate.HalfLife()
+}
+
+// Clear resets the sketch and the decayed count.
+func (d *Decayed) Clear() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.hll.Clear()
+	d.rate.Reset()
+	d.reset()
+}
+
+// reset recomputes the incremental estimate state for empty registers.
+func (d *Decayed) reset() {
+	d.sum = float64(d.hll.m)
+	d.zeros = int(d.hll.m)
+	d.estimate = 0
+}
@@ -0,0 +1,123 @@
+package hyperloglog
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitalvas/gokit/ewma"
+)
+
+func TestNewDecayed(t *testing.T) {
+	d := NewDecayed(12, 30*time.Second)
+	assert.Equal(t, uint8(12), d.Sketch().Precision())
+	assert.Equal(t, 30*time.Second, d.HalfLife())
+	assert.Equal(t, uint64(0), d.Count())
+	assert.Equal(t, 0.0, d.DecayedCount())
+}
+
+func TestDecayed(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("count matches sketch", func(t *testing.T) {
+		d := NewDecayed(14, time.Minute)
+		h := New(14)
+		for i := range 50000 {
+			item := fmt.Sprintf("item-%d", i)
+			d.AddStringAt(item, base)
+			h.AddString(item)
+		}
+
+		assert.Equal(t, h.Count(), d.Count())
+		assert.Equal(t, h.Count(), d.Sketch().Count())
+	})
+
+	t.Run("decayed count tracks new distinct items", func(t *testing.T) {
+		d := NewDecayed(14, 10*time.Second)
+		for i := range 1000 {
+			d.AddStringAt(fmt.Sprintf("item-%d", i), base)
+		}
+
+		assert.InDelta(t, float64(d.Count()), d.DecayedCountAt(base), 1)
+		assert.InDelta(t, 1000, d.DecayedCountAt(base), 20)
+		assert.InDelta(t, 500, d.DecayedCountAt(base.Add(10*time.Second)), 10)
+
+		// Repeated items do not add to the decayed count
+		for i := range 1000 {
+			d.AddStringAt(fmt.Sprintf("item-%d", i), base.Add(10*time.Second))
+		}
+		assert.InDelta(t, 500, d.DecayedCountAt(base.Add(10*time.Second)), 10)
+	})
+
+	t.Run("estimate drops are subtracted", func(t *testing.T) {
+		// Precision 4 crosses from linear counting to the raw estimate
+		// within a few dozen items, where the estimate can fall
+		d := NewDecayed(4, time.Hour)
+		h := New(4)
+		dropped := false
+		for i := range 200 {
+			before := h.Count()
+			item := fmt.Sprintf("item-%d", i)
+			d.AddStringAt(item, base)
+			h.AddString(item)
+			dropped = dropped || h.Count() < before
+
+			// Without decay the decayed count equals the estimate
+			require.InDelta(t, float64(d.estimate), d.DecayedCountAt(base), 1e-6, "item %d", i)
+		}
+		assert.True(t, dropped, "estimate never dropped")
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		clock := ewma.NewManualClock(base)
+		d := NewDecayedWithClock(14, time.Minute, clock)
+		for i := range 100 {
+			d.Add([]byte(fmt.Sprintf("item-%d", i)))
+		}
+
+		clock.Advance(time.Minute)
+		assert.InDelta(t, 50, d.DecayedCount(), 2)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		d := NewDecayed(14, time.Minute)
+		d.AddString("a")
+		d.Clear()
+
+		assert.Equal(t, uint64(0), d.Count())
+		assert.Equal(t, 0.0, d.DecayedCount())
+
+		d.AddStringAt("a", base)
+		assert.Equal(t, uint64(1), d.Count())
+	})
+}
+
+func TestDecayedConcurrency(t *testing.T) {
+	d := NewDecayed(14, time.Minute)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				d.AddString(fmt.Sprintf("g%d-%d", g, i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.InDelta(t, 8000, float64(d.Count()), 200)
+}
+
+func BenchmarkDecayed_Add(b *testing.B) {
+	d := NewDecayed(14, time.Minute)
+	data := []byte("item")
+	b.ReportAllocs()
+	for b.Loop() {
+		d.Add(data)
+	}
+}
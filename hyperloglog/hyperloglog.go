@@ -93,6 +93,12 @@ func (h *HyperLogLog) Count() uint64 {
 		}
 	}
 
+	return uint64(h.estimate(sum, zeros))
+}
+
+// estimate returns the bias-corrected cardinality estimate from the
+// harmonic sum of the registers and the number of zero registers.
+func (h *HyperLogLog) estimate(sum float64, zeros int) float64 {
 	estimate := h.alpha * float64(h.m) * float64(h.m) / sum
 
 	// Apply bias correction for different ranges
@@ -108,7 +114,7 @@ func (h *HyperLogLog) Count() uint64 {
 	}
 	// No correction for medium range
 
-	return uint64(estimate)
+	return estimate
 }
 
 // Merge combines another HyperLogLog into this one.
@@ -0,0 +1,55 @@
+package spacesaving
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Codec encodes and decodes item keys for Export and Import.
+// Implement it to control the serialized form of non-string keys.
+type Codec[K comparable] interface {
+	Encode(item K) ([]byte, error)
+	Decode(data []byte) (K, error)
+}
+
+// StringCodec stores string keys as raw bytes.
+type StringCodec struct{}
+
+// Encode returns the key bytes.
+func (StringCodec) Encode(item string) ([]byte, error) {
+	return []byte(item), nil
+}
+
+// Decode returns the key as a string.
+func (StringCodec) Decode(data []byte) (string, error) {
+	return string(data), nil
+}
+
+// GobCodec encodes keys with encoding/gob.
+// Works for any gob-encodable key, including types implementing
+// encoding.BinaryMarshaler such as netip.Addr.
+type GobCodec[K comparable] struct{}
+
+// Encode gob-encodes the key.
+func (GobCodec[K]) Encode(item K) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(item); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode gob-decodes the key.
+func (GobCodec[K]) Decode(data []byte) (K, error) {
+	var item K
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&item)
+	return item, err
+}
+
+// defaultCodec returns StringCodec for string keys and GobCodec otherwise.
+func defaultCodec[K comparable]() Codec[K] {
+	if codec, ok := any(StringCodec{}).(Codec[K]); ok {
+		return codec
+	}
+	return GobCodec[K]{}
+}
@@ -0,0 +1,43 @@
+package spacesaving
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringCodec(t *testing.T) {
+	data, err := StringCodec{}.Encode("hello")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+
+	value, err := StringCodec{}.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", value)
+}
+
+func TestGobCodec(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		codec := GobCodec[netip.Addr]{}
+		addr := netip.MustParseAddr("2001:db8::1")
+
+		data, err := codec.Encode(addr)
+		require.NoError(t, err)
+
+		value, err := codec.Decode(data)
+		require.NoError(t, err)
+		assert.Equal(t, addr, value)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		_, err := GobCodec[int]{}.Decode([]byte{0xff})
+		assert.Error(t, err)
+	})
+}
+
+func TestDefaultCodec(t *testing.T) {
+	assert.IsType(t, StringCodec{}, defaultCodec[string]())
+	assert.IsType(t, GobCodec[int]{}, defaultCodec[int]())
+}
@@ -0,0 +1,161 @@
+package spacesaving
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Merge combines another summary into this one, keeping this summary's
+// capacity. After merging, Count bounds hold for the combined stream:
+// count - error <= true count <= count.
+//
+// The merge follows Agarwal et al. "Mergeable Summaries" (PODS 2012), using
+// the Space-Saving form of the Misra-Gries merge: an item missing from a full
+// summary is assumed to have that summary's minimum count (as both count and
+// error), counters are summed, and the largest capacity counters are kept.
+//
+// Use cases: Global top-K from per-host summaries, combining time shards
+func (ss *SpaceSaving[K]) Merge(other *SpaceSaving[K]) {
+	if other == nil || other == ss {
+		return
+	}
+
+	// Copy the other summary under its own lock to avoid lock ordering issues
+	other.mu.RLock()
+	otherCounters := make(map[K]counter[K], len(other.counters))
+	for k, c := range other.counters {
+		otherCounters[k] = *c
+	}
+	otherMin := uint64(0)
+	if len(other.counters) >= other.capacity && other.minHeap.size > 0 {
+		otherMin = other.minHeap.min().Count
+	}
+	other.mu.RUnlock()
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	selfMin := uint64(0)
+	if len(ss.counters) >= ss.capacity && ss.minHeap.size > 0 {
+		selfMin = ss.minHeap.min().Count
+	}
+
+	merged := make([]*counter[K], 0, len(ss.counters)+len(otherCounters))
+
+	for k, c := range ss.counters {
+		if o, exists := otherCounters[k]; exists {
+			merged = append(merged, &counter[K]{Item: k, Count: c.Count + o.Count, Error: c.Error + o.Error})
+		} else {
+			merged = append(merged, &counter[K]{Item: k, Count: c.Count + otherMin, Error: c.Error + otherMin})
+		}
+	}
+
+	for k, o := range otherCounters {
+		if _, exists := ss.counters[k]; !exists {
+			merged = append(merged, &counter[K]{Item: k, Count: o.Count + selfMin, Error: o.Error + selfMin})
+		}
+	}
+
+	// Keep the largest counters
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Count == merged[j].Count {
+			return merged[i].Error < merged[j].Error
+		}
+		return merged[i].Count > merged[j].Count
+	})
+	if len(merged) > ss.capacity {
+		merged = merged[:ss.capacity]
+	}
+
+	ss.counters = make(map[K]*counter[K], ss.capacity)
+	ss.minHeap = newMinHeap[K](ss.capacity)
+
+	for _, c := range merged {
+		ss.counters[c.Item] = c
+		ss.minHeap.push(c)
+	}
+}
+
+// Merge combines another rate tracker into this one at the current time.
+// See MergeAt.
+func (rt *RateTracker) Merge(other *RateTracker) {
+	rt.MergeAt(other, rt.clock.Now())
+}
+
+// MergeAt combines another rate tracker into this one, decaying both to
+// time t first. The merge uses the same rules as SpaceSaving.Merge with
+// rates in place of counts; the receiver's capacity and half-life are kept.
+func (rt *RateTracker) MergeAt(other *RateTracker, t time.Time) {
+	if other == nil || other == rt {
+		return
+	}
+
+	other.mu.RLock()
+	otherRates, otherMin := other.decayedAt(t)
+	other.mu.RUnlock()
+
+	rt.mu.Lock()
+
+	selfRates, selfMin := rt.decayedAt(t)
+
+	merged := make([]*rateCounter, 0, len(selfRates)+len(otherRates))
+
+	for k, c := range selfRates {
+		if o, exists := otherRates[k]; exists {
+			merged = append(merged, &rateCounter{Item: k, Rate: c.Rate + o.Rate, ErrorRate: c.ErrorRate + o.ErrorRate, LastUpdate: t})
+		} else {
+			merged = append(merged, &rateCounter{Item: k, Rate: c.Rate + otherMin, ErrorRate: c.ErrorRate + otherMin, LastUpdate: t})
+		}
+	}
+
+	for k, o := range otherRates {
+		if _, exists := selfRates[k]; !exists {
+			merged = append(merged, &rateCounter{Item: k, Rate: o.Rate + selfMin, ErrorRate: o.ErrorRate + selfMin, LastUpdate: t})
+		}
+	}
+
+	// Keep the largest counters
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Rate == merged[j].Rate {
+			return merged[i].ErrorRate < merged[j].ErrorRate
+		}
+		return merged[i].Rate > merged[j].Rate
+	})
+	if len(merged) > rt.capacity {
+		merged = merged[:rt.capacity]
+	}
+
+	rt.counters = make(map[string]*rateCounter, rt.capacity)
+	rt.minHeap = newRateMinHeap(rt.capacity)
+
+	for _, rc := range merged {
+		rt.counters[rc.Item] = rc
+		rt.minHeap.push(rc)
+	}
+
+	pending := rt.resync(t)
+	rt.mu.Unlock()
+
+	dispatch(pending)
+}
+
+// decayedAt returns all counters decayed to time t, and the minimum rate
+// if the tracker is full (zero otherwise). Caller must hold rt.mu.
+func (rt *RateTracker) decayedAt(t time.Time) (map[string]RateItem, float64) {
+	rates := make(map[string]RateItem, len(rt.counters))
+	minRate := math.Inf(1)
+
+	for k, rc := range rt.counters {
+		rate, errorRate := rt.rateAt(rc, t)
+
+		rates[k] = RateItem{Value: k, Rate: rate, ErrorRate: errorRate}
+		minRate = math.Min(minRate, rate)
+	}
+
+	if len(rt.counters) < rt.capacity {
+		minRate = 0
+	}
+
+	return rates, minRate
+}
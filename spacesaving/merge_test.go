@@ -0,0 +1,187 @@
+package spacesaving
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	t.Run("disjoint summaries under capacity are exact", func(t *testing.T) {
+		a := New[string](10)
+		b := New[string](10)
+		a.AddN("x", 5)
+		a.AddN("y", 3)
+		b.AddN("y", 4)
+		b.AddN("z", 1)
+
+		a.Merge(b)
+
+		for item, expected := range map[string]uint64{"x": 5, "y": 7, "z": 1} {
+			count, errBound := a.Count(item)
+			assert.Equal(t, expected, count, item)
+			assert.Equal(t, uint64(0), errBound, item)
+		}
+		assert.Equal(t, 3, a.Size())
+	})
+
+	t.Run("merged result keeps receiver capacity", func(t *testing.T) {
+		a := New[string](3)
+		b := New[string](10)
+		for i := range 10 {
+			b.AddN(fmt.Sprintf("k%d", i), uint64(i+1))
+		}
+
+		a.Merge(b)
+		assert.Equal(t, 3, a.Size())
+		assert.Equal(t, 3, a.Capacity())
+		assert.Equal(t, "k9", a.Top(1)[0].Value)
+	})
+
+	t.Run("error bounds hold across hosts", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(3))
+		exact := make(map[string]uint64)
+		hosts := make([]*SpaceSaving[string], 8)
+		for i := range hosts {
+			hosts[i] = New[string](50)
+		}
+
+		// Zipf-like stream split across hosts
+		zipf := rand.NewZipf(rng, 1.2, 1, 1000)
+		for i := range 50000 {
+			key := fmt.Sprintf("url-%d", zipf.Uint64())
+			exact[key]++
+			hosts[i%len(hosts)].Add(key)
+		}
+
+		global := New[string](50)
+		for _, h := range hosts {
+			global.Merge(h)
+		}
+
+		for _, item := range global.All() {
+			assert.GreaterOrEqual(t, item.Count, exact[item.Value], item.Value)
+			assert.LessOrEqual(t, item.Count-item.Error, exact[item.Value], item.Value)
+		}
+
+		// The heaviest items must be found
+		top := global.Top(3)
+		assert.Equal(t, "url-0", top[0].Value)
+		assert.Equal(t, "url-1", top[1].Value)
+	})
+
+	t.Run("merge nil and self", func(t *testing.T) {
+		a := New[string](10)
+		a.Add("x")
+		a.Merge(nil)
+		a.Merge(a)
+
+		count, _ := a.Count("x")
+		assert.Equal(t, uint64(1), count)
+	})
+
+	t.Run("generic keys", func(t *testing.T) {
+		a := New[int](5)
+		b := New[int](5)
+		a.AddN(1, 10)
+		b.AddN(1, 20)
+
+		a.Merge(b)
+		count, _ := a.Count(1)
+		assert.Equal(t, uint64(30), count)
+	})
+
+	t.Run("concurrent merges", func(t *testing.T) {
+		a := New[string](10)
+		b := New[string](10)
+		a.Add("x")
+		b.Add("y")
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.Merge(b)
+		}()
+		go func() {
+			defer wg.Done()
+			b.Merge(a)
+		}()
+		wg.Wait()
+	})
+}
+
+func TestRateTrackerMerge(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("rates are decayed to merge time and summed", func(t *testing.T) {
+		a := NewRateTracker(10, time.Minute)
+		b := NewRateTracker(10, time.Minute)
+
+		for range 10 {
+			a.TouchAt("x", base)
+		}
+		for range 10 {
+			b.TouchAt("x", base.Add(time.Minute))
+		}
+		b.TouchAt("y", base.Add(time.Minute))
+
+		a.MergeAt(b, base.Add(time.Minute))
+
+		rate, errorRate := a.RateAt("x", base.Add(time.Minute))
+		assert.InDelta(t, 15, rate, 1e-9)
+		assert.Equal(t, 0.0, errorRate)
+
+		rate, _ = a.RateAt("y", base.Add(time.Minute))
+		assert.InDelta(t, 1, rate, 1e-9)
+	})
+
+	t.Run("missing items inherit minimum as error", func(t *testing.T) {
+		a := NewRateTracker(2, time.Minute)
+		b := NewRateTracker(2, time.Minute)
+
+		a.TouchAt("x", base)
+		a.TouchAt("x", base)
+		a.TouchAt("y", base)
+		for range 5 {
+			b.TouchAt("z", base)
+		}
+
+		a.MergeAt(b, base)
+		assert.Equal(t, 2, a.Size())
+
+		// z: 5 from b plus a's min (1) as error
+		rate, errorRate := a.RateAt("z", base)
+		assert.InDelta(t, 6, rate, 1e-9)
+		assert.InDelta(t, 1, errorRate, 1e-9)
+
+		top := a.TopAt(2, base)
+		assert.Equal(t, "z", top[0].Value)
+		assert.Equal(t, "x", top[1].Value)
+	})
+
+	t.Run("merge nil and self", func(t *testing.T) {
+		a := NewRateTracker(10, time.Minute)
+		a.TouchAt("x", base)
+		a.Merge(nil)
+		a.Merge(a)
+		assert.Equal(t, 1, a.Size())
+	})
+}
+
+func BenchmarkSpaceSaving_Merge(b *testing.B) {
+	a := New[string](1000)
+	other := New[string](1000)
+	for i := range 5000 {
+		a.Add(fmt.Sprintf("a%d", i%1500))
+		other.Add(fmt.Sprintf("b%d", i%1500))
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		a.Merge(other)
+	}
+}
@@ -3,10 +3,11 @@ package spacesaving
 import (
 	"bytes"
 	"encoding/gob"
-	"math"
 	"sort"
 	"sync"
 	"time"
+
+	"github.com/vitalvas/gokit/ewma"
 )
 
 // RateTracker implements Space-Saving with exponential decay for tracking rates.
@@ -24,12 +25,13 @@ import (
 //   - Thread-safe: All operations are protected by mutex
 //   - Constant memory: O(k) where k is the number of counters
 type RateTracker struct {
-	mu        sync.RWMutex
-	counters  map[string]*rateCounter
-	minHeap   *rateMinHeap
-	capacity  int
-	halfLife  time.Duration
-	decayRate float64
+	mu       sync.RWMutex
+	counters map[string]*rateCounter
+	minHeap  *rateMinHeap
+	capacity int
+	decayer  ewma.Decay
+	clock    ewma.Clock
+	subs     []*Subscription
 }
 
 // rateCounter represents a rate counter with exponential decay.
@@ -53,39 +55,60 @@ type rateCounter struct {
 //   - Request monitoring: capacity=500, halfLife=60s
 //   - Network analysis: capacity=1000, halfLife=5m
 func NewRateTracker(capacity int, halfLife time.Duration) *RateTracker {
+	return NewRateTrackerWithClock(capacity, halfLife, ewma.SystemClock)
+}
+
+// NewRateTrackerWithClock creates a RateTracker that reads the current time
+// from clock. Methods without an explicit time use clock.Now().
+// If clock is nil, ewma.SystemClock is used.
+func NewRateTrackerWithClock(capacity int, halfLife time.Duration, clock ewma.Clock) *RateTracker {
 	if capacity <= 0 {
 		capacity = 100
 	}
-	if halfLife <= 0 {
-		halfLife = 60 * time.Second
+	if clock == nil {
+		clock = ewma.SystemClock
 	}
 
 	return &RateTracker{
-		counters:  make(map[string]*rateCounter, capacity),
-		minHeap:   newRateMinHeap(capacity),
-		capacity:  capacity,
-		halfLife:  halfLife,
-		decayRate: math.Ln2 / halfLife.Seconds(),
+		counters: make(map[string]*rateCounter, capacity),
+		minHeap:  newRateMinHeap(capacity),
+		capacity: capacity,
+		decayer:  ewma.NewDecay(halfLife),
+		clock:    clock,
 	}
 }
 
 // Touch records an event for the item at the current time.
 // Returns the current rate (events per second) after the update.
 func (rt *RateTracker) Touch(item string) float64 {
-	return rt.TouchAt(item, time.Now())
+	return rt.TouchAt(item, rt.clock.Now())
 }
 
 // TouchAt records an event for the item at the specified time.
 // Useful for processing historical data or testing.
 func (rt *RateTracker) TouchAt(item string, t time.Time) float64 {
 	rt.mu.Lock()
-	defer rt.mu.Unlock()
+	rate, evicted, ok := rt.touch(item, t)
+	var pending []notification
+	if len(rt.subs) > 0 {
+		pending = rt.notifyTouch(item, evicted, ok, t)
+	}
+	rt.mu.Unlock()
+
+	dispatch(pending)
 
+	return rate
+}
+
+// touch records an event for the item. Returns the new rate and, if a
+// counter was replaced, the evicted item with its rate at t.
+// Caller must hold rt.mu.
+func (rt *RateTracker) touch(item string, t time.Time) (float64, RateItem, bool) {
 	// If item already tracked, update its rate
 	if rc, exists := rt.counters[item]; exists {
 		rt.updateRate(rc, t, 1.0)
 		rt.minHeap.fix(rc.index)
-		return rc.Rate
+		return rc.Rate, RateItem{}, false
 	}
 
 	// If we haven't reached capacity, add new counter
@@ -98,7 +121,7 @@ func (rt *RateTracker) TouchAt(item string, t time.Time) float64 {
 		}
 		rt.counters[item] = rc
 		rt.minHeap.push(rc)
-		return 1.0
+		return 1.0, RateItem{}, false
 	}
 
 	// Replace minimum counter
@@ -106,6 +129,7 @@ func (rt *RateTracker) TouchAt(item string, t time.Time) float64 {
 	rt.decay(minCounter, t)
 
 	delete(rt.counters, minCounter.Item)
+	evicted := RateItem{Value: minCounter.Item, Rate: minCounter.Rate, ErrorRate: minCounter.ErrorRate}
 
 	minCounter.Item = item
 	minCounter.ErrorRate = minCounter.Rate
@@ -115,7 +139,7 @@ func (rt *RateTracker) TouchAt(item string, t time.Time) float64 {
 	rt.counters[item] = minCounter
 	rt.minHeap.fix(0)
 
-	return minCounter.Rate
+	return minCounter.Rate, evicted, true
 }
 
 // updateRate applies exponential decay and adds the new event.
@@ -131,20 +155,26 @@ func (rt *RateTracker) decay(rc *rateCounter, t time.Time) {
 		return
 	}
 
-	elapsed := t.Sub(rc.LastUpdate).Seconds()
-	if elapsed <= 0 {
-		return
-	}
-
-	decayFactor := math.Exp(-rt.decayRate * elapsed)
+	decayFactor := rt.decayer.Factor(t.Sub(rc.LastUpdate))
 	rc.Rate *= decayFactor
 	rc.ErrorRate *= decayFactor
 }
 
+// rateAt returns the counter's rate decayed to time t.
+// A nil counter has zero rate.
+func (rt *RateTracker) rateAt(rc *rateCounter, t time.Time) (rate float64, errorRate float64) {
+	if rc == nil {
+		return 0, 0
+	}
+
+	decayFactor := rt.decayer.Factor(t.Sub(rc.LastUpdate))
+	return rc.Rate * decayFactor, rc.ErrorRate * decayFactor
+}
+
 // Rate returns the current rate for the item.
 // If the item is not being tracked, returns an estimate based on the minimum rate.
 func (rt *RateTracker) Rate(item string) (rate float64, errorRate float64) {
-	return rt.RateAt(item, time.Now())
+	return rt.RateAt(item, rt.clock.Now())
 }
 
 // RateAt returns the rate for the item at the specified time.
@@ -154,27 +184,13 @@ func (rt *RateTracker) RateAt(item string, t time.Time) (rate float64, errorRate
 
 	if rc, exists := rt.counters[item]; exists {
 		// Apply decay to get current rate
-		elapsed := t.Sub(rc.LastUpdate).Seconds()
-		if elapsed > 0 {
-			decayFactor := math.Exp(-rt.decayRate * elapsed)
-			rate = rc.Rate * decayFactor
-			errorRate = rc.ErrorRate * decayFactor
-		} else {
-			rate = rc.Rate
-			errorRate = rc.ErrorRate
-		}
-		return rate, errorRate
+		return rt.rateAt(rc, t)
 	}
 
 	// Item not tracked
 	if rt.minHeap.size > 0 {
-		minCounter := rt.minHeap.min()
-		elapsed := t.Sub(minCounter.LastUpdate).Seconds()
-		if elapsed > 0 {
-			decayFactor := math.Exp(-rt.decayRate * elapsed)
-			return 0, minCounter.Rate * decayFactor
-		}
-		return 0, minCounter.Rate
+		minRate, _ := rt.rateAt(rt.minHeap.min(), t)
+		return 0, minRate
 	}
 
 	return 0, 0
@@ -189,7 +205,7 @@ type RateItem struct {
 
 // Top returns the top n items by rate at the current time.
 func (rt *RateTracker) Top(n int) []RateItem {
-	return rt.TopAt(n, time.Now())
+	return rt.TopAt(n, rt.clock.Now())
 }
 
 // TopAt returns the top n items by rate at the specified time.
@@ -204,16 +220,7 @@ func (rt *RateTracker) TopAt(n int, t time.Time) []RateItem {
 	// Get all items with current rates
 	items := make([]RateItem, 0, len(rt.counters))
 	for _, rc := range rt.counters {
-		elapsed := t.Sub(rc.LastUpdate).Seconds()
-		var rate, errorRate float64
-		if elapsed > 0 {
-			decayFactor := math.Exp(-rt.decayRate * elapsed)
-			rate = rc.Rate * decayFactor
-			errorRate = rc.ErrorRate * decayFactor
-		} else {
-			rate = rc.Rate
-			errorRate = rc.ErrorRate
-		}
+		rate, errorRate := rt.rateAt(rc, t)
 
 		items = append(items, RateItem{
 			Value:     rc.Item,
@@ -261,16 +268,19 @@ func (rt *RateTracker) Capacity() int {
 
 // HalfLife returns the decay half-life.
 func (rt *RateTracker) HalfLife() time.Duration {
-	return rt.halfLife
+	return rt.decayer.HalfLife()
 }
 
 // Reset clears all counters.
+// Subscribers receive leave and below-threshold events for all tracked items.
 func (rt *RateTracker) Reset() {
 	rt.mu.Lock()
-	defer rt.mu.Unlock()
-
 	rt.counters = make(map[string]*rateCounter, rt.capacity)
 	rt.minHeap = newRateMinHeap(rt.capacity)
+	pending := rt.resync(rt.clock.Now())
+	rt.mu.Unlock()
+
+	dispatch(pending)
 }
 
 // Export serializes the RateTracker structure.
@@ -278,21 +288,12 @@ func (rt *RateTracker) Export() ([]byte, error) {
 	rt.mu.RLock()
 	defer rt.mu.RUnlock()
 
-	now := time.Now()
+	now := rt.clock.Now()
 
 	// Convert to exportable format
 	items := make([]RateItem, 0, len(rt.counters))
 	for _, rc := range rt.counters {
-		elapsed := now.Sub(rc.LastUpdate).Seconds()
-		var rate, errorRate float64
-		if elapsed > 0 {
-			decayFactor := math.Exp(-rt.decayRate * elapsed)
-			rate = rc.Rate * decayFactor
-			errorRate = rc.ErrorRate * decayFactor
-		} else {
-			rate = rc.Rate
-			errorRate = rc.ErrorRate
-		}
+		rate, errorRate := rt.rateAt(rc, now)
 
 		items = append(items, RateItem{
 			Value:     rc.Item,
@@ -307,7 +308,7 @@ func (rt *RateTracker) Export() ([]byte, error) {
 		Items    []RateItem
 	}{
 		Capacity: rt.capacity,
-		HalfLife: rt.halfLife,
+		HalfLife: rt.decayer.HalfLife(),
 		Items:    items,
 	}
 
@@ -335,7 +336,7 @@ func ImportRateTracker(data []byte) (*RateTracker, error) {
 	}
 
 	rt := NewRateTracker(importData.Capacity, importData.HalfLife)
-	now := time.Now()
+	now := rt.clock.Now()
 
 	for _, item := range importData.Items {
 		rc := &rateCounter{
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vitalvas/gokit/ewma"
 )
 
 func TestNewRateTracker(t *testing.T) {
@@ -29,6 +30,27 @@ func TestNewRateTracker(t *testing.T) {
 	})
 }
 
+func TestRateTrackerClock(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	clock := ewma.NewManualClock(base)
+	rt := NewRateTrackerWithClock(10, 10*time.Second, clock)
+
+	rt.Touch("a")
+	rt.Touch("a")
+
+	clock.Advance(10 * time.Second)
+	rate, _ := rt.Rate("a")
+	assert.InDelta(t, 1.0, rate, 1e-9)
+
+	top := rt.Top(1)
+	assert.InDelta(t, 1.0, top[0].Rate, 1e-9)
+
+	t.Run("nil clock uses system clock", func(t *testing.T) {
+		rt := NewRateTrackerWithClock(10, time.Minute, nil)
+		assert.InDelta(t, 1.0, rt.Touch("a"), 1e-9)
+	})
+}
+
 func TestRateTrackerTouch(t *testing.T) {
 	t.Run("touch single item", func(t *testing.T) {
 		rt := NewRateTracker(10, 60*time.Second)
@@ -0,0 +1,219 @@
+package spacesaving
+
+import (
+	"hash/maphash"
+	"runtime"
+)
+
+// BatchAdder accepts pre-aggregated counts.
+// Implemented by SpaceSaving and Sharded.
+type BatchAdder[K comparable] interface {
+	AddBatch(items map[K]uint64)
+}
+
+// Sharded is a Space-Saving summary partitioned by key hash.
+// Each key always maps to the same shard, so shards summarize disjoint
+// sub-streams and writers to different keys rarely contend on one lock.
+// Top merges the per-shard results on read.
+//
+// Every shard has the full capacity c, so a shard that received N_shard of
+// the N events overestimates by at most N_shard/c <= N/c. This keeps the
+// guarantee of an unsharded summary of capacity c however keys are spread:
+// every item with more than N/c occurrences is tracked. Memory grows to
+// c counters per shard in exchange.
+type Sharded[K comparable] struct {
+	seed     maphash.Seed
+	shards   []*SpaceSaving[K]
+	capacity int
+}
+
+// NewSharded creates a sharded summary whose shards each track capacity
+// items. If shards is not positive, runtime.GOMAXPROCS(0) shards are used.
+func NewSharded[K comparable](capacity, shards int) *Sharded[K] {
+	if capacity <= 0 {
+		capacity = 100
+	}
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	s := &Sharded[K]{
+		seed:     maphash.MakeSeed(),
+		shards:   make([]*SpaceSaving[K], shards),
+		capacity: capacity * shards,
+	}
+
+	for i := range s.shards {
+		s.shards[i] = New[K](capacity)
+	}
+
+	return s
+}
+
+// Add records an occurrence of the item.
+// Returns the approximate count after the addition.
+func (s *Sharded[K]) Add(item K) uint64 {
+	return s.shard(item).AddN(item, 1)
+}
+
+// AddN records weight occurrences of the item.
+// Returns the approximate count after the addition.
+func (s *Sharded[K]) AddN(item K, weight uint64) uint64 {
+	return s.shard(item).AddN(item, weight)
+}
+
+// AddBatch records pre-aggregated counts, taking each shard lock once.
+func (s *Sharded[K]) AddBatch(items map[K]uint64) {
+	if len(s.shards) == 1 {
+		s.shards[0].AddBatch(items)
+		return
+	}
+
+	parts := make([]map[K]uint64, len(s.shards))
+	for item, weight := range items {
+		idx := s.index(item)
+		if parts[idx] == nil {
+			parts[idx] = make(map[K]uint64)
+		}
+		parts[idx][item] += weight
+	}
+
+	for i, part := range parts {
+		if part != nil {
+			s.shards[i].AddBatch(part)
+		}
+	}
+}
+
+// Count returns the approximate count and error bound for the item.
+func (s *Sharded[K]) Count(item K) (count uint64, err uint64) {
+	return s.shard(item).Count(item)
+}
+
+// Top returns the top n most frequent items across all shards.
+// Items are sorted by count in descending order.
+func (s *Sharded[K]) Top(n int) []Item[K] {
+	if n <= 0 {
+		return nil
+	}
+
+	var items []Item[K]
+	for _, shard := range s.shards {
+		items = append(items, shard.Top(n)...)
+	}
+
+	sortItems(items)
+
+	if n > len(items) {
+		n = len(items)
+	}
+
+	return items[:n]
+}
+
+// All returns all tracked items sorted by frequency (descending).
+func (s *Sharded[K]) All() []Item[K] {
+	return s.Top(s.capacity)
+}
+
+// Size returns the number of items currently being tracked.
+func (s *Sharded[K]) Size() int {
+	size := 0
+	for _, shard := range s.shards {
+		size += shard.Size()
+	}
+	return size
+}
+
+// Capacity returns the maximum number of items that can be tracked across
+// all shards.
+func (s *Sharded[K]) Capacity() int {
+	return s.capacity
+}
+
+// Shards returns the number of shards.
+func (s *Sharded[K]) Shards() int {
+	return len(s.shards)
+}
+
+// Reset clears all shards.
+func (s *Sharded[K]) Reset() {
+	for _, shard := range s.shards {
+		shard.Reset()
+	}
+}
+
+// Snapshot merges all shards into a single summary with the total capacity.
+// Shards hold disjoint keys, so every counter is kept with its error bound.
+// Useful for Export or for merging with summaries from other hosts.
+func (s *Sharded[K]) Snapshot() *SpaceSaving[K] {
+	ss := New[K](s.capacity)
+	for _, shard := range s.shards {
+		ss.Merge(shard)
+	}
+	return ss
+}
+
+func (s *Sharded[K]) index(item K) int {
+	return int(maphash.Comparable(s.seed, item) % uint64(len(s.shards)))
+}
+
+func (s *Sharded[K]) shard(item K) *SpaceSaving[K] {
+	return s.shards[s.index(item)]
+}
+
+// Buffer pre-aggregates counts locally and flushes them to a shared
+// summary in batches, so hot keys cost one map increment instead of a
+// lock and heap update per event.
+//
+// A Buffer is not safe for concurrent use; give each goroutine its own.
+// Pre-aggregation only reorders the stream, so the target's error
+// guarantees are unchanged; counts are visible after Flush.
+type Buffer[K comparable] struct {
+	target BatchAdder[K]
+	items  map[K]uint64
+	size   int
+}
+
+// NewBuffer creates a buffer that flushes to target when it holds size
+// distinct keys. If size is not positive, 1024 is used.
+func NewBuffer[K comparable](target BatchAdder[K], size int) *Buffer[K] {
+	if size <= 0 {
+		size = 1024
+	}
+
+	return &Buffer[K]{
+		target: target,
+		items:  make(map[K]uint64, size),
+		size:   size,
+	}
+}
+
+// Add records an occurrence of the item.
+func (b *Buffer[K]) Add(item K) {
+	b.AddN(item, 1)
+}
+
+// AddN records weight occurrences of the item.
+func (b *Buffer[K]) AddN(item K, weight uint64) {
+	b.items[item] += weight
+
+	if len(b.items) >= b.size {
+		b.Flush()
+	}
+}
+
+// Flush sends buffered counts to the target.
+func (b *Buffer[K]) Flush() {
+	if len(b.items) == 0 {
+		return
+	}
+
+	b.target.AddBatch(b.items)
+	clear(b.items)
+}
+
+// Len returns the number of distinct buffered keys.
+func (b *Buffer[K]) Len() int {
+	return len(b.items)
+}
@@ -0,0 +1,266 @@
+package spacesaving
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddBatch(t *testing.T) {
+	ss := New[string](10)
+	ss.Add("a")
+	ss.AddBatch(map[string]uint64{"a": 4, "b": 2, "c": 0})
+
+	count, _ := ss.Count("a")
+	assert.Equal(t, uint64(5), count)
+	count, _ = ss.Count("b")
+	assert.Equal(t, uint64(2), count)
+	assert.Equal(t, 2, ss.Size())
+}
+
+func TestNewSharded(t *testing.T) {
+	t.Run("every shard has the full capacity", func(t *testing.T) {
+		s := NewSharded[string](100, 8)
+		assert.Equal(t, 8, s.Shards())
+		assert.Equal(t, 800, s.Capacity())
+		assert.Equal(t, 100, s.shards[0].Capacity())
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		s := NewSharded[string](0, 0)
+		assert.Equal(t, runtime.GOMAXPROCS(0), s.Shards())
+		assert.GreaterOrEqual(t, s.Capacity(), 100)
+	})
+}
+
+func TestSharded(t *testing.T) {
+	t.Run("keys map to a single shard", func(t *testing.T) {
+		s := NewSharded[string](100, 4)
+		for range 10 {
+			s.Add("x")
+		}
+		s.AddN("x", 5)
+
+		count, errBound := s.Count("x")
+		assert.Equal(t, uint64(15), count)
+		assert.Equal(t, uint64(0), errBound)
+		assert.Equal(t, 1, s.Size())
+	})
+
+	t.Run("top merges shards", func(t *testing.T) {
+		s := NewSharded[string](100, 4)
+		for i := range 20 {
+			s.AddN(fmt.Sprintf("k%02d", i), uint64(i+1))
+		}
+
+		top := s.Top(3)
+		require.Len(t, top, 3)
+		assert.Equal(t, "k19", top[0].Value)
+		assert.Equal(t, "k18", top[1].Value)
+		assert.Equal(t, "k17", top[2].Value)
+		assert.Len(t, s.All(), 20)
+		assert.Nil(t, s.Top(0))
+	})
+
+	t.Run("heavy hitters match unsharded summary", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(5))
+		zipf := rand.NewZipf(rng, 1.3, 1, 10000)
+
+		exact := make(map[uint64]uint64)
+		s := NewSharded[uint64](200, 8)
+		for range 100000 {
+			k := zipf.Uint64()
+			exact[k]++
+			s.Add(k)
+		}
+
+		for _, item := range s.Top(10) {
+			assert.GreaterOrEqual(t, item.Count, exact[item.Value])
+			assert.LessOrEqual(t, item.Count-item.Error, exact[item.Value])
+		}
+		assert.Equal(t, uint64(0), s.Top(1)[0].Value)
+	})
+
+	t.Run("error bound holds when keys cluster in one shard", func(t *testing.T) {
+		const capacity = 10
+		s := NewSharded[string](capacity, 4)
+
+		// Route all traffic to shard 0: one heavy key and a long tail
+		var keys []string
+		for i := 0; len(keys) < 1001; i++ {
+			if k := fmt.Sprintf("k%d", i); s.index(k) == 0 {
+				keys = append(keys, k)
+			}
+		}
+
+		heavy, tail := keys[0], keys[1:]
+		var total uint64
+		for i, k := range tail {
+			s.Add(k)
+			total++
+			if i%5 == 0 {
+				s.Add(heavy)
+				total++
+			}
+		}
+
+		bound := total / capacity
+		count, errBound := s.Count(heavy)
+		assert.GreaterOrEqual(t, count, uint64(200))
+		assert.LessOrEqual(t, errBound, bound)
+		assert.Equal(t, heavy, s.Top(1)[0].Value)
+
+		for _, item := range s.All() {
+			assert.LessOrEqual(t, item.Error, bound)
+		}
+	})
+
+	t.Run("batch is partitioned", func(t *testing.T) {
+		s := NewSharded[string](100, 4)
+		s.AddBatch(map[string]uint64{"a": 1, "b": 2, "c": 3})
+
+		for item, expected := range map[string]uint64{"a": 1, "b": 2, "c": 3} {
+			count, _ := s.Count(item)
+			assert.Equal(t, expected, count)
+		}
+	})
+
+	t.Run("single shard batch", func(t *testing.T) {
+		s := NewSharded[string](10, 1)
+		s.AddBatch(map[string]uint64{"a": 3})
+		count, _ := s.Count("a")
+		assert.Equal(t, uint64(3), count)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		s := NewSharded[string](100, 4)
+		s.AddN("a", 10)
+		s.AddN("b", 5)
+
+		snap := s.Snapshot()
+		assert.Equal(t, s.Capacity(), snap.Capacity())
+		count, _ := snap.Count("a")
+		assert.Equal(t, uint64(10), count)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		s := NewSharded[string](100, 4)
+		s.Add("a")
+		s.Reset()
+		assert.Equal(t, 0, s.Size())
+	})
+
+	t.Run("concurrent add", func(t *testing.T) {
+		s := NewSharded[string](100, 4)
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 1000 {
+					s.Add(fmt.Sprintf("k%d", i%10))
+				}
+			}()
+		}
+		wg.Wait()
+
+		for _, item := range s.All() {
+			assert.Equal(t, uint64(800), item.Count)
+		}
+	})
+}
+
+func TestBuffer(t *testing.T) {
+	t.Run("flushes on size", func(t *testing.T) {
+		ss := New[string](10)
+		b := NewBuffer[string](ss, 2)
+
+		b.Add("a")
+		b.Add("a")
+		assert.Equal(t, 1, b.Len())
+		assert.Equal(t, 0, ss.Size())
+
+		b.AddN("b", 3)
+		assert.Equal(t, 0, b.Len())
+
+		count, _ := ss.Count("a")
+		assert.Equal(t, uint64(2), count)
+		count, _ = ss.Count("b")
+		assert.Equal(t, uint64(3), count)
+	})
+
+	t.Run("explicit flush", func(t *testing.T) {
+		ss := New[string](10)
+		b := NewBuffer[string](ss, 0)
+		assert.Equal(t, 1024, b.size)
+
+		b.Add("a")
+		b.Flush()
+		b.Flush()
+
+		count, _ := ss.Count("a")
+		assert.Equal(t, uint64(1), count)
+	})
+
+	t.Run("per-goroutine buffers into sharded summary", func(t *testing.T) {
+		s := NewSharded[int](100, 4)
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b := NewBuffer[int](s, 16)
+				for i := range 10000 {
+					b.Add(i % 5)
+				}
+				b.Flush()
+			}()
+		}
+		wg.Wait()
+
+		for _, item := range s.All() {
+			assert.Equal(t, uint64(16000), item.Count)
+		}
+	})
+}
+
+func BenchmarkSharded_ConcurrentAdd(b *testing.B) {
+	s := NewSharded[string](1000, 0)
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			s.Add(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkBuffer_ConcurrentAdd(b *testing.B) {
+	ss := New[string](1000)
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		buf := NewBuffer[string](ss, 256)
+		i := 0
+		for pb.Next() {
+			buf.Add(keys[i%len(keys)])
+			i++
+		}
+		buf.Flush()
+	})
+}
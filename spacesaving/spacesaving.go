@@ -2,8 +2,10 @@ package spacesaving
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/gob"
-	"sort"
+	"fmt"
+	"slices"
 	"sync"
 )
 
@@ -20,17 +22,18 @@ import (
 // Properties:
 //   - Constant memory: O(k) where k is the number of counters
 //   - Guaranteed error bound: count is overestimated by at most the count of the evicted item
+//   - Generic keys: any comparable type (string, netip.Addr, structs)
 //   - Thread-safe: All operations are protected by mutex
-type SpaceSaving struct {
+type SpaceSaving[K comparable] struct {
 	mu       sync.RWMutex
-	counters map[string]*counter
-	minHeap  *minHeap
+	counters map[K]*counter[K]
+	minHeap  *minHeap[K]
 	capacity int
 }
 
 // counter represents a frequency counter for an item.
-type counter struct {
-	Item  string
+type counter[K comparable] struct {
+	Item  K
 	Count uint64
 	Error uint64 // Maximum overestimation
 	index int    // Index in the min-heap
@@ -43,41 +46,70 @@ type counter struct {
 //   - Small streams: 100-1000 counters
 //   - Medium streams: 1000-10000 counters
 //   - Large streams: 10000+ counters
-func New(capacity int) *SpaceSaving {
+func New[K comparable](capacity int) *SpaceSaving[K] {
 	if capacity <= 0 {
 		capacity = 100
 	}
 
-	return &SpaceSaving{
-		counters: make(map[string]*counter, capacity),
-		minHeap:  newMinHeap(capacity),
+	return &SpaceSaving[K]{
+		counters: make(map[K]*counter[K], capacity),
+		minHeap:  newMinHeap[K](capacity),
 		capacity: capacity,
 	}
 }
 
 // Add records an occurrence of the item.
 // Returns the approximate count after the addition.
-func (ss *SpaceSaving) Add(item string) uint64 {
+func (ss *SpaceSaving[K]) Add(item K) uint64 {
+	return ss.AddN(item, 1)
+}
+
+// AddN records weight occurrences of the item, e.g. bytes transferred.
+// Returns the approximate count after the addition.
+//
+// The error guarantee of Count is preserved: an evicted counter's count
+// becomes the new item's error, regardless of weight.
+func (ss *SpaceSaving[K]) AddN(item K, weight uint64) uint64 {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	return ss.addN(item, weight)
+}
+
+// AddBatch records pre-aggregated counts under a single lock acquisition.
+// Equivalent to calling AddN for each entry.
+func (ss *SpaceSaving[K]) AddBatch(items map[K]uint64) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
+	for item, weight := range items {
+		ss.addN(item, weight)
+	}
+}
+
+// addN records weight occurrences of the item. Caller must hold ss.mu.
+func (ss *SpaceSaving[K]) addN(item K, weight uint64) uint64 {
 	// If item already tracked, increment its counter
 	if c, exists := ss.counters[item]; exists {
-		c.Count++
+		c.Count += weight
 		ss.minHeap.fix(c.index)
 		return c.Count
 	}
 
+	if weight == 0 {
+		return 0
+	}
+
 	// If we haven't reached capacity, add new counter
 	if len(ss.counters) < ss.capacity {
-		c := &counter{
+		c := &counter[K]{
 			Item:  item,
-			Count: 1,
+			Count: weight,
 			Error: 0,
 		}
 		ss.counters[item] = c
 		ss.minHeap.push(c)
-		return 1
+		return weight
 	}
 
 	// Replace minimum counter
@@ -86,7 +118,7 @@ func (ss *SpaceSaving) Add(item string) uint64 {
 
 	minCounter.Item = item
 	minCounter.Error = minCounter.Count
-	minCounter.Count++
+	minCounter.Count += weight
 
 	ss.counters[item] = minCounter
 	ss.minHeap.fix(0)
@@ -98,7 +130,7 @@ func (ss *SpaceSaving) Add(item string) uint64 {
 // If the item is not being tracked, returns 0.
 //
 // Note: The count may be overestimated by at most the Error value.
-func (ss *SpaceSaving) Count(item string) (count uint64, err uint64) {
+func (ss *SpaceSaving[K]) Count(item K) (count uint64, err uint64) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
@@ -116,8 +148,8 @@ func (ss *SpaceSaving) Count(item string) (count uint64, err uint64) {
 }
 
 // Item represents an item with its frequency information.
-type Item struct {
-	Value string
+type Item[K comparable] struct {
+	Value K
 	Count uint64
 	Error uint64
 }
@@ -125,7 +157,7 @@ type Item struct {
 // Top returns the top n most frequent items.
 // If n is greater than the number of tracked items, returns all tracked items.
 // Items are sorted by count in descending order.
-func (ss *SpaceSaving) Top(n int) []Item {
+func (ss *SpaceSaving[K]) Top(n int) []Item[K] {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
@@ -134,22 +166,16 @@ func (ss *SpaceSaving) Top(n int) []Item {
 	}
 
 	// Get all items
-	items := make([]Item, 0, len(ss.counters))
+	items := make([]Item[K], 0, len(ss.counters))
 	for _, c := range ss.counters {
-		items = append(items, Item{
+		items = append(items, Item[K]{
 			Value: c.Item,
 			Count: c.Count,
 			Error: c.Error,
 		})
 	}
 
-	// Sort by count descending
-	sort.Slice(items, func(i, j int) bool {
-		if items[i].Count == items[j].Count {
-			return items[i].Value < items[j].Value // Stable sort
-		}
-		return items[i].Count > items[j].Count
-	})
+	sortItems(items)
 
 	// Return top n
 	if n > len(items) {
@@ -160,50 +186,58 @@ func (ss *SpaceSaving) Top(n int) []Item {
 }
 
 // All returns all tracked items sorted by frequency (descending).
-func (ss *SpaceSaving) All() []Item {
+func (ss *SpaceSaving[K]) All() []Item[K] {
 	return ss.Top(ss.capacity)
 }
 
 // Size returns the number of items currently being tracked.
-func (ss *SpaceSaving) Size() int {
+func (ss *SpaceSaving[K]) Size() int {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 	return len(ss.counters)
 }
 
 // Capacity returns the maximum number of items that can be tracked.
-func (ss *SpaceSaving) Capacity() int {
+func (ss *SpaceSaving[K]) Capacity() int {
 	return ss.capacity
 }
 
 // Reset clears all counters.
-func (ss *SpaceSaving) Reset() {
+func (ss *SpaceSaving[K]) Reset() {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	ss.counters = make(map[string]*counter, ss.capacity)
-	ss.minHeap = newMinHeap(ss.capacity)
+	ss.counters = make(map[K]*counter[K], ss.capacity)
+	ss.minHeap = newMinHeap[K](ss.capacity)
 }
 
 // Export serializes the SpaceSaving structure.
-func (ss *SpaceSaving) Export() ([]byte, error) {
+// String keys are stored as-is; other key types are encoded with GobCodec.
+func (ss *SpaceSaving[K]) Export() ([]byte, error) {
+	return ss.ExportWithCodec(defaultCodec[K]())
+}
+
+// ExportWithCodec serializes the SpaceSaving structure, encoding keys with codec.
+func (ss *SpaceSaving[K]) ExportWithCodec(codec Codec[K]) ([]byte, error) {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
 
 	// Convert to exportable format
-	items := make([]Item, 0, len(ss.counters))
+	items := make([]exportItem, 0, len(ss.counters))
 	for _, c := range ss.counters {
-		items = append(items, Item{
-			Value: c.Item,
+		key, err := codec.Encode(c.Item)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, exportItem{
+			Key:   key,
 			Count: c.Count,
 			Error: c.Error,
 		})
 	}
 
-	data := struct {
-		Capacity int
-		Items    []Item
-	}{
+	data := exportData{
 		Capacity: ss.capacity,
 		Items:    items,
 	}
@@ -217,12 +251,14 @@ func (ss *SpaceSaving) Export() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// Import deserializes a SpaceSaving structure.
-func Import(data []byte) (*SpaceSaving, error) {
-	var importData struct {
-		Capacity int
-		Items    []Item
-	}
+// Import deserializes a SpaceSaving structure exported with Export.
+func Import[K comparable](data []byte) (*SpaceSaving[K], error) {
+	return ImportWithCodec(data, defaultCodec[K]())
+}
+
+// ImportWithCodec deserializes a SpaceSaving structure, decoding keys with codec.
+func ImportWithCodec[K comparable](data []byte, codec Codec[K]) (*SpaceSaving[K], error) {
+	var importData exportData
 
 	buf := bytes.NewBuffer(data)
 	dec := gob.NewDecoder(buf)
@@ -230,55 +266,168 @@ func Import(data []byte) (*SpaceSaving, error) {
 		return nil, err
 	}
 
-	ss := New(importData.Capacity)
+	ss := New[K](importData.Capacity)
 
 	for _, item := range importData.Items {
-		c := &counter{
-			Item:  item.Value,
+		key := item.Key
+		if key == nil {
+			// Legacy format stored string keys in Value
+			key = []byte(item.Value)
+		}
+
+		value, err := codec.Decode(key)
+		if err != nil {
+			return nil, err
+		}
+
+		c := &counter[K]{
+			Item:  value,
 			Count: item.Count,
 			Error: item.Error,
 		}
-		ss.counters[item.Value] = c
+		ss.counters[value] = c
 		ss.minHeap.push(c)
 	}
 
 	return ss, nil
 }
 
+// exportData is used for gob encoding/decoding.
+type exportData struct {
+	Capacity int
+	Items    []exportItem
+}
+
+// exportItem is a counter with its key encoded by a Codec.
+type exportItem struct {
+	Key   []byte
+	Value string // Legacy string key, read only
+	Count uint64
+	Error uint64
+}
+
+// sortItems sorts items by count in descending order, breaking ties by key
+// so that Top is deterministic.
+func sortItems[K comparable](items []Item[K]) {
+	if len(items) < 2 {
+		return
+	}
+
+	if compare := keyCompare[K](); compare != nil {
+		slices.SortFunc(items, func(a, b Item[K]) int {
+			if c := cmp.Compare(b.Count, a.Count); c != 0 {
+				return c
+			}
+			return compare(a.Value, b.Value)
+		})
+		return
+	}
+
+	// Other keys are formatted once each instead of on every comparison
+	type keyed struct {
+		item Item[K]
+		key  string
+	}
+
+	sorted := make([]keyed, len(items))
+	for i, item := range items {
+		sorted[i] = keyed{item: item, key: fmt.Sprint(item.Value)}
+	}
+
+	slices.SortFunc(sorted, func(a, b keyed) int {
+		if c := cmp.Compare(b.item.Count, a.item.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.key, b.key)
+	})
+
+	for i := range sorted {
+		items[i] = sorted[i].item
+	}
+}
+
+// keyCompare returns a comparison function for keys of ordered kinds and
+// types with a Compare method, such as netip.Addr, or nil for other types.
+func keyCompare[K comparable]() func(a, b K) int {
+	var zero K
+
+	switch any(zero).(type) {
+	case string:
+		return compareAs[K, string]
+	case int:
+		return compareAs[K, int]
+	case int8:
+		return compareAs[K, int8]
+	case int16:
+		return compareAs[K, int16]
+	case int32:
+		return compareAs[K, int32]
+	case int64:
+		return compareAs[K, int64]
+	case uint:
+		return compareAs[K, uint]
+	case uint8:
+		return compareAs[K, uint8]
+	case uint16:
+		return compareAs[K, uint16]
+	case uint32:
+		return compareAs[K, uint32]
+	case uint64:
+		return compareAs[K, uint64]
+	case uintptr:
+		return compareAs[K, uintptr]
+	case float32:
+		return compareAs[K, float32]
+	case float64:
+		return compareAs[K, float64]
+	case interface{ Compare(K) int }:
+		return func(a, b K) int {
+			return any(a).(interface{ Compare(K) int }).Compare(b)
+		}
+	default:
+		return nil
+	}
+}
+
+// compareAs compares keys whose type is known to be T.
+func compareAs[K comparable, T cmp.Ordered](a, b K) int {
+	return cmp.Compare(any(a).(T), any(b).(T))
+}
+
 // minHeap implements a min-heap for counters.
-type minHeap struct {
-	items []*counter
+type minHeap[K comparable] struct {
+	items []*counter[K]
 	size  int
 }
 
-func newMinHeap(capacity int) *minHeap {
-	return &minHeap{
-		items: make([]*counter, 0, capacity),
+func newMinHeap[K comparable](capacity int) *minHeap[K] {
+	return &minHeap[K]{
+		items: make([]*counter[K], 0, capacity),
 		size:  0,
 	}
 }
 
-func (h *minHeap) push(c *counter) {
+func (h *minHeap[K]) push(c *counter[K]) {
 	c.index = h.size
 	h.items = append(h.items, c)
 	h.size++
 	h.up(c.index)
 }
 
-func (h *minHeap) min() *counter {
+func (h *minHeap[K]) min() *counter[K] {
 	if h.size == 0 {
 		return nil
 	}
 	return h.items[0]
 }
 
-func (h *minHeap) fix(i int) {
+func (h *minHeap[K]) fix(i int) {
 	if !h.down(i) {
 		h.up(i)
 	}
 }
 
-func (h *minHeap) up(i int) {
+func (h *minHeap[K]) up(i int) {
 	for {
 		parent := (i - 1) / 2
 		if parent == i || h.items[parent].Count <= h.items[i].Count {
@@ -289,7 +438,7 @@ func (h *minHeap) up(i int) {
 	}
 }
 
-func (h *minHeap) down(i int) bool {
+func (h *minHeap[K]) down(i int) bool {
 	i0 := i
 	for {
 		left := 2*i + 1
@@ -313,7 +462,7 @@ func (h *minHeap) down(i int) bool {
 	return i > i0
 }
 
-func (h *minHeap) swap(i, j int) {
+func (h *minHeap[K]) swap(i, j int) {
 	h.items[i], h.items[j] = h.items[j], h.items[i]
 	h.items[i].index = i
 	h.items[j].index = j
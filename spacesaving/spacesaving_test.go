@@ -1,30 +1,35 @@
 package spacesaving
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
 	"fmt"
+	"net/netip"
 	"sort"
 	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
 	t.Run("create with default capacity", func(t *testing.T) {
-		ss := New(100)
+		ss := New[string](100)
 		assert.NotNil(t, ss)
 		assert.Equal(t, 100, ss.Capacity())
 		assert.Equal(t, 0, ss.Size())
 	})
 
 	t.Run("create with zero capacity uses default", func(t *testing.T) {
-		ss := New(0)
+		ss := New[string](0)
 		assert.NotNil(t, ss)
 		assert.Equal(t, 100, ss.Capacity())
 	})
 
 	t.Run("create with negative capacity uses default", func(t *testing.T) {
-		ss := New(-10)
+		ss := New[string](-10)
 		assert.NotNil(t, ss)
 		assert.Equal(t, 100, ss.Capacity())
 	})
@@ -32,14 +37,14 @@ func TestNew(t *testing.T) {
 
 func TestAdd(t *testing.T) {
 	t.Run("add single item", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		count := ss.Add("apple")
 		assert.Equal(t, uint64(1), count)
 		assert.Equal(t, 1, ss.Size())
 	})
 
 	t.Run("add duplicate items", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("apple")
 		ss.Add("apple")
 		count := ss.Add("apple")
@@ -49,7 +54,7 @@ func TestAdd(t *testing.T) {
 	})
 
 	t.Run("add multiple different items", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("apple")
 		ss.Add("banana")
 		ss.Add("cherry")
@@ -58,7 +63,7 @@ func TestAdd(t *testing.T) {
 	})
 
 	t.Run("add beyond capacity", func(t *testing.T) {
-		ss := New(3)
+		ss := New[string](3)
 		ss.Add("a")
 		ss.Add("b")
 		ss.Add("c")
@@ -72,7 +77,7 @@ func TestAdd(t *testing.T) {
 	})
 
 	t.Run("eviction preserves high-frequency items", func(t *testing.T) {
-		ss := New(3)
+		ss := New[string](3)
 
 		// Add items with different frequencies
 		for i := 0; i < 10; i++ {
@@ -90,9 +95,147 @@ func TestAdd(t *testing.T) {
 	})
 }
 
+func TestAddN(t *testing.T) {
+	t.Run("weighted increments", func(t *testing.T) {
+		ss := New[string](10)
+		assert.Equal(t, uint64(100), ss.AddN("a", 100))
+		assert.Equal(t, uint64(150), ss.AddN("a", 50))
+		assert.Equal(t, uint64(151), ss.Add("a"))
+
+		count, errBound := ss.Count("a")
+		assert.Equal(t, uint64(151), count)
+		assert.Equal(t, uint64(0), errBound)
+	})
+
+	t.Run("zero weight does not track new item", func(t *testing.T) {
+		ss := New[string](10)
+		assert.Equal(t, uint64(0), ss.AddN("a", 0))
+		assert.Equal(t, 0, ss.Size())
+
+		ss.AddN("b", 5)
+		assert.Equal(t, uint64(5), ss.AddN("b", 0))
+	})
+
+	t.Run("eviction keeps error bound", func(t *testing.T) {
+		ss := New[string](2)
+		ss.AddN("a", 100)
+		ss.AddN("b", 10)
+
+		// Replaces b (min=10): count=10+500, error=10
+		assert.Equal(t, uint64(510), ss.AddN("c", 500))
+
+		count, errBound := ss.Count("c")
+		assert.Equal(t, uint64(510), count)
+		assert.Equal(t, uint64(10), errBound)
+		assert.GreaterOrEqual(t, count-errBound, uint64(500))
+	})
+
+	t.Run("weighted top matches exact totals", func(t *testing.T) {
+		ss := New[string](50)
+		exact := make(map[string]uint64)
+
+		for i := range 10000 {
+			key := fmt.Sprintf("k%d", i%20)
+			weight := uint64(i%20 + 1)
+			ss.AddN(key, weight)
+			exact[key] += weight
+		}
+
+		for _, item := range ss.All() {
+			assert.Equal(t, exact[item.Value], item.Count)
+		}
+		assert.Equal(t, "k19", ss.Top(1)[0].Value)
+	})
+}
+
+func TestGenericKeys(t *testing.T) {
+	t.Run("netip.Addr keys", func(t *testing.T) {
+		ss := New[netip.Addr](10)
+		a := netip.MustParseAddr("192.0.2.1")
+		b := netip.MustParseAddr("2001:db8::1")
+
+		ss.AddN(a, 1500)
+		ss.AddN(b, 9000)
+		ss.AddN(a, 1500)
+
+		top := ss.Top(2)
+		assert.Equal(t, b, top[0].Value)
+		assert.Equal(t, uint64(9000), top[0].Count)
+		assert.Equal(t, a, top[1].Value)
+		assert.Equal(t, uint64(3000), top[1].Count)
+	})
+
+	t.Run("struct keys", func(t *testing.T) {
+		type flow struct {
+			Src, Dst string
+			Port     uint16
+		}
+
+		ss := New[flow](10)
+		ss.Add(flow{"a", "b", 443})
+		ss.Add(flow{"a", "b", 443})
+		ss.Add(flow{"a", "c", 80})
+
+		count, _ := ss.Count(flow{"a", "b", 443})
+		assert.Equal(t, uint64(2), count)
+	})
+
+	t.Run("integer keys tie-break deterministically", func(t *testing.T) {
+		ss := New[int](10)
+		for _, k := range []int{3, 1, 2} {
+			ss.Add(k)
+		}
+
+		top := ss.Top(3)
+		assert.Equal(t, []int{1, 2, 3}, []int{top[0].Value, top[1].Value, top[2].Value})
+	})
+
+	t.Run("numeric keys tie-break by value, not text", func(t *testing.T) {
+		ss := New[int](10)
+		for _, k := range []int{10, 9, -1} {
+			ss.Add(k)
+		}
+
+		top := ss.Top(3)
+		assert.Equal(t, []int{-1, 9, 10}, []int{top[0].Value, top[1].Value, top[2].Value})
+	})
+
+	t.Run("addr keys tie-break with Compare", func(t *testing.T) {
+		ss := New[netip.Addr](10)
+		for _, k := range []string{"10.0.0.10", "10.0.0.9", "::1"} {
+			ss.Add(netip.MustParseAddr(k))
+		}
+
+		top := ss.Top(3)
+		assert.Equal(t, "10.0.0.9", top[0].Value.String())
+		assert.Equal(t, "10.0.0.10", top[1].Value.String())
+		assert.Equal(t, "::1", top[2].Value.String())
+	})
+
+	t.Run("struct keys tie-break deterministically", func(t *testing.T) {
+		type flow struct {
+			Src  string
+			Port uint16
+		}
+
+		ss := New[flow](10)
+		for _, k := range []flow{{"b", 80}, {"a", 443}, {"a", 80}} {
+			ss.Add(k)
+		}
+		ss.Add(flow{"c", 22})
+		ss.Add(flow{"c", 22})
+
+		expected := []flow{{"c", 22}, {"a", 443}, {"a", 80}, {"b", 80}}
+		for range 10 {
+			top := ss.Top(4)
+			assert.Equal(t, expected, []flow{top[0].Value, top[1].Value, top[2].Value, top[3].Value})
+		}
+	})
+}
+
 func TestCount(t *testing.T) {
 	t.Run("count tracked item", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("apple")
 		ss.Add("apple")
 		ss.Add("apple")
@@ -103,7 +246,7 @@ func TestCount(t *testing.T) {
 	})
 
 	t.Run("count non-tracked item", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("apple")
 
 		count, err := ss.Count("banana")
@@ -112,7 +255,7 @@ func TestCount(t *testing.T) {
 	})
 
 	t.Run("count after eviction has error bound", func(t *testing.T) {
-		ss := New(2)
+		ss := New[string](2)
 		ss.Add("a")
 		ss.Add("b")
 		ss.Add("c") // Evicts "a"
@@ -126,7 +269,7 @@ func TestCount(t *testing.T) {
 
 func TestTop(t *testing.T) {
 	t.Run("top items in order", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 
 		// Add items with known frequencies
 		for i := 0; i < 5; i++ {
@@ -148,7 +291,7 @@ func TestTop(t *testing.T) {
 	})
 
 	t.Run("top n greater than size", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("a")
 		ss.Add("b")
 
@@ -157,7 +300,7 @@ func TestTop(t *testing.T) {
 	})
 
 	t.Run("top with n=0", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("a")
 
 		top := ss.Top(0)
@@ -165,7 +308,7 @@ func TestTop(t *testing.T) {
 	})
 
 	t.Run("top with negative n", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("a")
 
 		top := ss.Top(-1)
@@ -173,7 +316,7 @@ func TestTop(t *testing.T) {
 	})
 
 	t.Run("top from empty tracker", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		top := ss.Top(5)
 		assert.Len(t, top, 0)
 	})
@@ -181,7 +324,7 @@ func TestTop(t *testing.T) {
 
 func TestAll(t *testing.T) {
 	t.Run("all returns all items", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("a")
 		ss.Add("b")
 		ss.Add("c")
@@ -191,7 +334,7 @@ func TestAll(t *testing.T) {
 	})
 
 	t.Run("all sorted by frequency", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		for i := 0; i < 5; i++ {
 			ss.Add("a")
 		}
@@ -207,7 +350,7 @@ func TestAll(t *testing.T) {
 
 func TestSize(t *testing.T) {
 	t.Run("size increases with additions", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		assert.Equal(t, 0, ss.Size())
 
 		ss.Add("a")
@@ -218,7 +361,7 @@ func TestSize(t *testing.T) {
 	})
 
 	t.Run("size capped at capacity", func(t *testing.T) {
-		ss := New(3)
+		ss := New[string](3)
 		ss.Add("a")
 		ss.Add("b")
 		ss.Add("c")
@@ -230,7 +373,7 @@ func TestSize(t *testing.T) {
 
 func TestReset(t *testing.T) {
 	t.Run("reset clears all data", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("a")
 		ss.Add("b")
 		ss.Add("c")
@@ -245,7 +388,7 @@ func TestReset(t *testing.T) {
 
 func TestExportImport(t *testing.T) {
 	t.Run("export and import", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		for i := 0; i < 5; i++ {
 			ss.Add("apple")
 		}
@@ -257,7 +400,7 @@ func TestExportImport(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NotEmpty(t, data)
 
-		imported, err := Import(data)
+		imported, err := Import[string](data)
 		assert.NoError(t, err)
 		assert.NotNil(t, imported)
 
@@ -272,24 +415,115 @@ func TestExportImport(t *testing.T) {
 	})
 
 	t.Run("export empty tracker", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		data, err := ss.Export()
 		assert.NoError(t, err)
 
-		imported, err := Import(data)
+		imported, err := Import[string](data)
 		assert.NoError(t, err)
 		assert.Equal(t, 0, imported.Size())
 	})
 
 	t.Run("import invalid data", func(t *testing.T) {
-		_, err := Import([]byte("invalid"))
+		_, err := Import[string]([]byte("invalid"))
+		assert.Error(t, err)
+	})
+
+	t.Run("non-string keys with default codec", func(t *testing.T) {
+		ss := New[netip.Addr](10)
+		addr := netip.MustParseAddr("198.51.100.7")
+		ss.AddN(addr, 42)
+
+		data, err := ss.Export()
+		require.NoError(t, err)
+
+		imported, err := Import[netip.Addr](data)
+		require.NoError(t, err)
+
+		count, _ := imported.Count(addr)
+		assert.Equal(t, uint64(42), count)
+	})
+
+	t.Run("custom codec", func(t *testing.T) {
+		ss := New[netip.Addr](10)
+		addr := netip.MustParseAddr("2001:db8::7")
+		ss.AddN(addr, 7)
+
+		data, err := ss.ExportWithCodec(addrCodec{})
+		require.NoError(t, err)
+
+		imported, err := ImportWithCodec[netip.Addr](data, addrCodec{})
+		require.NoError(t, err)
+
+		count, _ := imported.Count(addr)
+		assert.Equal(t, uint64(7), count)
+	})
+
+	t.Run("codec errors are returned", func(t *testing.T) {
+		ss := New[netip.Addr](10)
+		ss.Add(netip.MustParseAddr("192.0.2.1"))
+
+		_, err := ss.ExportWithCodec(failCodec{})
+		assert.Error(t, err)
+
+		data, err := ss.Export()
+		require.NoError(t, err)
+
+		_, err = ImportWithCodec[netip.Addr](data, failCodec{})
 		assert.Error(t, err)
 	})
+
+	t.Run("import legacy format", func(t *testing.T) {
+		legacy := struct {
+			Capacity int
+			Items    []struct {
+				Value string
+				Count uint64
+				Error uint64
+			}
+		}{Capacity: 5}
+		legacy.Items = append(legacy.Items, struct {
+			Value string
+			Count uint64
+			Error uint64
+		}{Value: "apple", Count: 9, Error: 1})
+
+		var buf bytes.Buffer
+		require.NoError(t, gob.NewEncoder(&buf).Encode(legacy))
+
+		imported, err := Import[string](buf.Bytes())
+		require.NoError(t, err)
+
+		count, errBound := imported.Count("apple")
+		assert.Equal(t, uint64(9), count)
+		assert.Equal(t, uint64(1), errBound)
+		assert.Equal(t, 5, imported.Capacity())
+	})
+}
+
+type addrCodec struct{}
+
+func (addrCodec) Encode(item netip.Addr) ([]byte, error) {
+	return item.MarshalText()
+}
+
+func (addrCodec) Decode(data []byte) (netip.Addr, error) {
+	return netip.ParseAddr(string(data))
+}
+
+type failCodec struct{}
+
+func (failCodec) Encode(netip.Addr) ([]byte, error) {
+	return nil, errors.New("encode failed")
+}
+
+func (failCodec) Decode([]byte) (netip.Addr, error) {
+	return netip.Addr{}, errors.New("decode failed")
 }
 
 func TestAccuracy(t *testing.T) {
 	t.Run("accurate counts for heavy hitters", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 
 		// Add heavy hitter
 		for i := 0; i < 100; i++ {
@@ -307,7 +541,7 @@ func TestAccuracy(t *testing.T) {
 	})
 
 	t.Run("top items accuracy", func(t *testing.T) {
-		ss := New(5)
+		ss := New[string](5)
 
 		// Known distribution
 		items := map[string]int{
@@ -335,7 +569,7 @@ func TestAccuracy(t *testing.T) {
 
 func TestZipfDistribution(t *testing.T) {
 	t.Run("zipf distribution tracking", func(t *testing.T) {
-		ss := New(100)
+		ss := New[string](100)
 
 		// Simulate Zipf distribution (realistic for web traffic, word frequencies, etc.)
 		// In Zipf: frequency(rank) ~ 1/rank
@@ -366,7 +600,7 @@ func TestZipfDistribution(t *testing.T) {
 
 func TestConcurrency(t *testing.T) {
 	t.Run("concurrent adds", func(t *testing.T) {
-		ss := New(100)
+		ss := New[string](100)
 		var wg sync.WaitGroup
 
 		// Multiple goroutines adding items
@@ -393,7 +627,7 @@ func TestConcurrency(t *testing.T) {
 	})
 
 	t.Run("concurrent reads and writes", func(_ *testing.T) {
-		ss := New(50)
+		ss := New[string](50)
 		done := make(chan bool)
 
 		// Writer
@@ -420,7 +654,7 @@ func TestConcurrency(t *testing.T) {
 
 func TestEdgeCases(t *testing.T) {
 	t.Run("empty string item", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		count := ss.Add("")
 		assert.Equal(t, uint64(1), count)
 
@@ -429,14 +663,14 @@ func TestEdgeCases(t *testing.T) {
 	})
 
 	t.Run("very long item", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		longItem := string(make([]byte, 10000))
 		count := ss.Add(longItem)
 		assert.Equal(t, uint64(1), count)
 	})
 
 	t.Run("single capacity", func(t *testing.T) {
-		ss := New(1)
+		ss := New[string](1)
 		ss.Add("a")
 		ss.Add("b")
 
@@ -448,7 +682,7 @@ func TestEdgeCases(t *testing.T) {
 
 func TestStableSort(t *testing.T) {
 	t.Run("items with same count sorted by value", func(t *testing.T) {
-		ss := New(10)
+		ss := New[string](10)
 		ss.Add("c")
 		ss.Add("a")
 		ss.Add("b")
@@ -462,7 +696,7 @@ func TestStableSort(t *testing.T) {
 }
 
 func BenchmarkSpaceSaving_Add(b *testing.B) {
-	ss := New(1000)
+	ss := New[string](1000)
 	b.ReportAllocs()
 	for b.Loop() {
 		ss.Add("item-50")
@@ -470,7 +704,7 @@ func BenchmarkSpaceSaving_Add(b *testing.B) {
 }
 
 func BenchmarkSpaceSaving_Count(b *testing.B) {
-	ss := New(1000)
+	ss := New[string](1000)
 	for i := range 10000 {
 		ss.Add(fmt.Sprintf("item-%d", i%100))
 	}
@@ -481,7 +715,7 @@ func BenchmarkSpaceSaving_Count(b *testing.B) {
 }
 
 func BenchmarkSpaceSaving_Top(b *testing.B) {
-	ss := New(1000)
+	ss := New[string](1000)
 	for i := range 10000 {
 		ss.Add(fmt.Sprintf("item-%d", i%100))
 	}
@@ -491,8 +725,25 @@ func BenchmarkSpaceSaving_Top(b *testing.B) {
 	}
 }
 
+func BenchmarkSpaceSaving_TopStructKeys(b *testing.B) {
+	type flow struct {
+		Src, Dst netip.Addr
+		Port     uint16
+	}
+
+	ss := New[flow](1000)
+	src := netip.MustParseAddr("192.0.2.1")
+	for i := range 1000 {
+		ss.Add(flow{Src: src, Dst: netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)}), Port: 443})
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = ss.Top(10)
+	}
+}
+
 func BenchmarkSpaceSaving_ConcurrentAdd(b *testing.B) {
-	ss := New(1000)
+	ss := New[string](1000)
 	b.ReportAllocs()
 	b.RunParallel(func(pb *testing.PB) {
 		i := 0
@@ -504,7 +755,7 @@ func BenchmarkSpaceSaving_ConcurrentAdd(b *testing.B) {
 }
 
 func BenchmarkSpaceSaving_Export(b *testing.B) {
-	ss := New(1000)
+	ss := New[string](1000)
 	for i := range 10000 {
 		ss.Add(fmt.Sprintf("item-%d", i%100))
 	}
@@ -515,14 +766,14 @@ func BenchmarkSpaceSaving_Export(b *testing.B) {
 }
 
 func BenchmarkSpaceSaving_Import(b *testing.B) {
-	ss := New(1000)
+	ss := New[string](1000)
 	for i := range 10000 {
 		ss.Add(fmt.Sprintf("item-%d", i%100))
 	}
 	data, _ := ss.Export()
 	b.ReportAllocs()
 	for b.Loop() {
-		_, _ = Import(data)
+		_, _ = Import[string](data)
 	}
 }
 
@@ -534,7 +785,7 @@ func FuzzSpaceSaving_Add(f *testing.F) {
 	f.Add("very-long-item-name-for-testing")
 
 	f.Fuzz(func(t *testing.T, item string) {
-		ss := New(100)
+		ss := New[string](100)
 		count := ss.Add(item)
 		if count < 1 {
 			t.Error("count should be at least 1 after adding")
@@ -552,7 +803,7 @@ func FuzzSpaceSaving_ExportImport(f *testing.F) {
 	f.Add("", "x", "y")
 
 	f.Fuzz(func(t *testing.T, s1, s2, s3 string) {
-		ss := New(100)
+		ss := New[string](100)
 		ss.Add(s1)
 		ss.Add(s2)
 		ss.Add(s3)
@@ -562,7 +813,7 @@ func FuzzSpaceSaving_ExportImport(f *testing.F) {
 			t.Fatalf("export failed: %v", err)
 		}
 
-		imported, err := Import(data)
+		imported, err := Import[string](data)
 		if err != nil {
 			t.Fatalf("import failed: %v", err)
 		}
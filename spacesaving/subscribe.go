@@ -0,0 +1,378 @@
+package spacesaving
+
+import (
+	"sort"
+	"time"
+)
+
+// EventType identifies the kind of change reported to a subscriber.
+type EventType int
+
+const (
+	// EventEnterTop reports an item entering the top-K.
+	EventEnterTop EventType = iota
+	// EventLeaveTop reports an item leaving the top-K.
+	EventLeaveTop
+	// EventAbove reports an item's rate reaching the high threshold.
+	EventAbove
+	// EventBelow reports an item's rate falling under the low threshold,
+	// or an item above the threshold being evicted from the tracker.
+	EventBelow
+)
+
+// String returns the event type name.
+func (e EventType) String() string {
+	switch e {
+	case EventEnterTop:
+		return "enter_top"
+	case EventLeaveTop:
+		return "leave_top"
+	case EventAbove:
+		return "above"
+	case EventBelow:
+		return "below"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change in an item's top-K membership or threshold state.
+type Event struct {
+	Type      EventType
+	Item      string
+	Rate      float64
+	ErrorRate float64
+	Time      time.Time
+}
+
+// Subscription is a registered top-K or threshold watcher on a RateTracker.
+type Subscription struct {
+	rt      *RateTracker
+	fn      func(Event)
+	k       int     // top-K size; zero for threshold subscriptions
+	high    float64 // threshold to enter the above state
+	low     float64 // threshold to leave the above state
+	members map[string]struct{}
+}
+
+type notification struct {
+	fn    func(Event)
+	event Event
+}
+
+// SubscribeTop calls fn when an item enters or leaves the top k by rate.
+// Items already in the top k are reported as entering on subscription.
+//
+// Membership changes only when an item is touched, evicted, merged or
+// reset: decay scales all rates by the same factor and never reorders them.
+// If k is not positive, 10 is used.
+//
+// Callbacks run synchronously on the goroutine that caused the change,
+// after the tracker lock is released, so they may query the tracker.
+// Callbacks from concurrent writers may run concurrently.
+func (rt *RateTracker) SubscribeTop(k int, fn func(Event)) *Subscription {
+	if k <= 0 {
+		k = 10
+	}
+
+	return rt.subscribe(&Subscription{
+		rt:      rt,
+		fn:      fn,
+		k:       k,
+		members: make(map[string]struct{}, k),
+	})
+}
+
+// SubscribeThreshold calls fn with EventAbove when an item's rate reaches
+// high, and with EventBelow once it falls under low. The gap between low
+// and high provides hysteresis so rates hovering near one value do not
+// flap. If low exceeds high, low is set to high.
+//
+// Crossings upward are detected on TouchAt. Rates only decay between
+// touches, so call Evaluate periodically to detect items going quiet.
+// Items above the threshold are reported on subscription.
+func (rt *RateTracker) SubscribeThreshold(high, low float64, fn func(Event)) *Subscription {
+	if low > high {
+		low = high
+	}
+
+	return rt.subscribe(&Subscription{
+		rt:      rt,
+		fn:      fn,
+		high:    high,
+		low:     low,
+		members: make(map[string]struct{}),
+	})
+}
+
+// Close removes the subscription. No events are delivered after Close
+// returns, except those already being dispatched.
+func (s *Subscription) Close() {
+	rt := s.rt
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	for i, sub := range rt.subs {
+		if sub == s {
+			rt.subs = append(rt.subs[:i], rt.subs[i+1:]...)
+			break
+		}
+	}
+}
+
+// Members returns the items currently in the top-K or above the threshold,
+// in Top order: by rate at the current time, highest first.
+func (s *Subscription) Members() []string {
+	rt := s.rt
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	now := rt.clock.Now()
+	ranked := make([]RateItem, 0, len(s.members))
+	for item := range s.members {
+		rate, errorRate := rt.rateAt(rt.counters[item], now)
+		ranked = append(ranked, RateItem{Value: item, Rate: rate, ErrorRate: errorRate})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranksAbove(ranked[i], ranked[j])
+	})
+
+	items := make([]string, len(ranked))
+	for i, item := range ranked {
+		items[i] = item.Value
+	}
+
+	return items
+}
+
+// NotifyChan returns a callback that sends events on ch without blocking.
+// Events are dropped when ch is full; size the buffer for the expected burst.
+func NotifyChan(ch chan<- Event) func(Event) {
+	return func(e Event) {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Evaluate re-checks threshold subscriptions at the current time.
+// See EvaluateAt.
+func (rt *RateTracker) Evaluate() {
+	rt.EvaluateAt(rt.clock.Now())
+}
+
+// EvaluateAt reports EventBelow for items whose rate has decayed under the
+// low threshold since their last touch.
+func (rt *RateTracker) EvaluateAt(t time.Time) {
+	rt.mu.Lock()
+	var pending []notification
+	for _, sub := range rt.subs {
+		if sub.k > 0 {
+			continue
+		}
+
+		for item := range sub.members {
+			rate, errorRate := rt.rateAt(rt.counters[item], t)
+			if rate < sub.low {
+				delete(sub.members, item)
+				pending = append(pending, sub.notify(EventBelow, item, rate, errorRate, t))
+			}
+		}
+	}
+	rt.mu.Unlock()
+
+	dispatch(pending)
+}
+
+func (rt *RateTracker) subscribe(s *Subscription) *Subscription {
+	rt.mu.Lock()
+	rt.subs = append(rt.subs, s)
+	pending := s.resync(rt, rt.clock.Now())
+	rt.mu.Unlock()
+
+	dispatch(pending)
+
+	return s
+}
+
+// notifyTouch updates subscriptions after item was touched at t.
+// Caller must hold rt.mu.
+func (rt *RateTracker) notifyTouch(item string, evicted RateItem, wasEvicted bool, t time.Time) []notification {
+	var pending []notification
+
+	rc := rt.counters[item]
+	touched := RateItem{Value: item, Rate: rc.Rate, ErrorRate: rc.ErrorRate}
+
+	for _, sub := range rt.subs {
+		if wasEvicted {
+			if _, ok := sub.members[evicted.Value]; ok {
+				delete(sub.members, evicted.Value)
+				pending = append(pending, sub.notify(sub.leaveType(), evicted.Value, evicted.Rate, evicted.ErrorRate, t))
+
+				// The eviction order follows undecayed rates, so refill the
+				// freed slot from all counters ranked by decayed rate
+				// rather than admitting the touched item unconditionally
+				if sub.k > 0 {
+					pending = append(pending, sub.resync(rt, t)...)
+					continue
+				}
+			}
+		}
+
+		if sub.k > 0 {
+			pending = sub.touchTop(rt, touched, t, pending)
+		} else {
+			pending = sub.touchThreshold(touched, t, pending)
+		}
+	}
+
+	return pending
+}
+
+// touchTop admits the touched item to the top-K if it now outranks the
+// lowest member by decayed rate. Touching only raises one rate, so at most
+// one item enters and one leaves.
+func (s *Subscription) touchTop(rt *RateTracker, touched RateItem, t time.Time, pending []notification) []notification {
+	if _, ok := s.members[touched.Value]; ok {
+		return pending
+	}
+
+	if len(s.members) < s.k {
+		s.members[touched.Value] = struct{}{}
+		return append(pending, s.notify(EventEnterTop, touched.Value, touched.Rate, touched.ErrorRate, t))
+	}
+
+	var lowest RateItem
+	found := false
+	for member := range s.members {
+		rate, errorRate := rt.rateAt(rt.counters[member], t)
+		candidate := RateItem{Value: member, Rate: rate, ErrorRate: errorRate}
+		if !found || ranksAbove(lowest, candidate) {
+			lowest = candidate
+			found = true
+		}
+	}
+
+	if !ranksAbove(touched, lowest) {
+		return pending
+	}
+
+	delete(s.members, lowest.Value)
+	s.members[touched.Value] = struct{}{}
+
+	return append(pending,
+		s.notify(EventLeaveTop, lowest.Value, lowest.Rate, lowest.ErrorRate, t),
+		s.notify(EventEnterTop, touched.Value, touched.Rate, touched.ErrorRate, t),
+	)
+}
+
+// touchThreshold applies the hysteresis rule to the touched item.
+func (s *Subscription) touchThreshold(touched RateItem, t time.Time, pending []notification) []notification {
+	_, above := s.members[touched.Value]
+
+	switch {
+	case !above && touched.Rate >= s.high:
+		s.members[touched.Value] = struct{}{}
+		return append(pending, s.notify(EventAbove, touched.Value, touched.Rate, touched.ErrorRate, t))
+	case above && touched.Rate < s.low:
+		delete(s.members, touched.Value)
+		return append(pending, s.notify(EventBelow, touched.Value, touched.Rate, touched.ErrorRate, t))
+	}
+
+	return pending
+}
+
+// resync recomputes membership of every subscription from the current
+// counters, used after bulk changes such as Merge and Reset.
+// Caller must hold rt.mu.
+func (rt *RateTracker) resync(t time.Time) []notification {
+	var pending []notification
+	for _, sub := range rt.subs {
+		pending = append(pending, sub.resync(rt, t)...)
+	}
+	return pending
+}
+
+// resync recomputes membership from the current counters.
+// Caller must hold rt.mu.
+func (s *Subscription) resync(rt *RateTracker, t time.Time) []notification {
+	items := make([]RateItem, 0, len(rt.counters))
+	for _, rc := range rt.counters {
+		rate, errorRate := rt.rateAt(rc, t)
+		items = append(items, RateItem{Value: rc.Item, Rate: rate, ErrorRate: errorRate})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return ranksAbove(items[i], items[j])
+	})
+
+	next := make(map[string]RateItem)
+	for i, item := range items {
+		if s.k > 0 && i >= s.k {
+			break
+		}
+		_, member := s.members[item.Value]
+		if s.k > 0 || item.Rate >= s.high || (member && item.Rate >= s.low) {
+			next[item.Value] = item
+		}
+	}
+
+	var pending []notification
+
+	for member := range s.members {
+		if _, ok := next[member]; ok {
+			continue
+		}
+		rate, errorRate := rt.rateAt(rt.counters[member], t)
+		delete(s.members, member)
+		pending = append(pending, s.notify(s.leaveType(), member, rate, errorRate, t))
+	}
+
+	for _, item := range items {
+		if _, ok := next[item.Value]; !ok {
+			continue
+		}
+		if _, ok := s.members[item.Value]; ok {
+			continue
+		}
+		s.members[item.Value] = struct{}{}
+		pending = append(pending, s.notify(s.enterType(), item.Value, item.Rate, item.ErrorRate, t))
+	}
+
+	return pending
+}
+
+func (s *Subscription) enterType() EventType {
+	if s.k > 0 {
+		return EventEnterTop
+	}
+	return EventAbove
+}
+
+func (s *Subscription) leaveType() EventType {
+	if s.k > 0 {
+		return EventLeaveTop
+	}
+	return EventBelow
+}
+
+func (s *Subscription) notify(typ EventType, item string, rate, errorRate float64, t time.Time) notification {
+	return notification{
+		fn:    s.fn,
+		event: Event{Type: typ, Item: item, Rate: rate, ErrorRate: errorRate, Time: t},
+	}
+}
+
+// ranksAbove reports whether a sorts before b in Top order.
+func ranksAbove(a, b RateItem) bool {
+	if a.Rate == b.Rate {
+		return a.Value < b.Value
+	}
+	return a.Rate > b.Rate
+}
+
+func dispatch(pending []notification) {
+	for _, n := range pending {
+		n.fn(n.event)
+	}
+}
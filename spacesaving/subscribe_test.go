@@ -0,0 +1,328 @@
+package spacesaving
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vitalvas/gokit/ewma"
+)
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) add(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+func (l *eventLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]string, len(l.events))
+	for i, e := range l.events {
+		out[i] = e.Type.String() + ":" + e.Item
+	}
+	l.events = nil
+
+	return out
+}
+
+func TestSubscribeTop(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("enter and leave", func(t *testing.T) {
+		rt := NewRateTracker(10, time.Minute)
+		log := &eventLog{}
+		sub := rt.SubscribeTop(2, log.add)
+
+		rt.TouchAt("a", base)
+		rt.TouchAt("b", base)
+		assert.Equal(t, []string{"enter_top:a", "enter_top:b"}, log.take())
+
+		// c ties with a and b but sorts after them
+		rt.TouchAt("c", base)
+		assert.Empty(t, log.take())
+
+		rt.TouchAt("c", base)
+		assert.Equal(t, []string{"leave_top:b", "enter_top:c"}, log.take())
+		assert.Equal(t, []string{"a", "c"}, sub.Members())
+
+		// Touching a member changes nothing
+		rt.TouchAt("c", base)
+		assert.Empty(t, log.take())
+	})
+
+	t.Run("membership matches TopAt", func(t *testing.T) {
+		rt := NewRateTracker(20, 10*time.Second)
+		sub := rt.SubscribeTop(5, func(Event) {})
+
+		for i := range 2000 {
+			item := fmt.Sprintf("k%d", (i*i)%37)
+			now := base.Add(time.Duration(i) * 100 * time.Millisecond)
+			rt.TouchAt(item, now)
+
+			var expected []string
+			for _, it := range rt.TopAt(5, now) {
+				expected = append(expected, it.Value)
+			}
+			assert.ElementsMatch(t, expected, sub.Members(), "step %d", i)
+		}
+	})
+
+	t.Run("eviction of member", func(t *testing.T) {
+		rt := NewRateTracker(2, time.Minute)
+		log := &eventLog{}
+		rt.SubscribeTop(2, log.add)
+
+		rt.TouchAt("a", base)
+		rt.TouchAt("a", base)
+		rt.TouchAt("b", base)
+		log.take()
+
+		rt.TouchAt("c", base)
+		assert.Equal(t, []string{"leave_top:b", "enter_top:c"}, log.take())
+	})
+
+	t.Run("eviction ranks candidates by decayed rate", func(t *testing.T) {
+		clock := ewma.NewManualClock(base)
+		rt := NewRateTrackerWithClock(3, time.Minute, clock)
+		log := &eventLog{}
+		sub := rt.SubscribeTop(2, log.add)
+
+		for range 5 {
+			rt.Touch("old")
+		}
+
+		// old decays to 2.5 and drops out, but keeps the largest stored rate
+		clock.Advance(time.Minute)
+		for range 4 {
+			rt.Touch("q")
+		}
+		for range 3 {
+			rt.Touch("m")
+		}
+		assert.Equal(t, []string{"q", "m"}, sub.Members())
+		log.take()
+
+		// m has the lowest stored rate, so it is evicted although it is a member
+		rt.Touch("d")
+		assert.Equal(t, []string{"leave_top:m", "enter_top:d"}, log.take())
+		assert.Equal(t, topValues(rt.Top(2)), sub.Members())
+
+		for i := range 200 {
+			clock.Advance(time.Duration(i%7) * 10 * time.Second)
+			rt.Touch(fmt.Sprintf("k%d", (i*i)%11))
+			require.Equal(t, topValues(rt.Top(2)), sub.Members(), "step %d", i)
+		}
+	})
+
+	t.Run("existing members reported on subscribe", func(t *testing.T) {
+		rt := NewRateTracker(10, time.Minute)
+		rt.Touch("a")
+		rt.Touch("a")
+		rt.Touch("b")
+		rt.Touch("c")
+
+		log := &eventLog{}
+		rt.SubscribeTop(1, log.add)
+		assert.Equal(t, []string{"enter_top:a"}, log.take())
+	})
+
+	t.Run("reset and close", func(t *testing.T) {
+		rt := NewRateTracker(10, time.Minute)
+		log := &eventLog{}
+		sub := rt.SubscribeTop(3, log.add)
+
+		rt.Touch("a")
+		log.take()
+
+		rt.Reset()
+		assert.Equal(t, []string{"leave_top:a"}, log.take())
+
+		sub.Close()
+		rt.Touch("b")
+		assert.Empty(t, log.take())
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		rt := NewRateTracker(10, time.Minute)
+		log := &eventLog{}
+		rt.SubscribeTop(1, log.add)
+
+		rt.TouchAt("a", base)
+		log.take()
+
+		other := NewRateTracker(10, time.Minute)
+		other.TouchAt("b", base)
+		other.TouchAt("b", base)
+		rt.MergeAt(other, base)
+
+		assert.Equal(t, []string{"leave_top:a", "enter_top:b"}, log.take())
+	})
+
+	t.Run("default k", func(t *testing.T) {
+		rt := NewRateTracker(10, time.Minute)
+		assert.Equal(t, 10, rt.SubscribeTop(0, func(Event) {}).k)
+	})
+}
+
+func topValues(items []RateItem) []string {
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i] = item.Value
+	}
+	return values
+}
+
+func TestSubscribeThreshold(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("hysteresis", func(t *testing.T) {
+		rt := NewRateTracker(10, 10*time.Second)
+		log := &eventLog{}
+		sub := rt.SubscribeThreshold(5, 2, log.add)
+
+		for range 4 {
+			rt.TouchAt("x", base)
+		}
+		assert.Empty(t, log.take())
+
+		rt.TouchAt("x", base)
+		events := log.events
+		require.Len(t, events, 1)
+		assert.Equal(t, EventAbove, events[0].Type)
+		assert.Equal(t, 5.0, events[0].Rate)
+		assert.Equal(t, base, events[0].Time)
+		log.take()
+
+		// One half-life later: 2.5 + 1 = 3.5, between low and high
+		rt.TouchAt("x", base.Add(10*time.Second))
+		assert.Empty(t, log.take())
+		assert.Equal(t, []string{"x"}, sub.Members())
+
+		// Two more half-lives: 0.875 + 1 < 2
+		rt.TouchAt("x", base.Add(30*time.Second))
+		assert.Equal(t, []string{"below:x"}, log.take())
+		assert.Empty(t, sub.Members())
+	})
+
+	t.Run("evaluate detects quiet items", func(t *testing.T) {
+		rt := NewRateTracker(10, 10*time.Second)
+		log := &eventLog{}
+		rt.SubscribeThreshold(3, 1, log.add)
+
+		for range 4 {
+			rt.TouchAt("x", base)
+		}
+		assert.Equal(t, []string{"above:x"}, log.take())
+
+		rt.EvaluateAt(base.Add(10 * time.Second))
+		assert.Empty(t, log.take())
+
+		rt.EvaluateAt(base.Add(30 * time.Second))
+		assert.Equal(t, []string{"below:x"}, log.take())
+	})
+
+	t.Run("eviction reports below", func(t *testing.T) {
+		rt := NewRateTracker(1, time.Minute)
+		log := &eventLog{}
+		rt.SubscribeThreshold(1, 1, log.add)
+
+		rt.TouchAt("a", base)
+		rt.TouchAt("b", base)
+		assert.Equal(t, []string{"above:a", "below:a", "above:b"}, log.take())
+	})
+
+	t.Run("low above high is clamped", func(t *testing.T) {
+		rt := NewRateTracker(10, time.Minute)
+		sub := rt.SubscribeThreshold(2, 5, func(Event) {})
+		assert.Equal(t, 2.0, sub.low)
+	})
+
+	t.Run("callbacks may query tracker", func(t *testing.T) {
+		rt := NewRateTracker(10, time.Minute)
+		var blocked []string
+		rt.SubscribeThreshold(3, 3, func(e Event) {
+			if e.Type == EventAbove {
+				blocked = append(blocked, e.Item)
+				_ = rt.Top(1)
+			}
+		})
+
+		for range 3 {
+			rt.TouchAt("attacker", base)
+		}
+		assert.Equal(t, []string{"attacker"}, blocked)
+	})
+}
+
+func TestNotifyChan(t *testing.T) {
+	ch := make(chan Event, 1)
+	rt := NewRateTracker(10, time.Minute)
+	rt.SubscribeTop(5, NotifyChan(ch))
+
+	rt.Touch("a")
+	rt.Touch("b") // dropped, channel full
+
+	e := <-ch
+	assert.Equal(t, EventEnterTop, e.Type)
+	assert.Equal(t, "a", e.Item)
+	assert.Empty(t, ch)
+}
+
+func TestSubscriptionConcurrency(t *testing.T) {
+	rt := NewRateTracker(50, time.Minute)
+	log := &eventLog{}
+	rt.SubscribeTop(5, log.add)
+	rt.SubscribeThreshold(100, 50, log.add)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				rt.Touch(fmt.Sprintf("k%d", (g+i)%80))
+				if i%100 == 0 {
+					rt.Evaluate()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.NotEmpty(t, log.take())
+}
+
+func TestEventTypeString(t *testing.T) {
+	assert.Equal(t, "enter_top", EventEnterTop.String())
+	assert.Equal(t, "leave_top", EventLeaveTop.String())
+	assert.Equal(t, "above", EventAbove.String())
+	assert.Equal(t, "below", EventBelow.String())
+	assert.Equal(t, "unknown", EventType(99).String())
+}
+
+func BenchmarkRateTracker_TouchSubscribed(b *testing.B) {
+	rt := NewRateTracker(1000, time.Minute)
+	rt.SubscribeTop(10, func(Event) {})
+	rt.SubscribeThreshold(100, 50, func(Event) {})
+
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		rt.Touch(keys[i%len(keys)])
+		i++
+	}
+}
@@ -0,0 +1,210 @@
+package tdigest
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// DefaultQuantiles are the quantiles written by WriteSummary when none are given.
+	DefaultQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+	// DefaultBuckets are the histogram upper bounds written by WriteHistogram
+	// when none are given. They match the Prometheus client defaults (seconds).
+	DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+// Metric describes how a t-digest is exposed in the text exposition format.
+type Metric struct {
+	// Name is the metric family name (e.g. "http_request_duration_seconds").
+	Name string
+
+	// Help is the optional HELP text.
+	Help string
+
+	// Labels are constant labels added to every sample.
+	Labels map[string]string
+}
+
+// WriteSummary writes the t-digest as a Prometheus summary: one sample per
+// quantile, followed by _sum and _count.
+// If quantiles is empty, DefaultQuantiles is used.
+//
+// Example output:
+//
+//	# TYPE latency_seconds summary
+//	latency_seconds{quantile="0.5"} 0.012
+//	latency_seconds{quantile="0.99"} 0.25
+//	latency_seconds_sum 184.2
+//	latency_seconds_count 10000
+func WriteSummary(w io.Writer, m Metric, td *TDigest, quantiles []float64) error {
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+
+	td.mu.Lock()
+	td.flush()
+
+	values := make([]float64, len(quantiles))
+	for i, q := range quantiles {
+		values[i] = td.quantile(q)
+	}
+	count, sum := td.count, td.sum()
+
+	td.mu.Unlock()
+
+	var buf bytes.Buffer
+	writeHeader(&buf, m, "summary")
+
+	for i, q := range quantiles {
+		writeSample(&buf, m.Name, m.Labels, "quantile", formatFloat(q), values[i])
+	}
+
+	writeSample(&buf, m.Name+"_sum", m.Labels, "", "", sum)
+	writeSample(&buf, m.Name+"_count", m.Labels, "", "", count)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteHistogram writes the t-digest as a cumulative histogram with fixed
+// upper bounds. Bucket counts are derived from CDF and rounded to whole
+// observations; a final +Inf bucket equal to the total count is always added.
+// If buckets is empty, DefaultBuckets is used.
+//
+// Example output:
+//
+//	# TYPE latency_seconds histogram
+//	latency_seconds_bucket{le="0.1"} 8123
+//	latency_seconds_bucket{le="+Inf"} 10000
+//	latency_seconds_sum 184.2
+//	latency_seconds_count 10000
+func WriteHistogram(w io.Writer, m Metric, td *TDigest, buckets []float64) error {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	bounds := append([]float64(nil), buckets...)
+	sort.Float64s(bounds)
+
+	td.mu.Lock()
+	td.flush()
+
+	counts := make([]float64, len(bounds))
+	count, sum := td.count, td.sum()
+
+	if count > 0 {
+		prev := float64(0)
+		for i, le := range bounds {
+			// Cumulative counts must never decrease
+			counts[i] = math.Max(math.Round(td.cdf(le)*count), prev)
+			prev = counts[i]
+		}
+	}
+
+	td.mu.Unlock()
+
+	var buf bytes.Buffer
+	writeHeader(&buf, m, "histogram")
+
+	for i, le := range bounds {
+		if math.IsInf(le, 1) {
+			continue
+		}
+		writeSample(&buf, m.Name+"_bucket", m.Labels, "le", formatFloat(le), counts[i])
+	}
+
+	writeSample(&buf, m.Name+"_bucket", m.Labels, "le", "+Inf", count)
+	writeSample(&buf, m.Name+"_sum", m.Labels, "", "", sum)
+	writeSample(&buf, m.Name+"_count", m.Labels, "", "", count)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeHeader(buf *bytes.Buffer, m Metric, typ string) {
+	if m.Help != "" {
+		buf.WriteString("# HELP ")
+		buf.WriteString(m.Name)
+		buf.WriteByte(' ')
+		buf.WriteString(escapeHelp(m.Help))
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteString("# TYPE ")
+	buf.WriteString(m.Name)
+	buf.WriteByte(' ')
+	buf.WriteString(typ)
+	buf.WriteByte('\n')
+}
+
+func writeSample(buf *bytes.Buffer, name string, labels map[string]string, extraName, extraValue string, value float64) {
+	buf.WriteString(name)
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 || extraName != "" {
+		buf.WriteByte('{')
+
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeLabel(buf, k, labels[k])
+		}
+
+		if extraName != "" {
+			if len(keys) > 0 {
+				buf.WriteByte(',')
+			}
+			writeLabel(buf, extraName, extraValue)
+		}
+
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(value))
+	buf.WriteByte('\n')
+}
+
+func writeLabel(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	buf.WriteString(escapeLabelValue(value))
+	buf.WriteByte('"')
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,184 @@
+package tdigest
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSummary(t *testing.T) {
+	t.Run("quantiles sum and count", func(t *testing.T) {
+		td := New(100)
+		for i := 1; i <= 100; i++ {
+			td.Add(float64(i))
+		}
+
+		var sb strings.Builder
+		err := WriteSummary(&sb, Metric{
+			Name: "latency_seconds",
+			Help: "Request latency.",
+		}, td, []float64{0.5, 1})
+		require.NoError(t, err)
+
+		expected := `# HELP latency_seconds Request latency.
+# TYPE latency_seconds summary
+latency_seconds{quantile="0.5"} 50.5
+latency_seconds{quantile="1"} 100
+latency_seconds_sum 5050
+latency_seconds_count 100
+`
+		assert.Equal(t, expected, sb.String())
+	})
+
+	t.Run("default quantiles", func(t *testing.T) {
+		td := New(100)
+		td.Add(1)
+
+		var sb strings.Builder
+		require.NoError(t, WriteSummary(&sb, Metric{Name: "m"}, td, nil))
+
+		out := sb.String()
+		assert.NotContains(t, out, "# HELP")
+		for _, q := range DefaultQuantiles {
+			assert.Contains(t, out, `m{quantile="`+formatFloat(q)+`"} 1`)
+		}
+	})
+
+	t.Run("labels are sorted and escaped", func(t *testing.T) {
+		td := New(100)
+		td.Add(2)
+
+		var sb strings.Builder
+		err := WriteSummary(&sb, Metric{
+			Name:   "m",
+			Labels: map[string]string{"path": `/a"b`, "method": "GET"},
+		}, td, []float64{0.5})
+		require.NoError(t, err)
+
+		assert.Contains(t, sb.String(), `m{method="GET",path="/a\"b",quantile="0.5"} 2`)
+		assert.Contains(t, sb.String(), `m_count{method="GET",path="/a\"b"} 1`)
+	})
+
+	t.Run("empty digest", func(t *testing.T) {
+		var sb strings.Builder
+		require.NoError(t, WriteSummary(&sb, Metric{Name: "m"}, New(100), []float64{0.99}))
+
+		assert.Contains(t, sb.String(), `m{quantile="0.99"} NaN`)
+		assert.Contains(t, sb.String(), "m_sum 0\n")
+		assert.Contains(t, sb.String(), "m_count 0\n")
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		err := WriteSummary(failWriter{}, Metric{Name: "m"}, New(100), nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestWriteHistogram(t *testing.T) {
+	t.Run("cumulative buckets from CDF", func(t *testing.T) {
+		td := New(100)
+		for i := 1; i <= 1000; i++ {
+			td.Add(float64(i) / 1000)
+		}
+
+		var sb strings.Builder
+		err := WriteHistogram(&sb, Metric{Name: "latency_seconds"}, td, []float64{0.5, 0.1, 0.9})
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+		require.Len(t, lines, 7)
+		assert.Equal(t, "# TYPE latency_seconds histogram", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], `latency_seconds_bucket{le="0.1"} `))
+		assert.True(t, strings.HasPrefix(lines[2], `latency_seconds_bucket{le="0.5"} `))
+		assert.True(t, strings.HasPrefix(lines[3], `latency_seconds_bucket{le="0.9"} `))
+		assert.Equal(t, `latency_seconds_bucket{le="+Inf"} 1000`, lines[4])
+		assert.Equal(t, "latency_seconds_sum 500.5", lines[5])
+		assert.Equal(t, "latency_seconds_count 1000", lines[6])
+
+		assert.InDelta(t, 100, bucketValue(t, lines[1]), 10)
+		assert.InDelta(t, 500, bucketValue(t, lines[2]), 10)
+		assert.InDelta(t, 900, bucketValue(t, lines[3]), 10)
+	})
+
+	t.Run("buckets are monotonic", func(t *testing.T) {
+		td := New(50)
+		for i := range 10000 {
+			td.Add(math.Sin(float64(i)) * 10)
+		}
+
+		var sb strings.Builder
+		buckets := []float64{-10, -5, -1, 0, 1, 5, 10}
+		require.NoError(t, WriteHistogram(&sb, Metric{Name: "m"}, td, buckets))
+
+		prev := float64(-1)
+		for _, line := range strings.Split(strings.TrimSpace(sb.String()), "\n") {
+			if !strings.HasPrefix(line, "m_bucket") {
+				continue
+			}
+			v := bucketValue(t, line)
+			assert.GreaterOrEqual(t, v, prev)
+			prev = v
+		}
+		assert.Equal(t, 10000.0, prev)
+	})
+
+	t.Run("explicit +Inf bucket is not duplicated", func(t *testing.T) {
+		td := New(100)
+		td.Add(1)
+
+		var sb strings.Builder
+		require.NoError(t, WriteHistogram(&sb, Metric{Name: "m"}, td, []float64{1, math.Inf(1)}))
+
+		assert.Equal(t, 1, strings.Count(sb.String(), `le="+Inf"`))
+	})
+
+	t.Run("default buckets with labels", func(t *testing.T) {
+		var sb strings.Builder
+		err := WriteHistogram(&sb, Metric{Name: "m", Labels: map[string]string{"job": "api"}}, New(100), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, len(DefaultBuckets)+1, strings.Count(sb.String(), "m_bucket"))
+		assert.Contains(t, sb.String(), `m_bucket{job="api",le="0.005"} 0`)
+		assert.Contains(t, sb.String(), `m_count{job="api"} 0`)
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		err := WriteHistogram(failWriter{}, Metric{Name: "m"}, New(100), nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestFormatFloat(t *testing.T) {
+	assert.Equal(t, "NaN", formatFloat(math.NaN()))
+	assert.Equal(t, "+Inf", formatFloat(math.Inf(1)))
+	assert.Equal(t, "-Inf", formatFloat(math.Inf(-1)))
+	assert.Equal(t, "0.25", formatFloat(0.25))
+	assert.Equal(t, "1e+06", formatFloat(1e6))
+}
+
+func TestEscapeHelp(t *testing.T) {
+	assert.Equal(t, `a\\b\nc`, escapeHelp("a\\b\nc"))
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func bucketValue(t *testing.T, line string) float64 {
+	t.Helper()
+
+	fields := strings.Fields(line)
+	require.Len(t, fields, 2)
+
+	v, err := strconv.ParseFloat(fields[1], 64)
+	require.NoError(t, err)
+
+	return v
+}
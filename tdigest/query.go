@@ -0,0 +1,174 @@
+package tdigest
+
+import "math"
+
+// Bin is a histogram bin covering values in [Lower, Upper].
+type Bin struct {
+	Lower float64
+	Upper float64
+	Count float64
+}
+
+// TrimmedMean returns the approximate mean of values between the lo and hi
+// quantiles (0-1). Centroids straddling a boundary contribute the part of
+// their weight that falls inside the range.
+//
+// Examples:
+//   - TrimmedMean(0, 1) equals Mean()
+//   - TrimmedMean(0.1, 0.9) drops the lowest and highest 10%
+//   - TrimmedMean(0, 0.99) excludes the top 1% of outliers
+func (td *TDigest) TrimmedMean(lo, hi float64) float64 {
+	lo = math.Max(lo, 0)
+	hi = math.Min(hi, 1)
+
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	if len(td.centroids) == 0 || td.count == 0 || lo >= hi {
+		return math.NaN()
+	}
+
+	lower := lo * td.count
+	upper := hi * td.count
+
+	sum := float64(0)
+	weight := float64(0)
+	weightSum := float64(0)
+
+	for i := range td.centroids {
+		c := &td.centroids[i]
+		start := weightSum
+		weightSum += c.Weight
+
+		if weightSum <= lower {
+			continue
+		}
+		if start >= upper {
+			break
+		}
+
+		// Weight of this centroid inside [lower, upper]
+		inside := math.Min(weightSum, upper) - math.Max(start, lower)
+		sum += c.Mean * inside
+		weight += inside
+	}
+
+	if weight == 0 {
+		return math.NaN()
+	}
+
+	return sum / weight
+}
+
+// Rank returns the approximate number of values <= x.
+func (td *TDigest) Rank(x float64) float64 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	if td.count == 0 {
+		return 0
+	}
+
+	return td.cdf(x) * td.count
+}
+
+// CountAbove returns the approximate number of values > x.
+//
+// Example: CountAbove(0.5) on request latencies in seconds returns the
+// number of requests slower than 500ms.
+func (td *TDigest) CountAbove(x float64) float64 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	if td.count == 0 {
+		return 0
+	}
+
+	return (1 - td.cdf(x)) * td.count
+}
+
+// Histogram returns an approximate histogram with n fixed-width bins
+// spanning [Min, Max]. Returns nil if the digest is empty or n < 1.
+func (td *TDigest) Histogram(n int) []Bin {
+	if n < 1 {
+		return nil
+	}
+
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	if td.count == 0 {
+		return nil
+	}
+
+	if td.min == td.max {
+		return []Bin{{Lower: td.min, Upper: td.max, Count: td.count}}
+	}
+
+	width := (td.max - td.min) / float64(n)
+	bins := make([]Bin, n)
+	prev := float64(0)
+
+	for i := range bins {
+		lower := td.min + float64(i)*width
+		upper := td.min + float64(i+1)*width
+
+		cumulative := td.count
+		if i < n-1 {
+			cumulative = td.cdf(upper) * td.count
+		} else {
+			upper = td.max
+		}
+
+		bins[i] = Bin{
+			Lower: lower,
+			Upper: upper,
+			Count: cumulative - prev,
+		}
+		prev = cumulative
+	}
+
+	return bins
+}
+
+// EqualWeightHistogram returns an approximate histogram with n bins that
+// each hold the same number of values. Bin boundaries are quantiles, so
+// narrow bins indicate dense regions. Returns nil if the digest is empty
+// or n < 1.
+func (td *TDigest) EqualWeightHistogram(n int) []Bin {
+	if n < 1 {
+		return nil
+	}
+
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	if td.count == 0 {
+		return nil
+	}
+
+	bins := make([]Bin, n)
+	lower := td.min
+
+	for i := range bins {
+		upper := td.quantile(float64(i+1) / float64(n))
+		bins[i] = Bin{
+			Lower: lower,
+			Upper: upper,
+			Count: td.count / float64(n),
+		}
+		lower = upper
+	}
+
+	return bins
+}
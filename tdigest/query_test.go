@@ -0,0 +1,239 @@
+package tdigest
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exactTrimmedMean(sorted []float64, lo, hi float64) float64 {
+	start := int(math.Round(lo * float64(len(sorted))))
+	end := int(math.Round(hi * float64(len(sorted))))
+
+	sum := float64(0)
+	for _, v := range sorted[start:end] {
+		sum += v
+	}
+
+	return sum / float64(end-start)
+}
+
+func exactCountAbove(sorted []float64, x float64) float64 {
+	idx := sort.Search(len(sorted), func(i int) bool { return sorted[i] > x })
+	return float64(len(sorted) - idx)
+}
+
+func syntheticDistributions() map[string][]float64 {
+	rng := rand.New(rand.NewSource(42))
+	n := 20000
+
+	uniform := make([]float64, n)
+	normal := make([]float64, n)
+	exponential := make([]float64, n)
+
+	for i := range n {
+		uniform[i] = rng.Float64() * 1000
+		normal[i] = rng.NormFloat64()*10 + 100
+		exponential[i] = rng.ExpFloat64() * 50
+	}
+
+	return map[string][]float64{
+		"uniform":     uniform,
+		"normal":      normal,
+		"exponential": exponential,
+	}
+}
+
+func TestTrimmedMean(t *testing.T) {
+	for name, values := range syntheticDistributions() {
+		t.Run(name, func(t *testing.T) {
+			td := New(100)
+			for _, v := range values {
+				td.Add(v)
+			}
+
+			sorted := append([]float64(nil), values...)
+			sort.Float64s(sorted)
+
+			spread := sorted[len(sorted)-1] - sorted[0]
+
+			for _, r := range [][2]float64{{0, 1}, {0.1, 0.9}, {0.25, 0.75}, {0, 0.99}, {0.01, 1}} {
+				exact := exactTrimmedMean(sorted, r[0], r[1])
+				assert.InDelta(t, exact, td.TrimmedMean(r[0], r[1]), spread*0.005,
+					"range [%.2f, %.2f]", r[0], r[1])
+			}
+		})
+	}
+
+	t.Run("full range equals mean", func(t *testing.T) {
+		td := New(100)
+		for i := range 1000 {
+			td.Add(float64(i))
+		}
+		assert.InDelta(t, td.Mean(), td.TrimmedMean(0, 1), 1e-9)
+	})
+
+	t.Run("out of range quantiles are clamped", func(t *testing.T) {
+		td := New(100)
+		for i := range 100 {
+			td.Add(float64(i))
+		}
+		assert.InDelta(t, td.Mean(), td.TrimmedMean(-1, 2), 1e-9)
+	})
+
+	t.Run("empty or inverted range", func(t *testing.T) {
+		td := New(100)
+		assert.True(t, math.IsNaN(td.TrimmedMean(0, 1)))
+
+		td.Add(1)
+		assert.True(t, math.IsNaN(td.TrimmedMean(0.6, 0.4)))
+	})
+}
+
+func TestRankAndCountAbove(t *testing.T) {
+	for name, values := range syntheticDistributions() {
+		t.Run(name, func(t *testing.T) {
+			td := New(100)
+			for _, v := range values {
+				td.Add(v)
+			}
+
+			sorted := append([]float64(nil), values...)
+			sort.Float64s(sorted)
+
+			n := float64(len(sorted))
+			for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+				x := sorted[int(q*n)]
+				exact := exactCountAbove(sorted, x)
+
+				assert.InDelta(t, exact, td.CountAbove(x), n*0.005, "q=%.3f", q)
+				assert.InDelta(t, n-exact, td.Rank(x), n*0.005, "q=%.3f", q)
+			}
+		})
+	}
+
+	t.Run("outside range", func(t *testing.T) {
+		td := New(100)
+		for i := 1; i <= 100; i++ {
+			td.Add(float64(i))
+		}
+
+		assert.Equal(t, 100.0, td.CountAbove(0))
+		assert.Equal(t, 0.0, td.CountAbove(100))
+		assert.Equal(t, 0.0, td.Rank(0))
+		assert.Equal(t, 100.0, td.Rank(100))
+	})
+
+	t.Run("empty digest", func(t *testing.T) {
+		td := New(100)
+		assert.Equal(t, 0.0, td.CountAbove(1))
+		assert.Equal(t, 0.0, td.Rank(1))
+	})
+}
+
+func TestHistogram(t *testing.T) {
+	for name, values := range syntheticDistributions() {
+		t.Run(name, func(t *testing.T) {
+			td := New(100)
+			for _, v := range values {
+				td.Add(v)
+			}
+
+			bins := td.Histogram(10)
+			require.Len(t, bins, 10)
+
+			n := float64(len(values))
+			total := float64(0)
+
+			for i, bin := range bins {
+				exact := float64(0)
+				for _, v := range values {
+					if v >= bin.Lower && (v < bin.Upper || (i == len(bins)-1 && v <= bin.Upper)) {
+						exact++
+					}
+				}
+
+				assert.InDelta(t, exact, bin.Count, n*0.01, "bin %d [%.2f, %.2f]", i, bin.Lower, bin.Upper)
+				total += bin.Count
+			}
+
+			assert.InDelta(t, n, total, 1e-6)
+			assert.Equal(t, td.Min(), bins[0].Lower)
+			assert.Equal(t, td.Max(), bins[len(bins)-1].Upper)
+		})
+	}
+
+	t.Run("single value", func(t *testing.T) {
+		td := New(100)
+		td.AddWeighted(5, 3)
+
+		bins := td.Histogram(4)
+		require.Len(t, bins, 1)
+		assert.Equal(t, Bin{Lower: 5, Upper: 5, Count: 3}, bins[0])
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		assert.Nil(t, New(100).Histogram(5))
+
+		td := New(100)
+		td.Add(1)
+		assert.Nil(t, td.Histogram(0))
+	})
+}
+
+func TestEqualWeightHistogram(t *testing.T) {
+	for name, values := range syntheticDistributions() {
+		t.Run(name, func(t *testing.T) {
+			td := New(100)
+			for _, v := range values {
+				td.Add(v)
+			}
+
+			bins := td.EqualWeightHistogram(5)
+			require.Len(t, bins, 5)
+
+			n := float64(len(values))
+			for i, bin := range bins {
+				assert.Equal(t, n/5, bin.Count)
+
+				exact := float64(0)
+				for _, v := range values {
+					if v >= bin.Lower && (v < bin.Upper || (i == len(bins)-1 && v <= bin.Upper)) {
+						exact++
+					}
+				}
+				assert.InDelta(t, n/5, exact, n*0.01, "bin %d [%.2f, %.2f]", i, bin.Lower, bin.Upper)
+
+				if i > 0 {
+					assert.Equal(t, bins[i-1].Upper, bin.Lower)
+				}
+			}
+
+			assert.Equal(t, td.Min(), bins[0].Lower)
+			assert.Equal(t, td.Max(), bins[len(bins)-1].Upper)
+		})
+	}
+
+	t.Run("invalid input", func(t *testing.T) {
+		assert.Nil(t, New(100).EqualWeightHistogram(5))
+
+		td := New(100)
+		td.Add(1)
+		assert.Nil(t, td.EqualWeightHistogram(-1))
+	})
+}
+
+func BenchmarkTDigest_TrimmedMean(b *testing.B) {
+	td := New(100)
+	for i := range 10000 {
+		td.Add(float64(i))
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = td.TrimmedMean(0.1, 0.9)
+	}
+}
@@ -0,0 +1,123 @@
+package tdigest
+
+import (
+	"math"
+	"math/rand/v2"
+	"runtime"
+)
+
+// Recorder is a sharded t-digest for high-rate ingestion.
+// Each Add goes to a randomly chosen shard, so concurrent writers rarely
+// contend on the same lock. Queries merge all shards into a single digest.
+//
+// Use cases: Request latency recording on hot paths, per-core metrics
+type Recorder struct {
+	compression float64
+	shards      []*TDigest
+}
+
+// NewRecorder creates a sharded recorder with the given compression factor.
+// If shards is not positive, runtime.GOMAXPROCS(0) shards are used.
+func NewRecorder(compression float64, shards int) *Recorder {
+	if compression <= 0 {
+		compression = 100
+	}
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	r := &Recorder{
+		compression: compression,
+		shards:      make([]*TDigest, shards),
+	}
+
+	for i := range r.shards {
+		r.shards[i] = New(compression)
+	}
+
+	return r
+}
+
+// Add records a value.
+func (r *Recorder) Add(value float64) {
+	r.AddWeighted(value, 1)
+}
+
+// AddWeighted records a value with a specific weight.
+func (r *Recorder) AddWeighted(value, weight float64) {
+	r.shards[rand.IntN(len(r.shards))].AddWeighted(value, weight)
+}
+
+// Snapshot merges all shards into a new t-digest.
+// Use this for consistent reads of multiple quantiles.
+func (r *Recorder) Snapshot() *TDigest {
+	td := New(r.compression)
+
+	for _, shard := range r.shards {
+		td.Merge(shard)
+	}
+
+	return td
+}
+
+// Quantile returns the estimated value at the given quantile (0-1)
+// across all shards.
+func (r *Recorder) Quantile(q float64) float64 {
+	return r.Snapshot().Quantile(q)
+}
+
+// CDF returns the proportion of values <= x across all shards.
+func (r *Recorder) CDF(x float64) float64 {
+	return r.Snapshot().CDF(x)
+}
+
+// Count returns the total number of values recorded.
+func (r *Recorder) Count() float64 {
+	count := float64(0)
+	for _, shard := range r.shards {
+		count += shard.Count()
+	}
+
+	return count
+}
+
+// Min returns the minimum value recorded.
+func (r *Recorder) Min() float64 {
+	result := math.NaN()
+	for _, shard := range r.shards {
+		if v := shard.Min(); !math.IsNaN(v) && (math.IsNaN(result) || v < result) {
+			result = v
+		}
+	}
+
+	return result
+}
+
+// Max returns the maximum value recorded.
+func (r *Recorder) Max() float64 {
+	result := math.NaN()
+	for _, shard := range r.shards {
+		if v := shard.Max(); !math.IsNaN(v) && (math.IsNaN(result) || v > result) {
+			result = v
+		}
+	}
+
+	return result
+}
+
+// Mean returns the approximate mean of all values recorded.
+func (r *Recorder) Mean() float64 {
+	return r.Snapshot().Mean()
+}
+
+// Reset clears all shards.
+func (r *Recorder) Reset() {
+	for _, shard := range r.shards {
+		shard.Reset()
+	}
+}
+
+// Shards returns the number of shards.
+func (r *Recorder) Shards() int {
+	return len(r.shards)
+}
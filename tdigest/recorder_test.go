@@ -0,0 +1,117 @@
+package tdigest
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRecorder(t *testing.T) {
+	t.Run("create with explicit shards", func(t *testing.T) {
+		r := NewRecorder(100, 4)
+		assert.Equal(t, 4, r.Shards())
+		assert.Equal(t, 0.0, r.Count())
+	})
+
+	t.Run("zero shards uses GOMAXPROCS", func(t *testing.T) {
+		r := NewRecorder(100, 0)
+		assert.Equal(t, runtime.GOMAXPROCS(0), r.Shards())
+	})
+
+	t.Run("zero compression uses default", func(t *testing.T) {
+		r := NewRecorder(0, 2)
+		assert.Equal(t, 100.0, r.Snapshot().Compression())
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("empty recorder", func(t *testing.T) {
+		r := NewRecorder(100, 4)
+		assert.True(t, math.IsNaN(r.Quantile(0.5)))
+		assert.True(t, math.IsNaN(r.Min()))
+		assert.True(t, math.IsNaN(r.Max()))
+		assert.True(t, math.IsNaN(r.Mean()))
+	})
+
+	t.Run("quantiles across shards", func(t *testing.T) {
+		r := NewRecorder(100, 8)
+		for i := range 10000 {
+			r.Add(float64(i))
+		}
+
+		assert.Equal(t, 10000.0, r.Count())
+		assert.Equal(t, 0.0, r.Min())
+		assert.Equal(t, 9999.0, r.Max())
+		assert.InDelta(t, 5000, r.Quantile(0.5), 200)
+		assert.InDelta(t, 9900, r.Quantile(0.99), 100)
+		assert.InDelta(t, 0.5, r.CDF(5000), 0.03)
+		assert.InDelta(t, 4999.5, r.Mean(), 50)
+	})
+
+	t.Run("weighted values", func(t *testing.T) {
+		r := NewRecorder(100, 2)
+		r.AddWeighted(10, 3)
+		r.AddWeighted(20, 0)
+		assert.Equal(t, 3.0, r.Count())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		r := NewRecorder(100, 4)
+		for i := range 100 {
+			r.Add(float64(i))
+		}
+
+		r.Reset()
+		assert.Equal(t, 0.0, r.Count())
+	})
+
+	t.Run("concurrent add", func(t *testing.T) {
+		r := NewRecorder(100, 4)
+
+		var wg sync.WaitGroup
+		for g := range 8 {
+			wg.Add(1)
+			go func(offset int) {
+				defer wg.Done()
+				for i := range 1000 {
+					r.Add(float64(offset*1000 + i))
+				}
+			}(g)
+		}
+
+		// Query while writers are running
+		_ = r.Quantile(0.5)
+
+		wg.Wait()
+
+		assert.Equal(t, 8000.0, r.Count())
+		assert.InDelta(t, 4000, r.Quantile(0.5), 200)
+	})
+}
+
+func BenchmarkRecorder_Add(b *testing.B) {
+	r := NewRecorder(100, 0)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			r.Add(float64(i))
+			i++
+		}
+	})
+}
+
+func BenchmarkTDigest_AddParallel(b *testing.B) {
+	td := New(100)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			td.Add(float64(i))
+			i++
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/gob"
 	"math"
 	"sort"
+	"sync"
 )
 
 // TDigest is a data structure for accurate estimation of quantiles.
@@ -13,10 +14,16 @@ import (
 // - Ability to merge multiple t-digests
 // - Better accuracy than histograms
 //
+// Values are appended to an unsorted buffer and merged into the centroid
+// list in batches, so Add is an amortized O(1) append. All methods are
+// safe for concurrent use.
+//
 // Use cases: Monitoring, analytics, distributed systems, percentile calculation
 type TDigest struct {
+	mu          sync.Mutex
 	compression float64
 	centroids   []centroid
+	buffer      []centroid
 	count       float64
 	min         float64
 	max         float64
@@ -46,6 +53,7 @@ func New(compression float64) *TDigest {
 	return &TDigest{
 		compression: compression,
 		centroids:   make([]centroid, 0, int(compression)*2),
+		buffer:      make([]centroid, 0, bufferSize(compression)),
 		count:       0,
 		min:         math.Inf(1),
 		max:         math.Inf(-1),
@@ -63,6 +71,9 @@ func (td *TDigest) AddWeighted(value, weight float64) {
 		return
 	}
 
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
 	// Update min/max
 	if value < td.min {
 		td.min = value
@@ -71,16 +82,16 @@ func (td *TDigest) AddWeighted(value, weight float64) {
 		td.max = value
 	}
 
-	// Add as new centroid
-	td.centroids = append(td.centroids, centroid{
+	// Buffer as new centroid
+	td.buffer = append(td.buffer, centroid{
 		Mean:   value,
 		Weight: weight,
 	})
 	td.count += weight
 
-	// Compress if needed
-	if len(td.centroids) > int(td.compression)*2 {
-		td.compress()
+	// Merge buffer in batches
+	if len(td.buffer) >= bufferSize(td.compression) {
+		td.flush()
 	}
 }
 
@@ -91,6 +102,15 @@ func (td *TDigest) AddWeighted(value, weight float64) {
 //   - Quantile(0.95) returns 95th percentile
 //   - Quantile(0.99) returns 99th percentile
 func (td *TDigest) Quantile(q float64) float64 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	return td.quantile(q)
+}
+
+func (td *TDigest) quantile(q float64) float64 {
 	if len(td.centroids) == 0 {
 		return math.NaN()
 	}
@@ -102,11 +122,6 @@ func (td *TDigest) Quantile(q float64) float64 {
 		q = 1
 	}
 
-	// Sort centroids if needed
-	if !td.isSorted() {
-		td.sort()
-	}
-
 	if len(td.centroids) == 1 {
 		return td.centroids[0].Mean
 	}
@@ -119,52 +134,56 @@ func (td *TDigest) Quantile(q float64) float64 {
 		return td.max
 	}
 
-	// Find quantile using linear interpolation
+	// Find quantile by interpolating between centroid centers
 	index := q * td.count
-	weightSum := float64(0)
 
-	for i := 0; i < len(td.centroids); i++ {
-		c := &td.centroids[i]
-		weightSum += c.Weight
+	first := &td.centroids[0]
+	if index < first.Weight/2 {
+		return td.min + (index/(first.Weight/2))*(first.Mean-td.min)
+	}
 
-		if weightSum >= index {
-			// Interpolate between this centroid and previous
-			if i == 0 {
-				return c.Mean
-			}
+	weightSum := first.Weight / 2
 
-			prev := &td.centroids[i-1]
-			prevWeightSum := weightSum - c.Weight
+	for i := 0; i < len(td.centroids)-1; i++ {
+		c := &td.centroids[i]
+		next := &td.centroids[i+1]
+		step := (c.Weight + next.Weight) / 2
 
+		if weightSum+step > index {
 			// Linear interpolation
-			if c.Weight > 1 {
-				// Interpolate within centroid
-				fraction := (index - prevWeightSum) / c.Weight
-				return prev.Mean + fraction*(c.Mean-prev.Mean)
-			}
-
-			return c.Mean
+			fraction := (index - weightSum) / step
+			return c.Mean + fraction*(next.Mean-c.Mean)
 		}
+
+		weightSum += step
 	}
 
-	return td.max
+	last := &td.centroids[len(td.centroids)-1]
+	fraction := (index - weightSum) / (last.Weight / 2)
+
+	return last.Mean + math.Min(fraction, 1)*(td.max-last.Mean)
 }
 
 // CDF returns the cumulative distribution function value at x.
 // Returns the proportion of values <= x.
 func (td *TDigest) CDF(x float64) float64 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	return td.cdf(x)
+}
+
+func (td *TDigest) cdf(x float64) float64 {
 	if len(td.centroids) == 0 {
 		return math.NaN()
 	}
 
-	if !td.isSorted() {
-		td.sort()
-	}
-
 	if x < td.min {
 		return 0
 	}
-	if x > td.max {
+	if x >= td.max {
 		return 1
 	}
 
@@ -175,39 +194,50 @@ func (td *TDigest) CDF(x float64) float64 {
 		return 1
 	}
 
-	weightSum := float64(0)
-
-	for i := 0; i < len(td.centroids); i++ {
-		c := &td.centroids[i]
+	first := &td.centroids[0]
+	if x < first.Mean {
+		if first.Mean == td.min {
+			return 0
+		}
+		return (x - td.min) / (first.Mean - td.min) * (first.Weight / 2) / td.count
+	}
 
-		if x < c.Mean {
-			// Interpolate between previous and current centroid
-			if i == 0 {
-				return 0
-			}
+	weightSum := first.Weight / 2
 
-			prev := &td.centroids[i-1]
-			prevWeightSum := weightSum
+	for i := 0; i < len(td.centroids)-1; i++ {
+		c := &td.centroids[i]
+		next := &td.centroids[i+1]
+		step := (c.Weight + next.Weight) / 2
 
-			// Linear interpolation
-			fraction := (x - prev.Mean) / (c.Mean - prev.Mean)
-			return (prevWeightSum + fraction*c.Weight) / td.count
+		if x < next.Mean {
+			// Linear interpolation between centroid centers
+			fraction := (x - c.Mean) / (next.Mean - c.Mean)
+			return (weightSum + fraction*step) / td.count
 		}
 
-		weightSum += c.Weight
+		weightSum += step
 	}
 
-	return 1
+	last := &td.centroids[len(td.centroids)-1]
+	fraction := (x - last.Mean) / (td.max - last.Mean)
+
+	return (weightSum + fraction*(last.Weight/2)) / td.count
 }
 
 // Count returns the total number of values added to the t-digest.
 func (td *TDigest) Count() float64 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
 	return td.count
 }
 
 // Min returns the minimum value seen.
 func (td *TDigest) Min() float64 {
-	if len(td.centroids) == 0 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	if td.count == 0 {
 		return math.NaN()
 	}
 	return td.min
@@ -215,7 +245,10 @@ func (td *TDigest) Min() float64 {
 
 // Max returns the maximum value seen.
 func (td *TDigest) Max() float64 {
-	if len(td.centroids) == 0 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	if td.count == 0 {
 		return math.NaN()
 	}
 	return td.max
@@ -223,36 +256,72 @@ func (td *TDigest) Max() float64 {
 
 // Mean returns the approximate mean of all values.
 func (td *TDigest) Mean() float64 {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
+	return td.mean()
+}
+
+func (td *TDigest) mean() float64 {
 	if len(td.centroids) == 0 || td.count == 0 {
 		return math.NaN()
 	}
 
+	return td.sum() / td.count
+}
+
+// sum returns the sum of all values. Caller must hold td.mu with the buffer flushed.
+func (td *TDigest) sum() float64 {
 	sum := float64(0)
 	for i := range td.centroids {
 		sum += td.centroids[i].Mean * td.centroids[i].Weight
 	}
 
-	return sum / td.count
+	return sum
 }
 
 // Merge combines another t-digest into this one.
 // Both t-digests should have the same compression factor for best results.
 func (td *TDigest) Merge(other *TDigest) {
-	if other == nil || len(other.centroids) == 0 {
+	if other == nil || other == td {
+		return
+	}
+
+	// Copy the other digest under its own lock to avoid lock ordering issues
+	other.mu.Lock()
+	other.flush()
+	centroids := append([]centroid(nil), other.centroids...)
+	count, minValue, maxValue := other.count, other.min, other.max
+	other.mu.Unlock()
+
+	if len(centroids) == 0 {
 		return
 	}
 
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.merge(centroids, count, minValue, maxValue)
+}
+
+// merge adds centroids with the given totals and compresses the result.
+// Caller must hold td.mu.
+func (td *TDigest) merge(centroids []centroid, count, minValue, maxValue float64) {
+	td.flush()
+
 	// Update min/max
-	if other.min < td.min {
-		td.min = other.min
+	if minValue < td.min {
+		td.min = minValue
 	}
-	if other.max > td.max {
-		td.max = other.max
+	if maxValue > td.max {
+		td.max = maxValue
 	}
 
 	// Add all centroids
-	td.centroids = append(td.centroids, other.centroids...)
-	td.count += other.count
+	td.centroids = append(td.centroids, centroids...)
+	td.count += count
 
 	// Compress the combined result
 	td.compress()
@@ -260,12 +329,30 @@ func (td *TDigest) Merge(other *TDigest) {
 
 // Reset clears all data from the t-digest.
 func (td *TDigest) Reset() {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
 	td.centroids = td.centroids[:0]
+	td.buffer = td.buffer[:0]
 	td.count = 0
 	td.min = math.Inf(1)
 	td.max = math.Inf(-1)
 }
 
+// scale multiplies all weights by factor.
+func (td *TDigest) scale(factor float64) {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	for i := range td.centroids {
+		td.centroids[i].Weight *= factor
+	}
+	for i := range td.buffer {
+		td.buffer[i].Weight *= factor
+	}
+	td.count *= factor
+}
+
 // Compression returns the compression factor.
 func (td *TDigest) Compression() float64 {
 	return td.compression
@@ -273,6 +360,11 @@ func (td *TDigest) Compression() float64 {
 
 // Export serializes the t-digest for storage or transmission.
 func (td *TDigest) Export() ([]byte, error) {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	td.flush()
+
 	var buf []byte
 	enc := gob.NewEncoder(&gobWriter{buf: &buf})
 
@@ -303,6 +395,7 @@ func Import(data []byte) (*TDigest, error) {
 	td := &TDigest{
 		compression: tdigestData.Compression,
 		centroids:   tdigestData.Centroids,
+		buffer:      make([]centroid, 0, bufferSize(tdigestData.Compression)),
 		count:       tdigestData.Count,
 		min:         tdigestData.Min,
 		max:         tdigestData.Max,
@@ -313,6 +406,26 @@ func Import(data []byte) (*TDigest, error) {
 
 // Internal methods
 
+// bufferSize returns the number of unmerged values kept before a flush.
+func bufferSize(compression float64) int {
+	return int(compression) * 4
+}
+
+// flush merges buffered values into the centroid list, leaving it sorted.
+// Caller must hold td.mu.
+func (td *TDigest) flush() {
+	if len(td.buffer) > 0 {
+		td.centroids = append(td.centroids, td.buffer...)
+		td.buffer = td.buffer[:0]
+	}
+
+	if len(td.centroids) > int(td.compression)*2 {
+		td.compress()
+	} else if !td.isSorted() {
+		td.sort()
+	}
+}
+
 func (td *TDigest) compress() {
 	if len(td.centroids) <= 1 {
 		return
@@ -321,30 +434,30 @@ func (td *TDigest) compress() {
 	// Sort centroids by mean
 	td.sort()
 
-	// Merge centroids based on t-digest algorithm
+	// Merge adjacent centroids while the merged centroid spans at most
+	// one unit of the scale function
 	newCentroids := make([]centroid, 0, len(td.centroids))
 	current := td.centroids[0]
 
-	weightSum := float64(0)
+	weightSum := current.Weight
+	qLimit := td.inverseScaleFunction(td.scaleFunction(0) + 1)
 
 	for i := 1; i < len(td.centroids); i++ {
 		c := td.centroids[i]
-		weightSum += current.Weight
-
-		// Check if we should merge this centroid
-		q := weightSum / td.count
-		k := td.scaleFunction(q)
 
-		if current.Weight+c.Weight <= td.maxWeight(k) {
+		if (weightSum+c.Weight)/td.count <= qLimit {
 			// Merge centroids
 			totalWeight := current.Weight + c.Weight
-			current.Mean = (current.Mean*current.Weight + c.Mean*c.Weight) / totalWeight
+			current.Mean += (c.Mean - current.Mean) * c.Weight / totalWeight
 			current.Weight = totalWeight
 		} else {
 			// Start new centroid
 			newCentroids = append(newCentroids, current)
+			qLimit = td.inverseScaleFunction(td.scaleFunction(weightSum/td.count) + 1)
 			current = c
 		}
+
+		weightSum += c.Weight
 	}
 
 	// Add last centroid
@@ -354,12 +467,15 @@ func (td *TDigest) compress() {
 }
 
 func (td *TDigest) scaleFunction(q float64) float64 {
-	// Using k_2 scale function (better for extreme quantiles)
+	// Using k_1 scale function (better for extreme quantiles)
 	return (td.compression / (2 * math.Pi)) * math.Asin(2*q-1)
 }
 
-func (td *TDigest) maxWeight(k float64) float64 {
-	return (4 * td.count * k * (1 - k)) / td.compression
+func (td *TDigest) inverseScaleFunction(k float64) float64 {
+	if k >= td.compression/4 {
+		return 1
+	}
+	return (math.Sin(k*2*math.Pi/td.compression) + 1) / 2
 }
 
 func (td *TDigest) sort() {
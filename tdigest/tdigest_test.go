@@ -4,6 +4,7 @@ import (
 	"math"
 	"math/rand"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -415,6 +416,101 @@ func TestEdgeCases(t *testing.T) {
 	})
 }
 
+func TestConcurrency(t *testing.T) {
+	t.Run("concurrent add and query", func(t *testing.T) {
+		td := New(100)
+
+		var wg sync.WaitGroup
+		for g := range 8 {
+			wg.Add(1)
+			go func(offset int) {
+				defer wg.Done()
+				for i := range 1000 {
+					td.Add(float64(offset*1000 + i))
+					if i%100 == 0 {
+						_ = td.Quantile(0.99)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 8000.0, td.Count())
+		assert.InDelta(t, 4000, td.Quantile(0.5), 200)
+	})
+
+	t.Run("concurrent merge", func(t *testing.T) {
+		td1 := New(100)
+		td2 := New(100)
+		for i := range 1000 {
+			td1.Add(float64(i))
+			td2.Add(float64(i))
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			td1.Merge(td2)
+		}()
+		go func() {
+			defer wg.Done()
+			td2.Merge(td1)
+		}()
+		wg.Wait()
+
+		assert.GreaterOrEqual(t, td1.Count(), 2000.0)
+		assert.GreaterOrEqual(t, td2.Count(), 2000.0)
+	})
+
+	t.Run("merge with self is ignored", func(t *testing.T) {
+		td := New(100)
+		td.Add(1)
+		td.Merge(td)
+		assert.Equal(t, 1.0, td.Count())
+	})
+}
+
+func TestBuffer(t *testing.T) {
+	t.Run("buffered values are visible to queries", func(t *testing.T) {
+		td := New(100)
+		td.Add(5)
+		td.Add(1)
+		td.Add(3)
+
+		assert.Equal(t, 1.0, td.Min())
+		assert.Equal(t, 5.0, td.Max())
+		assert.Equal(t, 3.0, td.Mean())
+		assert.Equal(t, 3.0, td.Quantile(0.5))
+	})
+
+	t.Run("buffer flushes in batches", func(t *testing.T) {
+		td := New(10)
+		for i := range bufferSize(10) - 1 {
+			td.Add(float64(i))
+		}
+		assert.Len(t, td.buffer, bufferSize(10)-1)
+
+		td.Add(100)
+		assert.Empty(t, td.buffer)
+		assert.LessOrEqual(t, len(td.centroids), bufferSize(10))
+	})
+
+	t.Run("export includes buffered values", func(t *testing.T) {
+		td := New(100)
+		td.Add(1)
+		td.Add(2)
+
+		data, err := td.Export()
+		require.NoError(t, err)
+
+		imported, err := Import(data)
+		require.NoError(t, err)
+		assert.Equal(t, 2.0, imported.Count())
+		assert.Equal(t, 1.5, imported.Mean())
+	})
+}
+
 func BenchmarkTDigest_Add(b *testing.B) {
 	td := New(100)
 	b.ReportAllocs()
@@ -0,0 +1,308 @@
+package tdigest
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Window is a time-windowed t-digest built from a ring of sub-digests.
+// Each sub-digest covers a fixed time slice; slices are rotated by the clock
+// and merged on query, so old traffic drops out of the results.
+//
+// Example: NewWindow(100, 10*time.Second, 6) keeps the last minute of data
+// in six 10-second slices.
+//
+// Use cases: Rolling p99 dashboards, recent-latency SLO checks
+type Window struct {
+	mu          sync.Mutex
+	compression float64
+	width       time.Duration
+	buckets     []*TDigest
+	epochs      []int64
+	latest      int64 // Newest epoch written
+}
+
+// NewWindow creates a windowed t-digest with the given number of slices,
+// each covering width of time.
+//
+// Defaults: width <= 0 uses 10 seconds, buckets <= 0 uses 6.
+func NewWindow(compression float64, width time.Duration, buckets int) *Window {
+	if compression <= 0 {
+		compression = 100
+	}
+	if width <= 0 {
+		width = 10 * time.Second
+	}
+	if buckets <= 0 {
+		buckets = 6
+	}
+
+	w := &Window{
+		compression: compression,
+		width:       width,
+		buckets:     make([]*TDigest, buckets),
+		epochs:      make([]int64, buckets),
+		latest:      math.MinInt64,
+	}
+
+	for i := range w.buckets {
+		w.buckets[i] = New(compression)
+		w.epochs[i] = math.MinInt64
+	}
+
+	return w
+}
+
+// Add inserts a value at the current time.
+func (w *Window) Add(value float64) {
+	w.AddWeightedAt(value, 1, time.Now())
+}
+
+// AddAt inserts a value at a specific time.
+// Useful for testing or processing historical data.
+func (w *Window) AddAt(value float64, t time.Time) {
+	w.AddWeightedAt(value, 1, t)
+}
+
+// AddWeightedAt inserts a value with a specific weight at a specific time.
+// Values older than the window span are ignored.
+func (w *Window) AddWeightedAt(value, weight float64, t time.Time) {
+	epoch := w.epoch(t)
+	slot := w.slot(epoch)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Measured against the newest slice, since the slot may still hold an
+	// even older one that would otherwise be reset for this value
+	if epoch < w.latest && w.latest-epoch >= int64(len(w.buckets)) {
+		return
+	}
+	w.latest = max(w.latest, epoch)
+
+	switch {
+	case epoch > w.epochs[slot]:
+		// Slot holds an expired slice, reuse it
+		w.buckets[slot].Reset()
+		w.epochs[slot] = epoch
+	case epoch < w.epochs[slot]:
+		// Value is older than the window
+		return
+	}
+
+	w.buckets[slot].AddWeighted(value, weight)
+}
+
+// Digest returns a new t-digest with all values from the last d.
+// If d is not positive or exceeds the window span, the whole window is used.
+func (w *Window) Digest(d time.Duration) *TDigest {
+	return w.DigestAt(d, time.Now())
+}
+
+// DigestAt returns a new t-digest with all values in the d preceding t.
+// Slices are included whole, so the covered period is d rounded up to
+// the slice width.
+func (w *Window) DigestAt(d time.Duration, t time.Time) *TDigest {
+	n := int64(len(w.buckets))
+	if d > 0 {
+		n = min(n, int64((d+w.width-1)/w.width))
+	}
+
+	current := w.epoch(t)
+	td := New(w.compression)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, epoch := range w.epochs {
+		if epoch <= current && epoch > current-n {
+			td.Merge(w.buckets[i])
+		}
+	}
+
+	return td
+}
+
+// Quantile returns the estimated value at the given quantile (0-1)
+// over the whole window.
+func (w *Window) Quantile(q float64) float64 {
+	return w.Digest(0).Quantile(q)
+}
+
+// CDF returns the proportion of values <= x over the whole window.
+func (w *Window) CDF(x float64) float64 {
+	return w.Digest(0).CDF(x)
+}
+
+// QuantileOver returns the estimated value at the given quantile (0-1)
+// over the last d.
+func (w *Window) QuantileOver(q float64, d time.Duration) float64 {
+	return w.Digest(d).Quantile(q)
+}
+
+// CDFOver returns the proportion of values <= x over the last d.
+func (w *Window) CDFOver(x float64, d time.Duration) float64 {
+	return w.Digest(d).CDF(x)
+}
+
+// Reset clears all slices.
+func (w *Window) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range w.buckets {
+		w.buckets[i].Reset()
+		w.epochs[i] = math.MinInt64
+	}
+	w.latest = math.MinInt64
+}
+
+// Span returns the total time covered by the window.
+func (w *Window) Span() time.Duration {
+	return w.width * time.Duration(len(w.buckets))
+}
+
+func (w *Window) epoch(t time.Time) int64 {
+	ns := t.UnixNano()
+	epoch := ns / int64(w.width)
+	if ns < 0 && ns%int64(w.width) != 0 {
+		epoch--
+	}
+
+	return epoch
+}
+
+func (w *Window) slot(epoch int64) int {
+	n := int64(len(w.buckets))
+	return int(((epoch % n) + n) % n)
+}
+
+// Decaying is a t-digest whose centroid weights decay exponentially over
+// time, so recent values dominate quantile estimates without hard window
+// boundaries.
+//
+// Decay is implemented as forward decay: new values are added with a
+// weight that grows over time, which is equivalent to shrinking all older
+// weights, and the digest is rescaled when the growth factor gets large.
+// Min and Max are not decayed.
+type Decaying struct {
+	mu        sync.Mutex
+	td        *TDigest
+	halfLife  time.Duration
+	decayRate float64
+	landmark  time.Time
+}
+
+// decayRescaleExponent bounds the forward-decay factor to e^exponent before
+// weights are rescaled, keeping them well inside float64 range.
+const decayRescaleExponent = 64
+
+// NewDecaying creates a decaying t-digest with the specified half-life.
+//
+// Half-life determines how quickly old values lose weight:
+//   - Short half-life (10s-1m): Fast reaction to latency shifts
+//   - Long half-life (5m-1h): Smooth, stable percentiles
+func NewDecaying(compression float64, halfLife time.Duration) *Decaying {
+	if halfLife <= 0 {
+		halfLife = 60 * time.Second
+	}
+
+	return &Decaying{
+		td:        New(compression),
+		halfLife:  halfLife,
+		decayRate: math.Ln2 / halfLife.Seconds(),
+	}
+}
+
+// Add inserts a value at the current time.
+func (d *Decaying) Add(value float64) {
+	d.AddWeightedAt(value, 1, time.Now())
+}
+
+// AddAt inserts a value at a specific time.
+// Useful for testing or processing historical data.
+func (d *Decaying) AddAt(value float64, t time.Time) {
+	d.AddWeightedAt(value, 1, t)
+}
+
+// AddWeightedAt inserts a value with a specific weight at a specific time.
+func (d *Decaying) AddWeightedAt(value, weight float64, t time.Time) {
+	if weight <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.landmark.IsZero() {
+		d.landmark = t
+	}
+
+	exponent := d.decayRate * t.Sub(d.landmark).Seconds()
+	if exponent > decayRescaleExponent {
+		// Move the landmark forward, shrinking existing weights
+		d.td.scale(math.Exp(-exponent))
+		d.landmark = t
+		exponent = 0
+	}
+
+	d.td.AddWeighted(value, weight*math.Exp(exponent))
+}
+
+// Quantile returns the estimated value at the given quantile (0-1),
+// weighting recent values more heavily.
+func (d *Decaying) Quantile(q float64) float64 {
+	return d.td.Quantile(q)
+}
+
+// CDF returns the decayed proportion of values <= x.
+func (d *Decaying) CDF(x float64) float64 {
+	return d.td.CDF(x)
+}
+
+// Mean returns the decayed mean of all values.
+func (d *Decaying) Mean() float64 {
+	return d.td.Mean()
+}
+
+// Count returns the decayed total weight at the current time.
+func (d *Decaying) Count() float64 {
+	return d.CountAt(time.Now())
+}
+
+// CountAt returns the decayed total weight at a specific time.
+func (d *Decaying) CountAt(t time.Time) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.landmark.IsZero() {
+		return 0
+	}
+
+	return d.td.Count() * math.Exp(-d.decayRate*t.Sub(d.landmark).Seconds())
+}
+
+// Min returns the minimum value seen since the last reset.
+func (d *Decaying) Min() float64 {
+	return d.td.Min()
+}
+
+// Max returns the maximum value seen since the last reset.
+func (d *Decaying) Max() float64 {
+	return d.td.Max()
+}
+
+// Reset clears all data.
+func (d *Decaying) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.td.Reset()
+	d.landmark = time.Time{}
+}
+
+// HalfLife returns the configured half-life.
+func (d *Decaying) HalfLife() time.Duration {
+	return d.halfLife
+}
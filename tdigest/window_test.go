@@ -0,0 +1,253 @@
+package tdigest
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWindow(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		w := NewWindow(0, 0, 0)
+		assert.Equal(t, time.Minute, w.Span())
+		assert.Len(t, w.buckets, 6)
+		assert.Equal(t, 100.0, w.compression)
+	})
+
+	t.Run("custom span", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 30)
+		assert.Equal(t, 30*time.Second, w.Span())
+	})
+}
+
+func TestWindow(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("values within window", func(t *testing.T) {
+		w := NewWindow(100, 10*time.Second, 6)
+		for i := range 100 {
+			w.AddAt(float64(i), base.Add(time.Duration(i)*100*time.Millisecond))
+		}
+
+		td := w.DigestAt(0, base.Add(10*time.Second))
+		assert.Equal(t, 100.0, td.Count())
+		assert.InDelta(t, 49.5, td.Quantile(0.5), 2)
+	})
+
+	t.Run("old slices rotate out", func(t *testing.T) {
+		w := NewWindow(100, 10*time.Second, 6)
+		for i := range 100 {
+			w.AddAt(1000, base.Add(time.Duration(i)*time.Millisecond))
+		}
+		for i := range 100 {
+			w.AddAt(float64(i), base.Add(time.Minute+time.Duration(i)*time.Millisecond))
+		}
+
+		td := w.DigestAt(0, base.Add(time.Minute+time.Second))
+		assert.Equal(t, 100.0, td.Count())
+		assert.Equal(t, 99.0, td.Max())
+	})
+
+	t.Run("slot reuse resets old data", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 2)
+		w.AddAt(1, base)
+		w.AddAt(2, base.Add(2*time.Second))
+
+		td := w.DigestAt(0, base.Add(2*time.Second))
+		assert.Equal(t, 1.0, td.Count())
+		assert.Equal(t, 2.0, td.Min())
+	})
+
+	t.Run("values older than window are ignored", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 2)
+		w.AddAt(1, base.Add(5*time.Second))
+		w.AddAt(2, base.Add(time.Second))
+
+		td := w.DigestAt(0, base.Add(5*time.Second))
+		assert.Equal(t, 1.0, td.Count())
+		assert.Equal(t, 1.0, td.Max())
+	})
+
+	t.Run("old value does not reclaim an expired slot", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 2)
+		w.AddAt(1, base.Add(5*time.Second))
+		w.AddAt(2, base.Add(10*time.Second))
+
+		// Slot of second 7 still holds second 5, which is even older
+		w.AddAt(3, base.Add(7*time.Second))
+
+		assert.Equal(t, 0.0, w.DigestAt(0, base.Add(8*time.Second)).Count())
+		td := w.DigestAt(0, base.Add(10*time.Second))
+		assert.Equal(t, 1.0, td.Count())
+		assert.Equal(t, 2.0, td.Max())
+	})
+
+	t.Run("digest over shorter duration", func(t *testing.T) {
+		w := NewWindow(100, 10*time.Second, 6)
+		for s := range 60 {
+			w.AddAt(float64(s), base.Add(time.Duration(s)*time.Second))
+		}
+
+		now := base.Add(59 * time.Second)
+
+		last10 := w.DigestAt(10*time.Second, now)
+		assert.Equal(t, 10.0, last10.Count())
+		assert.Equal(t, 50.0, last10.Min())
+
+		last30 := w.DigestAt(25*time.Second, now)
+		assert.Equal(t, 30.0, last30.Count())
+		assert.Equal(t, 30.0, last30.Min())
+
+		all := w.DigestAt(time.Hour, now)
+		assert.Equal(t, 60.0, all.Count())
+	})
+
+	t.Run("query after idle period is empty", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 5)
+		w.AddAt(1, base)
+
+		td := w.DigestAt(0, base.Add(time.Hour))
+		assert.Equal(t, 0.0, td.Count())
+		assert.True(t, math.IsNaN(td.Quantile(0.5)))
+	})
+
+	t.Run("times before unix epoch", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 3)
+		old := time.Unix(-10, -500)
+		w.AddAt(1, old)
+		w.AddAt(2, old.Add(time.Second))
+
+		td := w.DigestAt(0, old.Add(time.Second))
+		assert.Equal(t, 2.0, td.Count())
+	})
+
+	t.Run("wall clock helpers", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 10)
+		for i := 1; i <= 100; i++ {
+			w.Add(float64(i))
+		}
+
+		assert.InDelta(t, 50, w.Quantile(0.5), 2)
+		assert.InDelta(t, 50, w.QuantileOver(0.5, 5*time.Second), 2)
+		assert.InDelta(t, 0.5, w.CDF(50), 0.03)
+		assert.InDelta(t, 0.5, w.CDFOver(50, 5*time.Second), 0.03)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		w := NewWindow(100, time.Second, 3)
+		w.AddAt(1, base)
+		w.Reset()
+
+		assert.Equal(t, 0.0, w.DigestAt(0, base).Count())
+	})
+
+	t.Run("concurrent add and query", func(t *testing.T) {
+		w := NewWindow(100, 10*time.Millisecond, 10)
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 1000 {
+					w.Add(float64(i))
+					if i%100 == 0 {
+						_ = w.Quantile(0.99)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestDecaying(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+
+	t.Run("defaults", func(t *testing.T) {
+		d := NewDecaying(100, 0)
+		assert.Equal(t, time.Minute, d.HalfLife())
+		assert.Equal(t, 0.0, d.CountAt(base))
+	})
+
+	t.Run("count halves after half-life", func(t *testing.T) {
+		d := NewDecaying(100, time.Minute)
+		for range 100 {
+			d.AddAt(1, base)
+		}
+
+		assert.InDelta(t, 100, d.CountAt(base), 1e-9)
+		assert.InDelta(t, 50, d.CountAt(base.Add(time.Minute)), 1e-9)
+		assert.InDelta(t, 25, d.CountAt(base.Add(2*time.Minute)), 1e-9)
+	})
+
+	t.Run("recent values dominate quantiles", func(t *testing.T) {
+		d := NewDecaying(100, 10*time.Second)
+		for i := range 1000 {
+			d.AddAt(100+float64(i%10), base)
+		}
+		for i := range 1000 {
+			d.AddAt(float64(i%10), base.Add(time.Minute))
+		}
+
+		// Old values carry 1/64 of the weight of new ones
+		assert.Less(t, d.Quantile(0.5), 10.0)
+		assert.Greater(t, d.Quantile(0.999), 100.0)
+		assert.InDelta(t, 1.0/65, 1-d.CDF(50), 0.005)
+		assert.Equal(t, 0.0, d.Min())
+		assert.Equal(t, 109.0, d.Max())
+		assert.Less(t, d.Mean(), 10.0)
+	})
+
+	t.Run("rescale keeps weights finite", func(t *testing.T) {
+		d := NewDecaying(100, time.Second)
+		for i := range 1000 {
+			d.AddAt(float64(i), base.Add(time.Duration(i)*time.Second))
+		}
+
+		now := base.Add(999 * time.Second)
+		count := d.CountAt(now)
+		assert.False(t, math.IsInf(count, 0))
+		assert.False(t, math.IsNaN(count))
+		// Geometric series sum with ratio 1/2 converges to 2
+		assert.InDelta(t, 2, count, 0.01)
+		assert.InDelta(t, 998, d.Quantile(0.5), 2)
+	})
+
+	t.Run("zero weight is ignored", func(t *testing.T) {
+		d := NewDecaying(100, time.Second)
+		d.AddWeightedAt(1, 0, base)
+		assert.Equal(t, 0.0, d.CountAt(base))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		d := NewDecaying(100, time.Second)
+		d.Add(1)
+		d.Reset()
+		assert.Equal(t, 0.0, d.Count())
+		assert.True(t, math.IsNaN(d.Quantile(0.5)))
+	})
+}
+
+func BenchmarkWindow_Add(b *testing.B) {
+	w := NewWindow(100, 10*time.Second, 6)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		w.Add(float64(i))
+		i++
+	}
+}
+
+func BenchmarkDecaying_Add(b *testing.B) {
+	d := NewDecaying(100, time.Minute)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		d.Add(float64(i))
+		i++
+	}
+}
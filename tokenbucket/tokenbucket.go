@@ -0,0 +1,421 @@
+package tokenbucket
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrExceedsBurst indicates a request for more tokens than the bucket can hold
+	ErrExceedsBurst = errors.New("tokenbucket: n exceeds burst")
+
+	// ErrExceedsDeadline indicates that the required wait is longer than the context allows
+	ErrExceedsDeadline = errors.New("tokenbucket: wait exceeds context deadline")
+)
+
+// Limiter is a keyed token bucket rate limiter implemented with the Generic
+// Cell Rate Algorithm (GCRA). Each key has a bucket holding up to burst tokens
+// that refills at rate tokens per second; a request for n tokens is allowed
+// if the bucket holds at least n tokens.
+//
+// Instead of a token count and refill timestamp, GCRA stores a single
+// theoretical arrival time (TAT) per key: the time at which the bucket will be
+// full again. A request for n tokens at time now is allowed if
+// TAT + n*interval - burst*interval <= now, where interval = 1/rate.
+//
+// Properties:
+//   - O(1) per operation (map lookup + time arithmetic)
+//   - O(n) memory where n = number of tracked keys (one timestamp per key)
+//   - Full buckets are indistinguishable from untracked keys and are dropped
+//   - Thread-safe
+//   - Max tracked keys with fullest-bucket eviction
+type Limiter struct {
+	mu       sync.Mutex
+	entries  map[string]time.Time // Theoretical arrival time per key
+	rate     float64
+	burst    int
+	interval time.Duration // Time to refill one token
+	maxKeys  int
+	cleanup  *time.Ticker
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// New creates a new Limiter refilling rate tokens per second into buckets
+// holding up to burst tokens, with at most maxKeys tracked keys.
+//
+// Zero or negative rate defaults to 1, burst to 1 and maxKeys to 1000.
+// If cleanupInterval is positive, a background goroutine runs at that interval
+// to remove full buckets. If zero or negative, full buckets are only removed
+// lazily during eviction. Call Stop() to release the background goroutine when done.
+func New(rate float64, burst int, maxKeys int, cleanupInterval time.Duration) *Limiter {
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		rate = 1
+	}
+
+	if burst <= 0 {
+		burst = 1
+	}
+
+	if maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	interval := time.Duration(float64(time.Second) / rate)
+	if interval <= 0 {
+		interval = 1
+	}
+
+	l := &Limiter{
+		entries:  make(map[string]time.Time, maxKeys),
+		rate:     rate,
+		burst:    burst,
+		interval: interval,
+		maxKeys:  maxKeys,
+		done:     make(chan struct{}),
+	}
+
+	if cleanupInterval > 0 {
+		l.cleanup = time.NewTicker(cleanupInterval)
+		go l.cleanupLoop()
+	}
+
+	return l
+}
+
+// Stop stops the background cleanup goroutine if one was started.
+// It is safe to call multiple times.
+func (l *Limiter) Stop() {
+	l.stopOnce.Do(func() {
+		if l.cleanup != nil {
+			l.cleanup.Stop()
+		}
+		close(l.done)
+	})
+}
+
+func (l *Limiter) cleanupLoop() {
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-l.cleanup.C:
+			l.removeFull(time.Now())
+		}
+	}
+}
+
+func (l *Limiter) removeFull(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, tat := range l.entries {
+		if !tat.After(now) {
+			delete(l.entries, key)
+		}
+	}
+}
+
+// Rate returns the refill rate in tokens per second.
+func (l *Limiter) Rate() float64 {
+	return l.rate
+}
+
+// Burst returns the bucket capacity.
+func (l *Limiter) Burst() int {
+	return l.burst
+}
+
+// Allow reports whether one token is available for the key and consumes it.
+func (l *Limiter) Allow(key string) bool {
+	return l.AllowNAt(key, 1, time.Now())
+}
+
+// AllowN reports whether n tokens are available for the key and consumes them.
+// Nothing is consumed if the request is denied.
+func (l *Limiter) AllowN(key string, n int) bool {
+	return l.AllowNAt(key, n, time.Now())
+}
+
+// AllowNAt is like AllowN but evaluates the bucket at time t.
+func (l *Limiter) AllowNAt(key string, n int, t time.Time) bool {
+	if n <= 0 {
+		return true
+	}
+
+	if n > l.burst {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tat := l.tat(key, t)
+	newTat := tat.Add(time.Duration(n) * l.interval)
+
+	if newTat.Sub(t) > l.tolerance() {
+		return false
+	}
+
+	l.store(key, newTat, t)
+
+	return true
+}
+
+// Reservation holds tokens taken from a bucket ahead of time.
+// The caller must wait Delay before acting, or Cancel the reservation.
+type Reservation struct {
+	limiter *Limiter
+	key     string
+	tokens  int
+	ok      bool
+	delay   time.Duration
+	tat     time.Time // TAT right after the reservation was made
+}
+
+// OK reports whether the reservation was made. It is false only when
+// more tokens than the burst were requested.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long the caller must wait from the reservation time
+// before acting on it. Zero means the tokens were available immediately.
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel returns the reserved tokens to the bucket as far as possible.
+// Tokens are only restored if no later reservation was made for the same
+// key; otherwise those later reservations already account for them.
+func (r *Reservation) Cancel() {
+	if !r.ok || r.tokens == 0 {
+		return
+	}
+
+	r.limiter.mu.Lock()
+	defer r.limiter.mu.Unlock()
+
+	tat, ok := r.limiter.entries[r.key]
+	if !ok || !tat.Equal(r.tat) {
+		return
+	}
+
+	restored := tat.Add(-time.Duration(r.tokens) * r.limiter.interval)
+	r.limiter.entries[r.key] = restored
+	r.tokens = 0
+}
+
+// Reserve reserves one token for the key; see ReserveN.
+func (l *Limiter) Reserve(key string) *Reservation {
+	return l.ReserveNAt(key, 1, time.Now())
+}
+
+// ReserveN reserves n tokens for the key and returns how long the caller must
+// wait before they are available. Unlike AllowN, the tokens are always taken,
+// possibly driving the bucket into debt that later requests have to wait out.
+// The reservation is not OK if n exceeds the burst.
+func (l *Limiter) ReserveN(key string, n int) *Reservation {
+	return l.ReserveNAt(key, n, time.Now())
+}
+
+// ReserveNAt is like ReserveN but evaluates the bucket at time t.
+func (l *Limiter) ReserveNAt(key string, n int, t time.Time) *Reservation {
+	r := &Reservation{
+		limiter: l,
+		key:     key,
+		tokens:  max(n, 0),
+	}
+
+	if n > l.burst {
+		return r
+	}
+
+	r.ok = true
+
+	if n <= 0 {
+		return r
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tat := l.tat(key, t)
+	newTat := tat.Add(time.Duration(n) * l.interval)
+
+	r.delay = max(newTat.Sub(t)-l.tolerance(), 0)
+	r.tat = newTat
+
+	l.store(key, newTat, t)
+
+	return r
+}
+
+// Wait blocks until one token is available for the key; see WaitN.
+func (l *Limiter) Wait(ctx context.Context, key string) error {
+	return l.WaitN(ctx, key, 1)
+}
+
+// WaitN blocks until n tokens are available for the key and consumes them.
+// It returns ErrExceedsBurst if n exceeds the burst, ErrExceedsDeadline if the
+// wait would outlast the context deadline, or the context error if the context
+// is done while waiting. Reserved tokens are returned to the bucket on error.
+func (l *Limiter) WaitN(ctx context.Context, key string, n int) error {
+	if n > l.burst {
+		return ErrExceedsBurst
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r := l.ReserveNAt(key, n, now)
+
+	if r.delay == 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && now.Add(r.delay).After(deadline) {
+		r.Cancel()
+		return ErrExceedsDeadline
+	}
+
+	timer := time.NewTimer(r.delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// Status describes the state of a key's bucket, in the form needed for
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and Retry-After
+// HTTP response headers.
+type Status struct {
+	Limit      int           // Bucket capacity (burst)
+	Remaining  int           // Whole tokens currently available
+	RetryAfter time.Duration // Time until the next token is available; zero if one is available now
+	Reset      time.Duration // Time until the bucket is full again
+}
+
+// Status returns the bucket state of the key without consuming tokens.
+func (l *Limiter) Status(key string) Status {
+	return l.StatusAt(key, time.Now())
+}
+
+// StatusAt is like Status but evaluates the bucket at time t.
+func (l *Limiter) StatusAt(key string, t time.Time) Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tat := l.tat(key, t)
+	reset := tat.Sub(t)
+
+	// Tokens spent = ceil(reset / interval); the remainder refills gradually
+	used := int((reset + l.interval - 1) / l.interval)
+	remaining := max(l.burst-used, 0)
+
+	var retryAfter time.Duration
+	if remaining == 0 {
+		retryAfter = max(reset+l.interval-l.tolerance(), 0)
+	}
+
+	return Status{
+		Limit:      l.burst,
+		Remaining:  remaining,
+		RetryAfter: retryAfter,
+		Reset:      reset,
+	}
+}
+
+// Remaining returns the number of whole tokens currently available for the key.
+func (l *Limiter) Remaining(key string) int {
+	return l.Status(key).Remaining
+}
+
+// RetryAfter returns how long until one token is available for the key.
+// Returns zero if a token is available now.
+func (l *Limiter) RetryAfter(key string) time.Duration {
+	return l.Status(key).RetryAfter
+}
+
+// Reset clears all tracked keys, refilling every bucket.
+func (l *Limiter) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = make(map[string]time.Time, l.maxKeys)
+}
+
+// Len returns the number of currently tracked keys (including full buckets not yet cleaned up).
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.entries)
+}
+
+// tolerance returns the time it takes to refill a full bucket.
+func (l *Limiter) tolerance() time.Duration {
+	return time.Duration(l.burst) * l.interval
+}
+
+// tat returns the theoretical arrival time for the key, never earlier than now.
+// Caller must hold l.mu.
+func (l *Limiter) tat(key string, now time.Time) time.Time {
+	if tat, ok := l.entries[key]; ok && tat.After(now) {
+		return tat
+	}
+
+	return now
+}
+
+// store saves the theoretical arrival time for the key, evicting another key
+// if the key is new and the limiter is at capacity. Caller must hold l.mu.
+func (l *Limiter) store(key string, tat time.Time, now time.Time) {
+	if _, ok := l.entries[key]; !ok {
+		l.evictIfNeeded(now)
+	}
+
+	l.entries[key] = tat
+}
+
+// evictIfNeeded removes full buckets first, then evicts the key whose bucket
+// is closest to full if still at capacity. Caller must hold l.mu.
+func (l *Limiter) evictIfNeeded(now time.Time) {
+	if len(l.entries) < l.maxKeys {
+		return
+	}
+
+	for key, tat := range l.entries {
+		if !tat.After(now) {
+			delete(l.entries, key)
+		}
+	}
+
+	if len(l.entries) < l.maxKeys {
+		return
+	}
+
+	var oldestKey string
+	var oldestTat time.Time
+	found := false
+
+	for key, tat := range l.entries {
+		if !found || tat.Before(oldestTat) {
+			oldestKey = key
+			oldestTat = tat
+			found = true
+		}
+	}
+
+	delete(l.entries, oldestKey)
+}
@@ -0,0 +1,416 @@
+package tokenbucket
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("values", func(t *testing.T) {
+		l := New(10, 5, 100, 0)
+		defer l.Stop()
+
+		assert.Equal(t, 10.0, l.Rate())
+		assert.Equal(t, 5, l.Burst())
+		assert.Equal(t, 100*time.Millisecond, l.interval)
+		assert.Equal(t, 100, l.maxKeys)
+		assert.Nil(t, l.cleanup)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		for _, rate := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+			l := New(rate, 0, 0, 0)
+			assert.Equal(t, 1.0, l.Rate())
+			assert.Equal(t, 1, l.Burst())
+			assert.Equal(t, 1000, l.maxKeys)
+			l.Stop()
+		}
+	})
+
+	t.Run("very high rate", func(t *testing.T) {
+		l := New(1e12, 1, 0, 0)
+		defer l.Stop()
+
+		assert.Equal(t, time.Duration(1), l.interval)
+	})
+
+	t.Run("with cleanup interval", func(t *testing.T) {
+		l := New(1, 1, 100, 50*time.Millisecond)
+		assert.NotNil(t, l.cleanup)
+
+		l.Stop()
+		l.Stop() // Should not panic
+	})
+}
+
+func TestAllowN(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	t.Run("burst then refill", func(t *testing.T) {
+		l := New(10, 5, 100, 0)
+		defer l.Stop()
+
+		for range 5 {
+			assert.True(t, l.AllowNAt("key1", 1, start))
+		}
+		assert.False(t, l.AllowNAt("key1", 1, start))
+
+		// One token refills every 100ms
+		assert.False(t, l.AllowNAt("key1", 1, start.Add(99*time.Millisecond)))
+		assert.True(t, l.AllowNAt("key1", 1, start.Add(100*time.Millisecond)))
+		assert.False(t, l.AllowNAt("key1", 1, start.Add(100*time.Millisecond)))
+
+		// The bucket never holds more than burst tokens
+		later := start.Add(time.Hour)
+		assert.True(t, l.AllowNAt("key1", 5, later))
+		assert.False(t, l.AllowNAt("key1", 1, later))
+	})
+
+	t.Run("denied requests consume nothing", func(t *testing.T) {
+		l := New(10, 5, 100, 0)
+		defer l.Stop()
+
+		assert.True(t, l.AllowNAt("key1", 3, start))
+		assert.False(t, l.AllowNAt("key1", 3, start))
+		assert.True(t, l.AllowNAt("key1", 2, start))
+	})
+
+	t.Run("n exceeds burst", func(t *testing.T) {
+		l := New(10, 5, 100, 0)
+		defer l.Stop()
+
+		assert.False(t, l.AllowN("key1", 6))
+		assert.Equal(t, 0, l.Len())
+	})
+
+	t.Run("non-positive n", func(t *testing.T) {
+		l := New(10, 1, 100, 0)
+		defer l.Stop()
+
+		assert.True(t, l.Allow("key1"))
+		assert.True(t, l.AllowN("key1", 0))
+		assert.True(t, l.AllowN("key1", -1))
+		assert.False(t, l.Allow("key1"))
+	})
+
+	t.Run("independent keys", func(t *testing.T) {
+		l := New(1, 1, 100, 0)
+		defer l.Stop()
+
+		assert.True(t, l.AllowNAt("key1", 1, start))
+		assert.False(t, l.AllowNAt("key1", 1, start))
+		assert.True(t, l.AllowNAt("key2", 1, start))
+	})
+}
+
+func TestReserveN(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	t.Run("delays queue up", func(t *testing.T) {
+		l := New(10, 2, 100, 0)
+		defer l.Stop()
+
+		for _, expected := range []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond} {
+			r := l.ReserveNAt("key1", 1, start)
+			assert.True(t, r.OK())
+			assert.Equal(t, expected, r.Delay())
+		}
+
+		// Reservations put the bucket into debt
+		assert.False(t, l.AllowNAt("key1", 1, start.Add(200*time.Millisecond)))
+		assert.True(t, l.AllowNAt("key1", 1, start.Add(300*time.Millisecond)))
+	})
+
+	t.Run("exceeds burst", func(t *testing.T) {
+		l := New(10, 2, 100, 0)
+		defer l.Stop()
+
+		r := l.ReserveN("key1", 3)
+		assert.False(t, r.OK())
+		assert.Equal(t, time.Duration(0), r.Delay())
+		r.Cancel() // Should not panic
+		assert.Equal(t, 0, l.Len())
+	})
+
+	t.Run("cancel restores tokens", func(t *testing.T) {
+		l := New(10, 2, 100, 0)
+		defer l.Stop()
+
+		l.ReserveNAt("key1", 2, start)
+		r := l.ReserveNAt("key1", 1, start)
+		assert.Equal(t, 100*time.Millisecond, r.Delay())
+
+		r.Cancel()
+		r.Cancel() // Second cancel is a no-op
+		assert.Equal(t, 100*time.Millisecond, l.ReserveNAt("key1", 1, start).Delay())
+	})
+
+	t.Run("cancel after later reservation", func(t *testing.T) {
+		l := New(10, 1, 100, 0)
+		defer l.Stop()
+
+		first := l.ReserveNAt("key1", 1, start)
+		second := l.ReserveNAt("key1", 1, start)
+		assert.Equal(t, 100*time.Millisecond, second.Delay())
+
+		first.Cancel()
+		assert.Equal(t, 200*time.Millisecond, l.ReserveNAt("key1", 1, start).Delay())
+	})
+
+	t.Run("reserve", func(t *testing.T) {
+		l := New(1, 1, 100, 0)
+		defer l.Stop()
+
+		assert.Equal(t, time.Duration(0), l.Reserve("key1").Delay())
+		assert.Greater(t, l.Reserve("key1").Delay(), 900*time.Millisecond)
+	})
+}
+
+func TestWaitN(t *testing.T) {
+	t.Run("waits for token", func(t *testing.T) {
+		l := New(50, 1, 100, 0)
+		defer l.Stop()
+
+		require.NoError(t, l.Wait(context.Background(), "key1"))
+
+		begin := time.Now()
+		require.NoError(t, l.Wait(context.Background(), "key1"))
+		assert.GreaterOrEqual(t, time.Since(begin), 15*time.Millisecond)
+	})
+
+	t.Run("exceeds burst", func(t *testing.T) {
+		l := New(50, 1, 100, 0)
+		defer l.Stop()
+
+		assert.ErrorIs(t, l.WaitN(context.Background(), "key1", 2), ErrExceedsBurst)
+	})
+
+	t.Run("exceeds deadline", func(t *testing.T) {
+		l := New(1, 1, 100, 0)
+		defer l.Stop()
+
+		require.True(t, l.Allow("key1"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, l.Wait(ctx, "key1"), ErrExceedsDeadline)
+		assert.Greater(t, l.RetryAfter("key1"), 900*time.Millisecond, "tokens are returned")
+		assert.Less(t, l.RetryAfter("key1"), 1100*time.Millisecond)
+	})
+
+	t.Run("context canceled while waiting", func(t *testing.T) {
+		l := New(1, 1, 100, 0)
+		defer l.Stop()
+
+		require.True(t, l.Allow("key1"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		assert.ErrorIs(t, l.Wait(ctx, "key1"), context.Canceled)
+		assert.Less(t, l.RetryAfter("key1"), 1100*time.Millisecond, "tokens are returned")
+	})
+
+	t.Run("context already done", func(t *testing.T) {
+		l := New(1, 1, 100, 0)
+		defer l.Stop()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.ErrorIs(t, l.Wait(ctx, "key1"), context.Canceled)
+		assert.Equal(t, 0, l.Len())
+	})
+}
+
+func TestStatus(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	l := New(10, 5, 100, 0)
+	defer l.Stop()
+
+	assert.Equal(t, Status{Limit: 5, Remaining: 5}, l.StatusAt("key1", start))
+
+	l.AllowNAt("key1", 2, start)
+	assert.Equal(t, Status{Limit: 5, Remaining: 3, Reset: 200 * time.Millisecond}, l.StatusAt("key1", start))
+
+	l.AllowNAt("key1", 3, start)
+	assert.Equal(t, Status{
+		Limit:      5,
+		Remaining:  0,
+		RetryAfter: 100 * time.Millisecond,
+		Reset:      500 * time.Millisecond,
+	}, l.StatusAt("key1", start))
+
+	// Partially refilled token is not counted as remaining
+	now := start.Add(50 * time.Millisecond)
+	assert.Equal(t, Status{
+		Limit:      5,
+		Remaining:  0,
+		RetryAfter: 50 * time.Millisecond,
+		Reset:      450 * time.Millisecond,
+	}, l.StatusAt("key1", now))
+
+	now = start.Add(250 * time.Millisecond)
+	assert.Equal(t, 2, l.StatusAt("key1", now).Remaining)
+
+	// Reservations in debt push RetryAfter beyond one interval
+	l.ReserveNAt("key1", 5, now)
+	status := l.StatusAt("key1", now)
+	assert.Equal(t, 0, status.Remaining)
+	assert.Equal(t, 350*time.Millisecond, status.RetryAfter)
+
+	assert.Equal(t, 5, l.Remaining("key2"))
+	assert.Equal(t, time.Duration(0), l.RetryAfter("key2"))
+}
+
+func TestEviction(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	t.Run("evicts fullest bucket", func(t *testing.T) {
+		l := New(1, 10, 2, 0)
+		defer l.Stop()
+
+		l.AllowNAt("key1", 5, start)
+		l.AllowNAt("key2", 1, start)
+		l.AllowNAt("key3", 1, start)
+
+		assert.Equal(t, 2, l.Len())
+		assert.Equal(t, 5, l.StatusAt("key1", start).Remaining)
+		assert.Equal(t, 10, l.StatusAt("key2", start).Remaining)
+		assert.Equal(t, 9, l.StatusAt("key3", start).Remaining)
+	})
+
+	t.Run("evicts full buckets first", func(t *testing.T) {
+		l := New(1, 10, 2, 0)
+		defer l.Stop()
+
+		l.AllowNAt("key1", 1, start)
+		l.AllowNAt("key2", 5, start)
+		l.AllowNAt("key3", 1, start.Add(2*time.Second))
+
+		now := start.Add(2 * time.Second)
+		assert.Equal(t, 2, l.Len())
+		assert.Equal(t, 7, l.StatusAt("key2", now).Remaining)
+		assert.Equal(t, 9, l.StatusAt("key3", now).Remaining)
+	})
+
+	t.Run("background cleanup", func(t *testing.T) {
+		l := New(100, 1, 100, 20*time.Millisecond)
+		defer l.Stop()
+
+		l.Allow("key1")
+		l.Allow("key2")
+		assert.Equal(t, 2, l.Len())
+
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, 0, l.Len())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		l := New(1, 1, 100, 0)
+		defer l.Stop()
+
+		l.Allow("key1")
+		l.Reset()
+		assert.Equal(t, 0, l.Len())
+		assert.True(t, l.Allow("key1"))
+	})
+}
+
+func TestConcurrency(t *testing.T) {
+	l := New(0.001, 50, 1000, 0)
+	defer l.Stop()
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := range 10 {
+		wg.Go(func() {
+			key := fmt.Sprintf("key-%d", i%5)
+			for range 100 {
+				if l.Allow(key) {
+					allowed.Add(1)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	// Each of 5 keys holds 50 tokens and refills one per 1000s
+	assert.Equal(t, int64(250), allowed.Load())
+}
+
+func BenchmarkLimiter_Allow(b *testing.B) {
+	l := New(1e9, 1000, 10000, 0)
+	defer l.Stop()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		l.Allow("bench-key")
+	}
+}
+
+func BenchmarkLimiter_ConcurrentAllow(b *testing.B) {
+	l := New(1e9, 1000, 10000, 0)
+	defer l.Stop()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			l.Allow(fmt.Sprintf("key-%d", i%100))
+			i++
+		}
+	})
+}
+
+func FuzzLimiter_AllowN(f *testing.F) {
+	f.Add(10.0, 5, 3, int64(100))
+	f.Add(0.5, 1, 1, int64(0))
+	f.Add(1000.0, 100, 200, int64(5))
+
+	f.Fuzz(func(t *testing.T, rate float64, burst, n int, elapsedMs int64) {
+		if elapsedMs < 0 || elapsedMs > 1e6 {
+			t.Skip()
+		}
+
+		start := time.Unix(1700000000, 0)
+		l := New(rate, burst, 10, 0)
+		defer l.Stop()
+
+		status := l.StatusAt("key", start)
+		if status.Remaining != l.Burst() || status.RetryAfter != 0 {
+			t.Fatalf("fresh bucket status %+v", status)
+		}
+
+		allowed := l.AllowNAt("key", n, start)
+		if n > 0 && allowed != (n <= l.Burst()) {
+			t.Fatalf("AllowN(%d) with burst %d = %v", n, l.Burst(), allowed)
+		}
+
+		now := start.Add(time.Duration(elapsedMs) * time.Millisecond)
+		status = l.StatusAt("key", now)
+		if status.Remaining < 0 || status.Remaining > l.Burst() || status.RetryAfter < 0 || status.Reset < 0 {
+			t.Fatalf("invalid status %+v", status)
+		}
+
+		if (status.Remaining > 0) != (status.RetryAfter == 0) {
+			t.Fatalf("inconsistent status %+v", status)
+		}
+
+		if status.Remaining > 0 && !l.AllowNAt("key", status.Remaining, now) {
+			t.Fatalf("remaining %d tokens not allowed", status.Remaining)
+		}
+	})
+}